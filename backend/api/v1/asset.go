@@ -1,29 +1,56 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"building-asset-backend/internal/model"
 	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/database"
 	"building-asset-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AssetAPI struct {
-	assetService *service.AssetService
+	assetService    *service.AssetService
+	snapshotService *service.SnapshotService
 }
 
 func NewAssetAPI() *AssetAPI {
 	return &AssetAPI{
-		assetService: service.NewAssetService(),
+		assetService:    service.NewAssetService(),
+		snapshotService: service.NewSnapshotService(),
+	}
+}
+
+// scoped 返回应用当前请求数据权限的资产服务
+func (a *AssetAPI) scoped(c *gin.Context) *service.AssetService {
+	return a.assetService.WithContext(c.Request.Context())
+}
+
+// respondAssetError 资产相关写操作的错误响应，超出数据权限的记录按不存在处理
+func respondAssetError(c *gin.Context, err error, notFound, failed string) {
+	var parentErr service.ParentNotFoundError
+	switch {
+	case errors.As(err, &parentErr):
+		response.Error(c, http.StatusNotFound, parentErr.Error())
+	case database.IsRecordNotFoundError(err):
+		response.Error(c, http.StatusNotFound, notFound)
+	case errors.Is(err, service.ErrOutOfDataScope):
+		response.Error(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidCoordinates):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, failed)
 	}
 }
 
 // Asset CRUD operations
 
-// GetAssets 获取资产列表
+// GetAssets 获取资产列表，format=csv|xlsx时导出符合条件的全部资产
 func (a *AssetAPI) GetAssets(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
@@ -31,7 +58,14 @@ func (a *AssetAPI) GetAssets(c *gin.Context) {
 	assetType := c.Query("type")
 	status := c.Query("status")
 
-	assets, total, err := a.assetService.GetAssets(page, pageSize, name, assetType, status)
+	if c.Query("format") != "" {
+		exportList(c, "assets", assetExportColumns, func(maxRows int, fn func([]*model.Asset) error) error {
+			return a.scoped(c).ExportAssets(name, assetType, status, maxRows, fn)
+		})
+		return
+	}
+
+	assets, total, err := a.scoped(c).GetAssets(page, pageSize, name, assetType, status)
 	if err != nil {
 		response.ErrorWithData(c, http.StatusInternalServerError, "获取资产列表失败", err.Error())
 		return
@@ -53,7 +87,7 @@ func (a *AssetAPI) GetAsset(c *gin.Context) {
 		return
 	}
 
-	asset, err := a.assetService.GetAssetByID(uint(id))
+	asset, err := a.scoped(c).GetAssetByID(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusNotFound, "资产不存在")
 		return
@@ -71,9 +105,9 @@ func (a *AssetAPI) CreateAsset(c *gin.Context) {
 	}
 
 
-	asset, err := a.assetService.CreateAsset(&req)
+	asset, err := a.scoped(c).CreateAsset(&req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "创建资产失败")
+		respondAssetError(c, err, "资产不存在", "创建资产失败")
 		return
 	}
 
@@ -95,9 +129,9 @@ func (a *AssetAPI) UpdateAsset(c *gin.Context) {
 	}
 
 
-	asset, err := a.assetService.UpdateAsset(uint(id), &req)
+	asset, err := a.scoped(c).UpdateAsset(uint(id), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "更新资产失败")
+		respondAssetError(c, err, "资产不存在", "更新资产失败")
 		return
 	}
 
@@ -112,8 +146,8 @@ func (a *AssetAPI) DeleteAsset(c *gin.Context) {
 		return
 	}
 
-	if err := a.assetService.DeleteAsset(uint(id)); err != nil {
-		response.Error(c, http.StatusInternalServerError, "删除资产失败")
+	if err := a.scoped(c).DeleteAsset(uint(id)); err != nil {
+		respondAssetError(c, err, "资产不存在", "删除资产失败")
 		return
 	}
 
@@ -122,14 +156,21 @@ func (a *AssetAPI) DeleteAsset(c *gin.Context) {
 
 // Building CRUD operations
 
-// GetBuildings 获取建筑列表
+// GetBuildings 获取建筑列表，format=csv|xlsx时导出符合条件的全部建筑
 func (a *AssetAPI) GetBuildings(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 	assetID, _ := strconv.ParseUint(c.Query("asset_id"), 10, 64)
 	name := c.Query("name")
 
-	buildings, total, err := a.assetService.GetBuildings(page, pageSize, uint(assetID), name)
+	if c.Query("format") != "" {
+		exportList(c, "buildings", buildingExportColumns, func(maxRows int, fn func([]*model.Building) error) error {
+			return a.scoped(c).ExportBuildings(uint(assetID), name, maxRows, fn)
+		})
+		return
+	}
+
+	buildings, total, err := a.scoped(c).GetBuildings(page, pageSize, uint(assetID), name)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取建筑列表失败")
 		return
@@ -151,7 +192,7 @@ func (a *AssetAPI) GetBuilding(c *gin.Context) {
 		return
 	}
 
-	building, err := a.assetService.GetBuildingByID(uint(id))
+	building, err := a.scoped(c).GetBuildingByID(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusNotFound, "建筑不存在")
 		return
@@ -169,9 +210,9 @@ func (a *AssetAPI) CreateBuilding(c *gin.Context) {
 	}
 
 
-	building, err := a.assetService.CreateBuilding(&req)
+	building, err := a.scoped(c).CreateBuilding(&req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "创建建筑失败")
+		respondAssetError(c, err, "资产不存在", "创建建筑失败")
 		return
 	}
 
@@ -193,9 +234,9 @@ func (a *AssetAPI) UpdateBuilding(c *gin.Context) {
 	}
 
 
-	building, err := a.assetService.UpdateBuilding(uint(id), &req)
+	building, err := a.scoped(c).UpdateBuilding(uint(id), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "更新建筑失败")
+		respondAssetError(c, err, "建筑不存在", "更新建筑失败")
 		return
 	}
 
@@ -210,8 +251,8 @@ func (a *AssetAPI) DeleteBuilding(c *gin.Context) {
 		return
 	}
 
-	if err := a.assetService.DeleteBuilding(uint(id)); err != nil {
-		response.Error(c, http.StatusInternalServerError, "删除建筑失败")
+	if err := a.scoped(c).DeleteBuilding(uint(id)); err != nil {
+		respondAssetError(c, err, "建筑不存在", "删除建筑失败")
 		return
 	}
 
@@ -220,13 +261,20 @@ func (a *AssetAPI) DeleteBuilding(c *gin.Context) {
 
 // Floor CRUD operations
 
-// GetFloors 获取楼层列表
+// GetFloors 获取楼层列表，format=csv|xlsx时导出楼层，未指定建筑时导出全部楼层
 func (a *AssetAPI) GetFloors(c *gin.Context) {
 	buildingID, _ := strconv.ParseUint(c.Query("building_id"), 10, 64)
 
-	floors, err := a.assetService.GetFloorsByBuildingID(uint(buildingID))
+	if c.Query("format") != "" {
+		exportList(c, "floors", floorExportColumns, func(maxRows int, fn func([]*model.Floor) error) error {
+			return a.scoped(c).ExportFloors(uint(buildingID), maxRows, fn)
+		})
+		return
+	}
+
+	floors, err := a.scoped(c).GetFloorsByBuildingID(uint(buildingID))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "获取楼层列表失败")
+		respondAssetError(c, err, "建筑不存在", "获取楼层列表失败")
 		return
 	}
 
@@ -242,9 +290,9 @@ func (a *AssetAPI) CreateFloor(c *gin.Context) {
 	}
 
 
-	floor, err := a.assetService.CreateFloor(&req)
+	floor, err := a.scoped(c).CreateFloor(&req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "创建楼层失败")
+		respondAssetError(c, err, "建筑不存在", "创建楼层失败")
 		return
 	}
 
@@ -266,9 +314,9 @@ func (a *AssetAPI) UpdateFloor(c *gin.Context) {
 	}
 
 
-	floor, err := a.assetService.UpdateFloor(uint(id), &req)
+	floor, err := a.scoped(c).UpdateFloor(uint(id), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "更新楼层失败")
+		respondAssetError(c, err, "楼层不存在", "更新楼层失败")
 		return
 	}
 
@@ -283,8 +331,8 @@ func (a *AssetAPI) DeleteFloor(c *gin.Context) {
 		return
 	}
 
-	if err := a.assetService.DeleteFloor(uint(id)); err != nil {
-		response.Error(c, http.StatusInternalServerError, "删除楼层失败")
+	if err := a.scoped(c).DeleteFloor(uint(id)); err != nil {
+		respondAssetError(c, err, "楼层不存在", "删除楼层失败")
 		return
 	}
 
@@ -293,13 +341,20 @@ func (a *AssetAPI) DeleteFloor(c *gin.Context) {
 
 // Room CRUD operations
 
-// GetRooms 获取房间列表
+// GetRooms 获取房间列表，format=csv|xlsx时导出房间，未指定楼层时导出全部房间
 func (a *AssetAPI) GetRooms(c *gin.Context) {
 	floorID, _ := strconv.ParseUint(c.Query("floor_id"), 10, 64)
 
-	rooms, err := a.assetService.GetRoomsByFloorID(uint(floorID))
+	if c.Query("format") != "" {
+		exportList(c, "rooms", roomExportColumns, func(maxRows int, fn func([]*model.Room) error) error {
+			return a.scoped(c).ExportRooms(uint(floorID), maxRows, fn)
+		})
+		return
+	}
+
+	rooms, err := a.scoped(c).GetRoomsByFloorID(uint(floorID))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "获取房间列表失败")
+		respondAssetError(c, err, "楼层不存在", "获取房间列表失败")
 		return
 	}
 
@@ -315,9 +370,9 @@ func (a *AssetAPI) CreateRoom(c *gin.Context) {
 	}
 
 
-	room, err := a.assetService.CreateRoom(&req)
+	room, err := a.scoped(c).CreateRoom(&req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "创建房间失败")
+		respondAssetError(c, err, "楼层不存在", "创建房间失败")
 		return
 	}
 
@@ -339,9 +394,9 @@ func (a *AssetAPI) UpdateRoom(c *gin.Context) {
 	}
 
 
-	room, err := a.assetService.UpdateRoom(uint(id), &req)
+	room, err := a.scoped(c).UpdateRoom(uint(id), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "更新房间失败")
+		respondAssetError(c, err, "房间不存在", "更新房间失败")
 		return
 	}
 
@@ -356,21 +411,169 @@ func (a *AssetAPI) DeleteRoom(c *gin.Context) {
 		return
 	}
 
-	if err := a.assetService.DeleteRoom(uint(id)); err != nil {
-		response.Error(c, http.StatusInternalServerError, "删除房间失败")
+	if err := a.scoped(c).DeleteRoom(uint(id)); err != nil {
+		respondAssetError(c, err, "房间不存在", "删除房间失败")
 		return
 	}
 
 	response.Success(c, nil)
 }
 
-// GetAssetStatistics 获取资产统计数据
+// Change history
+
+// GetAssetHistory 获取资产变更记录
+func (a *AssetAPI) GetAssetHistory(c *gin.Context) {
+	a.history(c, model.EntityAsset, "无效的资产ID", "资产不存在")
+}
+
+// GetBuildingHistory 获取建筑变更记录
+func (a *AssetAPI) GetBuildingHistory(c *gin.Context) {
+	a.history(c, model.EntityBuilding, "无效的建筑ID", "建筑不存在")
+}
+
+// GetFloorHistory 获取楼层变更记录
+func (a *AssetAPI) GetFloorHistory(c *gin.Context) {
+	a.history(c, model.EntityFloor, "无效的楼层ID", "楼层不存在")
+}
+
+// GetRoomHistory 获取房间变更记录
+func (a *AssetAPI) GetRoomHistory(c *gin.Context) {
+	a.history(c, model.EntityRoom, "无效的房间ID", "房间不存在")
+}
+
+// history 按时间倒序分页返回实体的字段变更记录
+func (a *AssetAPI) history(c *gin.Context, entityType, invalid, notFound string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, invalid)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	logs, total, err := a.scoped(c).GetAssetHistory(entityType, uint(id), page, pageSize)
+	if err != nil {
+		respondAssetError(c, err, notFound, "获取变更记录失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// RecomputeRollups 按房间重算楼层、建筑、资产的汇总字段，返回修正前不一致的记录
+func (a *AssetAPI) RecomputeRollups(c *gin.Context) {
+	report, err := a.assetService.WithContext(c.Request.Context()).RecomputeAllRollups()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "重算汇总数据失败")
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// GetAssetStatistics 获取资产统计数据，支持按街道、资产和统计日期（YYYY-MM-DD）筛选
 func (a *AssetAPI) GetAssetStatistics(c *gin.Context) {
-	stats, err := a.assetService.GetAssetStatistics()
+	var filter service.AssetStatisticsFilter
+	if v := c.Query("street_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "无效的街道ID")
+			return
+		}
+		filter.StreetID = uint(id)
+	}
+	if v := c.Query("asset_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "无效的资产ID")
+			return
+		}
+		filter.AssetID = uint(id)
+	}
+	if v := c.Query("date"); v != "" {
+		date, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		filter.Date = &date
+	}
+
+	stats, err := a.scoped(c).GetAssetStatistics(filter)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "获取统计数据失败")
+		response.ErrorWithData(c, http.StatusInternalServerError, "获取统计数据失败", err.Error())
 		return
 	}
 
 	response.Success(c, stats)
 }
+
+// GetTrends 获取出租率和租金趋势，默认返回最近30天按日汇总的资产快照
+func (a *AssetAPI) GetTrends(c *gin.Context) {
+	filter := service.TrendFilter{
+		EntityType:  c.Query("entity_type"),
+		Granularity: c.DefaultQuery("granularity", service.GranularityDay),
+	}
+
+	ids := []struct {
+		param  string
+		label  string
+		target *uint
+	}{
+		{"entity_id", "实体ID", &filter.EntityID},
+		{"street_id", "街道ID", &filter.StreetID},
+		{"asset_id", "资产ID", &filter.AssetID},
+	}
+	for _, id := range ids {
+		if v := c.Query(id.param); v != "" {
+			value, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "无效的"+id.label)
+				return
+			}
+			*id.target = uint(value)
+		}
+	}
+
+	today := time.Now()
+	filter.EndDate = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	if v := c.Query("end_date"); v != "" {
+		date, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		filter.EndDate = date
+	}
+	filter.StartDate = filter.EndDate.AddDate(0, 0, -29)
+	if v := c.Query("start_date"); v != "" {
+		date, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		filter.StartDate = date
+	}
+
+	points, err := a.snapshotService.WithContext(c.Request.Context()).GetTrends(filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTrendQuery) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.ErrorWithData(c, http.StatusInternalServerError, "获取趋势数据失败", err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"start_date":  filter.StartDate.Format("2006-01-02"),
+		"end_date":    filter.EndDate.Format("2006-01-02"),
+		"granularity": filter.Granularity,
+		"points":      points,
+	})
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGeoJSONSize 多边形查询请求体的最大字节数
+const maxGeoJSONSize = 1 << 20
+
+// SearchAssetsInBBox 按矩形范围（min_lng、min_lat、max_lng、max_lat）框选资产并汇总
+func (a *AssetAPI) SearchAssetsInBBox(c *gin.Context) {
+	values, ok := queryFloats(c, "min_lng", "min_lat", "max_lng", "max_lat")
+	if !ok {
+		return
+	}
+
+	result, err := a.scoped(c).SearchAssetsInBBox(values[0], values[1], values[2], values[3])
+	respondGeoResult(c, result, err)
+}
+
+// SearchAssetsInRadius 查询距中心点（lng、lat）radius米内的资产，按距离排序并汇总
+func (a *AssetAPI) SearchAssetsInRadius(c *gin.Context) {
+	values, ok := queryFloats(c, "lng", "lat", "radius")
+	if !ok {
+		return
+	}
+
+	result, err := a.scoped(c).SearchAssetsInRadius(values[0], values[1], values[2])
+	respondGeoResult(c, result, err)
+}
+
+// SearchAssetsInPolygon 查询请求体中GeoJSON多边形内的资产并汇总
+func (a *AssetAPI) SearchAssetsInPolygon(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxGeoJSONSize))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "请求体过大或读取失败")
+		return
+	}
+
+	result, err := a.scoped(c).SearchAssetsInPolygon(body)
+	respondGeoResult(c, result, err)
+}
+
+// queryFloats 读取必填的数值查询参数，参数缺失或无效时返回400
+func queryFloats(c *gin.Context, keys ...string) ([]float64, bool) {
+	values := make([]float64, len(keys))
+	for i, key := range keys {
+		v, err := strconv.ParseFloat(c.Query(key), 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "缺少或无效的参数"+key)
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
+
+func respondGeoResult(c *gin.Context, result *service.GeoSearchResult, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCoordinates) || errors.Is(err, service.ErrInvalidGeoQuery) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.ErrorWithData(c, http.StatusInternalServerError, "空间查询失败", err.Error())
+		return
+	}
+	response.Success(c, result)
+}
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/response"
+	"building-asset-backend/pkg/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportAssets 从CSV或XLSX批量导入资产、建筑、楼层和房间
+// dry_run=true时只返回预览不保存；存在错误行时不保存任何记录
+func (a *AssetAPI) ImportAssets(c *gin.Context) {
+	maxSize := config.Get().Upload.MaxSize
+	if maxSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "请上传导入文件")
+		return
+	}
+	defer file.Close()
+
+	if maxSize > 0 && header.Size > maxSize {
+		response.Error(c, http.StatusBadRequest, "文件大小超过限制")
+		return
+	}
+	format := spreadsheet.FormatOf(header.Filename)
+	if format == "" {
+		response.Error(c, http.StatusBadRequest, spreadsheet.ErrUnsupportedFormat.Error())
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的dry_run参数")
+		return
+	}
+
+	result, err := a.scoped(c).ImportAssets(file, format, dryRun)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidImportFile) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.ErrorWithData(c, http.StatusInternalServerError, "导入失败", err.Error())
+		return
+	}
+
+	if !dryRun && !result.Committed {
+		response.ErrorWithData(c, http.StatusBadRequest, "导入数据存在错误，未保存任何记录", result)
+		return
+	}
+	response.Success(c, result)
+}
+
+// GetImportTemplate 下载导入模板，format=xlsx（默认）为分工作表模板，format=csv为平铺模板
+func (a *AssetAPI) GetImportTemplate(c *gin.Context) {
+	format := c.DefaultQuery("format", spreadsheet.FormatXLSX)
+	if format != spreadsheet.FormatXLSX && format != spreadsheet.FormatCSV {
+		response.Error(c, http.StatusBadRequest, spreadsheet.ErrUnsupportedFormat.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := spreadsheet.Write(&buf, format, service.ImportTemplate(format)...); err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成导入模板失败")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="asset_import_template.`+format+`"`)
+	c.Data(http.StatusOK, spreadsheet.ContentType(format), buf.Bytes())
+}
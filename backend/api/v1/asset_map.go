@@ -0,0 +1,228 @@
+package v1
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMapGeoJSON 以GeoJSON FeatureCollection返回地图上的资产
+// 支持bbox=最小经度,最小纬度,最大经度,最大纬度 和 zoom=缩放级别（指定时按网格聚合）
+func (a *AssetAPI) GetMapGeoJSON(c *gin.Context) {
+	features, ok := a.mapFeatures(c)
+	if !ok {
+		return
+	}
+
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, 0, len(features))}
+	for _, feature := range features {
+		item := geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONPoint{Type: "Point", Coordinates: [2]float64{feature.Longitude, feature.Latitude}},
+			Properties: mapFeatureProperties(feature),
+		}
+		// 资产簇附带簇内资产的范围，便于前端点击后缩放
+		if feature.Asset == nil {
+			item.BBox = feature.BBox[:]
+		}
+		collection.Features = append(collection.Features, item)
+	}
+
+	data, err := json.Marshal(collection)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成GeoJSON失败")
+		return
+	}
+	c.Data(http.StatusOK, "application/geo+json; charset=utf-8", data)
+}
+
+// GetMapKML 以KML文件导出地图上的资产，参数同 GetMapGeoJSON
+func (a *AssetAPI) GetMapKML(c *gin.Context) {
+	features, ok := a.mapFeatures(c)
+	if !ok {
+		return
+	}
+
+	document := kmlDocument{Name: "资产分布", Placemarks: make([]kmlPlacemark, 0, len(features))}
+	for _, feature := range features {
+		placemark := kmlPlacemark{
+			Point: kmlPoint{Coordinates: strconv.FormatFloat(feature.Longitude, 'f', -1, 64) + "," +
+				strconv.FormatFloat(feature.Latitude, 'f', -1, 64)},
+		}
+		if feature.Asset != nil {
+			placemark.Name = feature.Asset.AssetName
+			placemark.Description = feature.Asset.Address
+		} else {
+			placemark.Name = fmt.Sprintf("%d个资产", feature.Count)
+		}
+		for _, property := range mapFeatureProperties(feature) {
+			placemark.Data = append(placemark.Data, kmlData{Name: property.key, Value: fmt.Sprint(property.value)})
+		}
+		document.Placemarks = append(document.Placemarks, placemark)
+	}
+
+	data, err := xml.MarshalIndent(kml{Xmlns: "http://www.opengis.net/kml/2.2", Document: document}, "", "  ")
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成KML失败")
+		return
+	}
+	filename := fmt.Sprintf("assets_%s.kml", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/vnd.google-earth.kml+xml", append([]byte(xml.Header), data...))
+}
+
+// mapFeatures 解析bbox和zoom参数并查询地图要素，出错时已写入响应
+func (a *AssetAPI) mapFeatures(c *gin.Context) ([]*service.MapFeature, bool) {
+	var query service.MapQuery
+	if v := c.Query("bbox"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			response.Error(c, http.StatusBadRequest, "bbox格式应为最小经度,最小纬度,最大经度,最大纬度")
+			return nil, false
+		}
+		var bbox [4]float64
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "bbox格式应为最小经度,最小纬度,最大经度,最大纬度")
+				return nil, false
+			}
+			bbox[i] = value
+		}
+		query.BBox = &bbox
+	}
+	if v := c.Query("zoom"); v != "" {
+		zoom, err := strconv.Atoi(v)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "无效的缩放级别")
+			return nil, false
+		}
+		query.Zoom = &zoom
+	}
+
+	features, err := a.scoped(c).GetMapFeatures(query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCoordinates) || errors.Is(err, service.ErrInvalidGeoQuery) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		response.ErrorWithData(c, http.StatusInternalServerError, "获取地图数据失败", err.Error())
+		return nil, false
+	}
+	return features, true
+}
+
+// mapProperty 要素属性，按顺序输出到KML
+type mapProperty struct {
+	key   string
+	value interface{}
+}
+
+type mapProperties []mapProperty
+
+// MarshalJSON 按属性顺序输出JSON对象
+func (p mapProperties) MarshalJSON() ([]byte, error) {
+	var buf strings.Builder
+	buf.WriteByte('{')
+	for i, property := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(property.key)
+		value, err := json.Marshal(property.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return []byte(buf.String()), nil
+}
+
+// mapFeatureProperties 资产要素包含编码、名称、街道等信息，资产簇包含资产数
+func mapFeatureProperties(feature *service.MapFeature) mapProperties {
+	var properties mapProperties
+	if asset := feature.Asset; asset != nil {
+		street := ""
+		if asset.Street != nil {
+			street = asset.Street.Name
+		}
+		properties = mapProperties{
+			{"id", asset.ID},
+			{"cluster", false},
+			{"asset_code", asset.AssetCode},
+			{"asset_name", asset.AssetName},
+			{"street", street},
+			{"address", asset.Address},
+			{"status", asset.Status},
+		}
+	} else {
+		properties = mapProperties{
+			{"cluster", true},
+			{"asset_count", feature.Count},
+		}
+	}
+	return append(properties,
+		mapProperty{"building_count", feature.BuildingCount},
+		mapProperty{"total_area", feature.TotalArea},
+		mapProperty{"rentable_area", feature.Statistics.RentableArea},
+		mapProperty{"rented_area", feature.Statistics.RentedArea},
+		mapProperty{"occupancy_rate", feature.Statistics.OccupancyRate},
+	)
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string        `json:"type"`
+	BBox       []float64     `json:"bbox,omitempty"`
+	Geometry   geoJSONPoint  `json:"geometry"`
+	Properties mapProperties `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type kml struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Point       kmlPoint  `xml:"Point"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
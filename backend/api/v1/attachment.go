@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAttachments 返回获取实体附件列表的处理函数，entityType为asset、building、floor或room
+func (a *AssetAPI) GetAttachments(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, ok := attachmentOwnerID(c)
+		if !ok {
+			return
+		}
+
+		attachments, err := a.scoped(c).GetAttachments(entityType, ownerID)
+		if err != nil {
+			respondAssetError(c, err, "记录不存在", "获取附件列表失败")
+			return
+		}
+
+		response.Success(c, attachments)
+	}
+}
+
+// UploadAttachment 返回上传实体附件的处理函数，表单字段：file、category、description
+func (a *AssetAPI) UploadAttachment(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, ok := attachmentOwnerID(c)
+		if !ok {
+			return
+		}
+
+		maxSize := config.Get().Upload.MaxSize
+		if maxSize > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+		}
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "请上传附件文件")
+			return
+		}
+		defer file.Close()
+
+		if maxSize > 0 && header.Size > maxSize {
+			response.Error(c, http.StatusBadRequest, "文件大小超过限制")
+			return
+		}
+
+		attachment, err := a.scoped(c).UploadAttachment(entityType, ownerID,
+			c.PostForm("category"), c.PostForm("description"), header.Filename, file)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAttachment) {
+				response.Error(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			respondAssetError(c, err, "记录不存在", "上传附件失败")
+			return
+		}
+
+		response.Success(c, attachment)
+	}
+}
+
+// DownloadAttachment 返回下载实体附件的处理函数，inline=true时在浏览器中直接打开
+func (a *AssetAPI) DownloadAttachment(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, ok := attachmentOwnerID(c)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "无效的附件ID")
+			return
+		}
+
+		attachment, file, err := a.scoped(c).OpenAttachment(entityType, ownerID, uint(id))
+		if err != nil {
+			respondAssetError(c, err, "附件不存在", "下载附件失败")
+			return
+		}
+		defer file.Close()
+
+		disposition := "attachment"
+		if c.Query("inline") == "true" {
+			disposition = "inline"
+		}
+		c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, file, map[string]string{
+			"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+			"X-Content-Type-Options": "nosniff",
+		})
+	}
+}
+
+// DeleteAttachment 返回删除实体附件的处理函数
+func (a *AssetAPI) DeleteAttachment(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, ok := attachmentOwnerID(c)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "无效的附件ID")
+			return
+		}
+
+		if err := a.scoped(c).DeleteAttachment(entityType, ownerID, uint(id)); err != nil {
+			respondAssetError(c, err, "附件不存在", "删除附件失败")
+			return
+		}
+
+		response.Success(c, nil)
+	}
+}
+
+// attachmentOwnerID 读取路径中的实体ID，无效时已写入响应
+func attachmentOwnerID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
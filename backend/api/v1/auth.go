@@ -1,9 +1,10 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
-	"strings"
 
+	"building-asset-backend/internal/model"
 	"building-asset-backend/internal/service"
 	"building-asset-backend/pkg/auth"
 	"building-asset-backend/pkg/response"
@@ -13,12 +14,14 @@ import (
 
 type AuthAPI struct {
 	userService *service.UserService
+	authService *service.AuthService
 	logService  *service.LogService
 }
 
 func NewAuthAPI() *AuthAPI {
 	return &AuthAPI{
 		userService: service.NewUserService(),
+		authService: service.NewAuthService(),
 		logService:  service.NewLogService(),
 	}
 }
@@ -35,31 +38,119 @@ func (a *AuthAPI) Login(c *gin.Context) {
 		return
 	}
 
-	// 验证用户凭据
-	user, err := a.userService.ValidateCredentials(req.Username, req.Password)
+	// 验证用户凭据（含失败次数限制）
+	user, err := a.authService.Authenticate(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		// 记录登录失败日志
 		a.logService.LogLogin(req.Username, c.ClientIP(), c.Request.UserAgent(), "failed", err.Error())
-		response.Error(c, http.StatusUnauthorized, "登录失败")
+		switch {
+		case errors.Is(err, service.ErrAccountTemporarilyLocked),
+			errors.Is(err, service.ErrIPBlocked),
+			errors.Is(err, service.ErrUserLocked):
+			response.Error(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrLoginGuardUnavailable):
+			response.Error(c, http.StatusServiceUnavailable, err.Error())
+		default:
+			response.Error(c, http.StatusUnauthorized, "登录失败")
+		}
 		return
 	}
 
-	// 生成JWT token
-	var roleNames []string
-	for _, role := range user.Roles {
-		roleNames = append(roleNames, role.Name)
+	// 默认密码、过期密码或重置后的密码必须先修改，不签发正式token
+	if reason := a.userService.PasswordChangeReason(user, req.Password); reason != "" {
+		changeToken, err := a.authService.IssuePasswordChangeToken(user)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "生成token失败")
+			return
+		}
+
+		a.logService.LogLogin(user.Username, c.ClientIP(), c.Request.UserAgent(), "success", "登录成功，需修改密码")
+		response.Success(c, gin.H{
+			"must_change_password": true,
+			"reason":               reason,
+			"change_token":         changeToken,
+		})
+		return
 	}
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Name, roleNames)
+
+	// 需要两步验证时只返回临时凭证，否则签发正式token
+	result, err := a.authService.CompleteLogin(user)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "生成token失败")
 		return
 	}
 
 	// 记录登录成功日志
-	a.logService.LogLogin(user.Username, c.ClientIP(), c.Request.UserAgent(), "success", "登录成功")
+	message := "登录成功"
+	if result.Tokens == nil {
+		message = "密码验证成功，等待两步验证"
+	}
+	a.logService.LogLogin(user.Username, c.ClientIP(), c.Request.UserAgent(), "success", message)
 
-	response.Success(c, gin.H{
-		"token": token,
+	response.Success(c, loginResultResponse(result, user))
+}
+
+// ChangeExpiredPassword 使用登录返回的临时凭证修改密码并完成登录
+func (a *AuthAPI) ChangeExpiredPassword(c *gin.Context) {
+	var req struct {
+		ChangeToken string `json:"change_token" binding:"required"`
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	result, user, err := a.authService.ChangeExpiredPassword(req.ChangeToken, req.OldPassword, req.NewPassword)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.Success(c, loginResultResponse(result, user))
+}
+
+// ChangePassword 当前用户修改密码，成功后其他会话失效并返回新的token
+func (a *AuthAPI) ChangePassword(c *gin.Context) {
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	userID := c.GetUint("userID")
+	if err := a.userService.ChangePassword(userID, req.OldPassword, req.NewPassword); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := a.userService.GetUserByID(userID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+
+	tokens, err := a.authService.IssueTokens(user)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成token失败")
+		return
+	}
+
+	response.Success(c, loginResponse(tokens, user))
+}
+
+// loginResponse 登录成功响应数据
+func loginResponse(tokens *service.TokenPair, user *model.User) gin.H {
+	return gin.H{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 		"user": gin.H{
 			"id":           user.ID,
 			"username":     user.Username,
@@ -70,43 +161,65 @@ func (a *AuthAPI) Login(c *gin.Context) {
 			"organization": user.Organization,
 			"roles":        user.Roles,
 		},
-	})
+	}
+}
+
+// loginResultResponse 密码校验通过后的响应数据，需要两步验证时只返回临时凭证
+func loginResultResponse(result *service.LoginResult, user *model.User) gin.H {
+	if result.Tokens != nil {
+		return loginResponse(result.Tokens, user)
+	}
+	return gin.H{
+		"mfa_required":       result.MFARequired,
+		"mfa_setup_required": result.MFASetupRequired,
+		"mfa_token":          result.MFAToken,
+	}
 }
 
 // Logout 用户登出
 func (a *AuthAPI) Logout(c *gin.Context) {
-	// 可以在这里实现token黑名单等功能
+	claims, ok := c.MustGet("claims").(*auth.Claims)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "无效的认证信息")
+		return
+	}
+
+	// 吊销当前token及其会话
+	if err := a.authService.Logout(claims); err != nil {
+		response.Error(c, http.StatusInternalServerError, "登出失败")
+		return
+	}
+
+	a.logService.LogLogout(claims.UserID, claims.Username, c.ClientIP(), c.Request.UserAgent())
+
 	response.Success(c, nil)
 }
 
-// RefreshToken 刷新token
+// RefreshToken 使用刷新token换取新的token对
 func (a *AuthAPI) RefreshToken(c *gin.Context) {
-	tokenString := c.GetHeader("Authorization")
-	if tokenString == "" {
-		response.Error(c, http.StatusUnauthorized, "缺少token")
-		return
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 
-	// 移除Bearer前缀
-	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-
-	// 解析旧token
-	claims, err := auth.ParseToken(tokenString)
-	if err != nil {
-		response.Error(c, http.StatusUnauthorized, "无效的token")
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
 		return
 	}
 
-	// 生成新token
-	newToken, err := auth.GenerateToken(claims.UserID, claims.Username, claims.Name, claims.Roles)
+	tokens, _, err := a.authService.RefreshTokens(req.RefreshToken)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "生成token失败")
+		switch {
+		case errors.Is(err, auth.ErrRefreshTokenReused):
+			response.Error(c, http.StatusUnauthorized, "刷新token已被使用，请重新登录")
+		case errors.Is(err, auth.ErrRefreshTokenInvalid):
+			response.Error(c, http.StatusUnauthorized, "无效的刷新token")
+		default:
+			response.Error(c, http.StatusUnauthorized, "刷新token失败")
+		}
 		return
 	}
 
-	response.Success(c, gin.H{
-		"token": newToken,
-	})
+	response.Success(c, tokens)
 }
 
 // GetUserInfo 获取当前用户信息
@@ -0,0 +1,312 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/logger"
+	"building-asset-backend/pkg/response"
+	"building-asset-backend/pkg/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportColumn 导出列，Value返回字符串、数字、布尔或时间
+type exportColumn[T any] struct {
+	Title   string // 中文表头
+	TitleEn string // 英文表头
+	Value   func(item *T) interface{}
+}
+
+// exportList 按format参数导出列表，流式写出符合筛选条件的全部记录
+// 表头按lang参数或Accept-Language选择中文或英文；读取数据前出错时返回JSON错误
+func exportList[T any](c *gin.Context, name string, columns []exportColumn[T], export func(maxRows int, fn func([]*T) error) error) {
+	format := c.Query("format")
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		response.Error(c, http.StatusBadRequest, spreadsheet.ErrUnsupportedFormat.Error())
+		return
+	}
+	english := exportInEnglish(c)
+
+	var writer spreadsheet.Writer
+	begin := func() error {
+		if writer != nil {
+			return nil
+		}
+		filename := fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102150405"), format)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Header("Content-Type", spreadsheet.ContentType(format))
+		c.Status(http.StatusOK)
+
+		var err error
+		if writer, err = spreadsheet.NewWriter(c.Writer, format, name); err != nil {
+			return err
+		}
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.Title
+			if english {
+				header[i] = column.TitleEn
+			}
+		}
+		return writer.WriteRow(header)
+	}
+
+	err := export(config.Get().Export.MaxRows, func(items []*T) error {
+		if err := begin(); err != nil {
+			return err
+		}
+		for _, item := range items {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				row[i] = exportValue(column.Value(item), english)
+			}
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		if err = begin(); err == nil {
+			err = writer.Close()
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	// CSV已开始写出时无法再返回错误信息，只能中断响应
+	if c.Writer.Written() {
+		logger.Errorf("导出%s失败: %v", name, err)
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Del("Content-Disposition")
+	switch {
+	case errors.Is(err, service.ErrExportTooLarge):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	case database.IsRecordNotFoundError(err):
+		response.Error(c, http.StatusNotFound, "记录不存在")
+	default:
+		response.ErrorWithData(c, http.StatusInternalServerError, "导出失败", err.Error())
+	}
+}
+
+// exportInEnglish 是否使用英文表头
+func exportInEnglish(c *gin.Context) bool {
+	lang := c.Query("lang")
+	if lang == "" {
+		lang = c.GetHeader("Accept-Language")
+	}
+	return strings.HasPrefix(strings.ToLower(lang), "en")
+}
+
+// exportValue 格式化单元格
+func exportValue(value interface{}, english bool) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		switch {
+		case english && v:
+			return "Yes"
+		case english:
+			return "No"
+		case v:
+			return "是"
+		}
+		return "否"
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return exportValue(*v, english)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+var assetExportColumns = []exportColumn[model.Asset]{
+	{"资产ID", "ID", func(a *model.Asset) interface{} { return a.ID }},
+	{"资产编码", "Asset Code", func(a *model.Asset) interface{} { return a.AssetCode }},
+	{"资产名称", "Asset Name", func(a *model.Asset) interface{} { return a.AssetName }},
+	{"所属街道", "Street", func(a *model.Asset) interface{} {
+		if a.Street != nil {
+			return a.Street.Name
+		}
+		return ""
+	}},
+	{"详细地址", "Address", func(a *model.Asset) interface{} { return a.Address }},
+	{"经度", "Longitude", func(a *model.Asset) interface{} { return a.Longitude }},
+	{"纬度", "Latitude", func(a *model.Asset) interface{} { return a.Latitude }},
+	{"土地性质", "Land Nature", func(a *model.Asset) interface{} { return a.LandNature }},
+	{"总面积(平方米)", "Total Area (m²)", func(a *model.Asset) interface{} { return a.TotalArea }},
+	{"可租面积(平方米)", "Rentable Area (m²)", func(a *model.Asset) interface{} { return a.RentableArea }},
+	{"资产标签", "Tags", func(a *model.Asset) interface{} { return strings.Join(a.AssetTags, ",") }},
+	{"状态", "Status", func(a *model.Asset) interface{} { return a.Status }},
+	{"描述", "Description", func(a *model.Asset) interface{} { return a.Description }},
+	{"创建时间", "Created At", func(a *model.Asset) interface{} { return a.CreatedAt }},
+	{"更新时间", "Updated At", func(a *model.Asset) interface{} { return a.UpdatedAt }},
+}
+
+var buildingExportColumns = []exportColumn[model.Building]{
+	{"建筑ID", "ID", func(b *model.Building) interface{} { return b.ID }},
+	{"建筑编码", "Building Code", func(b *model.Building) interface{} { return b.BuildingCode }},
+	{"建筑名称", "Building Name", func(b *model.Building) interface{} { return b.BuildingName }},
+	{"资产编码", "Asset Code", func(b *model.Building) interface{} {
+		if b.Asset != nil {
+			return b.Asset.AssetCode
+		}
+		return ""
+	}},
+	{"资产名称", "Asset Name", func(b *model.Building) interface{} {
+		if b.Asset != nil {
+			return b.Asset.AssetName
+		}
+		return ""
+	}},
+	{"建筑类型", "Building Type", func(b *model.Building) interface{} { return b.BuildingType }},
+	{"总楼层数", "Total Floors", func(b *model.Building) interface{} { return b.TotalFloors }},
+	{"地下楼层数", "Underground Floors", func(b *model.Building) interface{} { return b.UndergroundFloors }},
+	{"总面积(平方米)", "Total Area (m²)", func(b *model.Building) interface{} { return b.TotalArea }},
+	{"可租面积(平方米)", "Rentable Area (m²)", func(b *model.Building) interface{} { return b.RentableArea }},
+	{"建造年份", "Construction Year", func(b *model.Building) interface{} { return b.ConstructionYear }},
+	{"电梯数量", "Elevators", func(b *model.Building) interface{} { return b.ElevatorCount }},
+	{"停车位数量", "Parking Spaces", func(b *model.Building) interface{} { return b.ParkingSpaces }},
+	{"绿化率(%)", "Green Rate (%)", func(b *model.Building) interface{} { return b.GreenRate }},
+	{"物业公司", "Property Company", func(b *model.Building) interface{} { return b.PropertyCompany }},
+	{"物业电话", "Property Phone", func(b *model.Building) interface{} { return b.PropertyPhone }},
+	{"配套设施", "Features", func(b *model.Building) interface{} { return strings.Join(b.Features, ",") }},
+	{"状态", "Status", func(b *model.Building) interface{} { return b.Status }},
+	{"描述", "Description", func(b *model.Building) interface{} { return b.Description }},
+	{"创建时间", "Created At", func(b *model.Building) interface{} { return b.CreatedAt }},
+}
+
+var floorExportColumns = []exportColumn[model.Floor]{
+	{"楼层ID", "ID", func(f *model.Floor) interface{} { return f.ID }},
+	{"建筑编码", "Building Code", func(f *model.Floor) interface{} {
+		if f.Building != nil {
+			return f.Building.BuildingCode
+		}
+		return ""
+	}},
+	{"建筑名称", "Building Name", func(f *model.Floor) interface{} {
+		if f.Building != nil {
+			return f.Building.BuildingName
+		}
+		return ""
+	}},
+	{"楼层号", "Floor Number", func(f *model.Floor) interface{} { return f.FloorNumber }},
+	{"楼层名称", "Floor Name", func(f *model.Floor) interface{} { return f.FloorName }},
+	{"楼层面积(平方米)", "Floor Area (m²)", func(f *model.Floor) interface{} { return f.FloorArea }},
+	{"可租面积(平方米)", "Rentable Area (m²)", func(f *model.Floor) interface{} { return f.RentableArea }},
+	{"已租面积(平方米)", "Rented Area (m²)", func(f *model.Floor) interface{} { return f.RentedArea }},
+	{"出租率(%)", "Occupancy Rate (%)", func(f *model.Floor) interface{} { return f.OccupancyRate }},
+	{"平均租金(元/平方米/月)", "Avg Rent (CNY/m²/month)", func(f *model.Floor) interface{} { return f.AvgRentPrice }},
+	{"状态", "Status", func(f *model.Floor) interface{} { return f.Status }},
+	{"描述", "Description", func(f *model.Floor) interface{} { return f.Description }},
+	{"创建时间", "Created At", func(f *model.Floor) interface{} { return f.CreatedAt }},
+}
+
+var roomExportColumns = []exportColumn[model.Room]{
+	{"房间ID", "ID", func(r *model.Room) interface{} { return r.ID }},
+	{"建筑编码", "Building Code", func(r *model.Room) interface{} {
+		if r.Floor != nil && r.Floor.Building != nil {
+			return r.Floor.Building.BuildingCode
+		}
+		return ""
+	}},
+	{"楼层号", "Floor Number", func(r *model.Room) interface{} {
+		if r.Floor != nil {
+			return r.Floor.FloorNumber
+		}
+		return ""
+	}},
+	{"房间号", "Room Number", func(r *model.Room) interface{} { return r.RoomNumber }},
+	{"房间类型", "Room Type", func(r *model.Room) interface{} { return r.RoomType }},
+	{"房间面积(平方米)", "Room Area (m²)", func(r *model.Room) interface{} { return r.RoomArea }},
+	{"租金(元/月)", "Rent (CNY/month)", func(r *model.Room) interface{} { return r.RentPrice }},
+	{"装修情况", "Decoration", func(r *model.Room) interface{} { return r.Decoration }},
+	{"朝向", "Orientation", func(r *model.Room) interface{} { return r.Orientation }},
+	{"是否有窗", "Has Window", func(r *model.Room) interface{} { return r.HasWindow }},
+	{"是否有空调", "Has AC", func(r *model.Room) interface{} { return r.HasAC }},
+	{"状态", "Status", func(r *model.Room) interface{} { return r.Status }},
+	{"描述", "Description", func(r *model.Room) interface{} { return r.Description }},
+	{"创建时间", "Created At", func(r *model.Room) interface{} { return r.CreatedAt }},
+}
+
+var userExportColumns = []exportColumn[model.User]{
+	{"用户ID", "ID", func(u *model.User) interface{} { return u.ID }},
+	{"用户名", "Username", func(u *model.User) interface{} { return u.Username }},
+	{"姓名", "Name", func(u *model.User) interface{} { return u.Name }},
+	{"手机号", "Phone", func(u *model.User) interface{} { return u.Phone }},
+	{"邮箱", "Email", func(u *model.User) interface{} { return u.Email }},
+	{"所属组织", "Organization", func(u *model.User) interface{} {
+		if u.Organization != nil {
+			return u.Organization.Name
+		}
+		return ""
+	}},
+	{"角色", "Roles", func(u *model.User) interface{} {
+		names := make([]string, len(u.Roles))
+		for i, role := range u.Roles {
+			names[i] = role.Name
+		}
+		return strings.Join(names, ",")
+	}},
+	{"状态", "Status", func(u *model.User) interface{} { return u.Status }},
+	{"两步验证", "2FA Enabled", func(u *model.User) interface{} { return u.TOTPEnabled }},
+	{"最后登录时间", "Last Login Time", func(u *model.User) interface{} { return u.LastLoginTime }},
+	{"最后登录IP", "Last Login IP", func(u *model.User) interface{} { return u.LastLoginIP }},
+	{"创建时间", "Created At", func(u *model.User) interface{} { return u.CreatedAt }},
+}
+
+var operationLogExportColumns = []exportColumn[model.OperationLog]{
+	{"日志ID", "ID", func(l *model.OperationLog) interface{} { return l.ID }},
+	{"操作时间", "Operation Time", func(l *model.OperationLog) interface{} { return l.OperationTime }},
+	{"用户名", "Username", func(l *model.OperationLog) interface{} { return l.Username }},
+	{"模块", "Module", func(l *model.OperationLog) interface{} { return l.Module }},
+	{"操作类型", "Action", func(l *model.OperationLog) interface{} { return l.Action }},
+	{"操作描述", "Description", func(l *model.OperationLog) interface{} { return l.Description }},
+	{"请求方法", "Method", func(l *model.OperationLog) interface{} { return l.RequestMethod }},
+	{"请求URL", "URL", func(l *model.OperationLog) interface{} { return l.RequestURL }},
+	{"请求参数", "Params", func(l *model.OperationLog) interface{} { return l.RequestParams }},
+	{"响应状态码", "Status", func(l *model.OperationLog) interface{} { return l.ResponseStatus }},
+	{"响应时间(毫秒)", "Response Time (ms)", func(l *model.OperationLog) interface{} { return l.ResponseTime }},
+	{"客户端IP", "Client IP", func(l *model.OperationLog) interface{} { return l.ClientIP }},
+	{"请求ID", "Request ID", func(l *model.OperationLog) interface{} { return l.RequestID }},
+}
+
+var loginLogExportColumns = []exportColumn[model.LoginLog]{
+	{"日志ID", "ID", func(l *model.LoginLog) interface{} { return l.ID }},
+	{"登录时间", "Login Time", func(l *model.LoginLog) interface{} { return l.LoginTime }},
+	{"用户名", "Username", func(l *model.LoginLog) interface{} { return l.Username }},
+	{"登录类型", "Type", func(l *model.LoginLog) interface{} { return l.LoginType }},
+	{"状态", "Status", func(l *model.LoginLog) interface{} { return l.Status }},
+	{"消息", "Message", func(l *model.LoginLog) interface{} { return l.Message }},
+	{"客户端IP", "Client IP", func(l *model.LoginLog) interface{} { return l.ClientIP }},
+	{"User-Agent", "User-Agent", func(l *model.LoginLog) interface{} { return l.UserAgent }},
+}
@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"net/http"
+
+	"building-asset-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthAPI struct {
+	healthService *service.HealthService
+}
+
+func NewHealthAPI() *HealthAPI {
+	return &HealthAPI{
+		healthService: service.NewHealthService(),
+	}
+}
+
+// Live 存活检查，进程能处理请求即返回200，不检查依赖，避免依赖故障时实例被反复重启
+func (h *HealthAPI) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": service.HealthStatusOK})
+}
+
+// Ready 就绪检查，数据库、Redis或迁移检查失败时返回503，编排系统据此停止转发流量
+func (h *HealthAPI) Ready(c *gin.Context) {
+	report := h.healthService.Ready(c.Request.Context())
+	if !report.OK() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
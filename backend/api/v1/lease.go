@@ -0,0 +1,292 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LeaseAPI struct {
+	leaseService *service.LeaseService
+}
+
+func NewLeaseAPI() *LeaseAPI {
+	return &LeaseAPI{
+		leaseService: service.NewLeaseService(),
+	}
+}
+
+// scoped 返回应用当前请求数据权限的租赁服务
+func (l *LeaseAPI) scoped(c *gin.Context) *service.LeaseService {
+	return l.leaseService.WithContext(c.Request.Context())
+}
+
+// respondLeaseError 租赁相关操作的错误响应
+func respondLeaseError(c *gin.Context, err error, notFound, failed string) {
+	switch {
+	case database.IsRecordNotFoundError(err):
+		response.Error(c, http.StatusNotFound, notFound)
+	case errors.Is(err, service.ErrInvalidLease), errors.Is(err, service.ErrLeaseRoomNotFound):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrLeaseRoomConflict), errors.Is(err, service.ErrTenantInUse):
+		response.Error(c, http.StatusConflict, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, failed)
+	}
+}
+
+// leaseRequest 合同请求参数，日期格式为 2006-01-02
+type leaseRequest struct {
+	LeaseNo      string  `json:"lease_no"`
+	TenantID     uint    `json:"tenant_id"`
+	Lessor       string  `json:"lessor"`
+	StartDate    string  `json:"start_date"`
+	EndDate      string  `json:"end_date"`
+	MonthlyRent  float64 `json:"monthly_rent"`
+	Deposit      float64 `json:"deposit"`
+	PaymentCycle string  `json:"payment_cycle"`
+	Status       string  `json:"status"`
+	Description  string  `json:"description"`
+	RoomIDs      []uint  `json:"room_ids"`
+}
+
+// toModel 转换为合同模型，日期为空时保持零值
+func (r *leaseRequest) toModel() (*model.Lease, error) {
+	lease := &model.Lease{
+		LeaseNo:      r.LeaseNo,
+		TenantID:     r.TenantID,
+		Lessor:       r.Lessor,
+		MonthlyRent:  r.MonthlyRent,
+		Deposit:      r.Deposit,
+		PaymentCycle: r.PaymentCycle,
+		Status:       r.Status,
+		Description:  r.Description,
+	}
+
+	var err error
+	if r.StartDate != "" {
+		if lease.StartDate, err = time.ParseInLocation("2006-01-02", r.StartDate, time.Local); err != nil {
+			return nil, err
+		}
+	}
+	if r.EndDate != "" {
+		if lease.EndDate, err = time.ParseInLocation("2006-01-02", r.EndDate, time.Local); err != nil {
+			return nil, err
+		}
+	}
+	return lease, nil
+}
+
+// Tenant CRUD operations
+
+// GetTenants 获取租户列表
+func (l *LeaseAPI) GetTenants(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	name := c.Query("name")
+	status := c.Query("status")
+
+	tenants, total, err := l.scoped(c).GetTenants(page, pageSize, name, status)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取租户列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":      tenants,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetTenant 获取租户详情
+func (l *LeaseAPI) GetTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的租户ID")
+		return
+	}
+
+	tenant, err := l.scoped(c).GetTenantByID(uint(id))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "租户不存在")
+		return
+	}
+
+	response.Success(c, tenant)
+}
+
+// CreateTenant 创建租户
+func (l *LeaseAPI) CreateTenant(c *gin.Context) {
+	var req model.Tenant
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	tenant, err := l.scoped(c).CreateTenant(&req)
+	if err != nil {
+		respondLeaseError(c, err, "租户不存在", "创建租户失败")
+		return
+	}
+
+	response.Success(c, tenant)
+}
+
+// UpdateTenant 更新租户
+func (l *LeaseAPI) UpdateTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的租户ID")
+		return
+	}
+
+	var req model.Tenant
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	tenant, err := l.scoped(c).UpdateTenant(uint(id), &req)
+	if err != nil {
+		respondLeaseError(c, err, "租户不存在", "更新租户失败")
+		return
+	}
+
+	response.Success(c, tenant)
+}
+
+// DeleteTenant 删除租户
+func (l *LeaseAPI) DeleteTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的租户ID")
+		return
+	}
+
+	if err := l.scoped(c).DeleteTenant(uint(id)); err != nil {
+		respondLeaseError(c, err, "租户不存在", "删除租户失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Lease CRUD operations
+
+// GetLeases 获取租赁合同列表
+func (l *LeaseAPI) GetLeases(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	leaseNo := c.Query("lease_no")
+	tenantID, _ := strconv.ParseUint(c.Query("tenant_id"), 10, 64)
+	roomID, _ := strconv.ParseUint(c.Query("room_id"), 10, 64)
+	status := c.Query("status")
+
+	leases, total, err := l.scoped(c).GetLeases(page, pageSize, leaseNo, uint(tenantID), uint(roomID), status)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取合同列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":      leases,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetLease 获取租赁合同详情
+func (l *LeaseAPI) GetLease(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的合同ID")
+		return
+	}
+
+	lease, err := l.scoped(c).GetLeaseByID(uint(id))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "合同不存在")
+		return
+	}
+
+	response.Success(c, lease)
+}
+
+// CreateLease 创建租赁合同
+func (l *LeaseAPI) CreateLease(c *gin.Context) {
+	var req leaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.LeaseNo == "" || req.TenantID == 0 {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	lease, err := req.toModel()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "日期格式错误，应为YYYY-MM-DD")
+		return
+	}
+
+	lease, err = l.scoped(c).CreateLease(lease, req.RoomIDs)
+	if err != nil {
+		respondLeaseError(c, err, "合同不存在", "创建合同失败")
+		return
+	}
+
+	response.Success(c, lease)
+}
+
+// UpdateLease 更新租赁合同，未传room_ids时保持原有房间
+func (l *LeaseAPI) UpdateLease(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的合同ID")
+		return
+	}
+
+	var req leaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	updates, err := req.toModel()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "日期格式错误，应为YYYY-MM-DD")
+		return
+	}
+
+	lease, err := l.scoped(c).UpdateLease(uint(id), updates, req.RoomIDs)
+	if err != nil {
+		respondLeaseError(c, err, "合同不存在", "更新合同失败")
+		return
+	}
+
+	response.Success(c, lease)
+}
+
+// DeleteLease 删除租赁合同
+func (l *LeaseAPI) DeleteLease(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的合同ID")
+		return
+	}
+
+	if err := l.scoped(c).DeleteLease(uint(id)); err != nil {
+		respondLeaseError(c, err, "合同不存在", "删除合同失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -1,12 +1,14 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"building-asset-backend/internal/model"
 	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/database"
 	"building-asset-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +16,7 @@ import (
 
 type SystemAPI struct {
 	userService *service.UserService
+	authService *service.AuthService
 	roleService *service.RoleService
 	menuService *service.MenuService
 	logService  *service.LogService
@@ -22,6 +25,7 @@ type SystemAPI struct {
 func NewSystemAPI() *SystemAPI {
 	return &SystemAPI{
 		userService: service.NewUserService(),
+		authService: service.NewAuthService(),
 		roleService: service.NewRoleService(),
 		menuService: service.NewMenuService(),
 		logService:  service.NewLogService(),
@@ -30,6 +34,11 @@ func NewSystemAPI() *SystemAPI {
 
 // User management
 
+// users 返回应用当前请求数据权限的用户服务
+func (s *SystemAPI) users(c *gin.Context) *service.UserService {
+	return s.userService.WithContext(c.Request.Context())
+}
+
 func (s *SystemAPI) GetUsers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
@@ -38,7 +47,14 @@ func (s *SystemAPI) GetUsers(c *gin.Context) {
 	status := c.Query("status")
 	orgID, _ := strconv.ParseUint(c.Query("org_id"), 10, 64)
 
-	users, total, err := s.userService.GetUsers(page, pageSize, username, realName, status, uint(orgID))
+	if c.Query("format") != "" {
+		exportList(c, "users", userExportColumns, func(maxRows int, fn func([]*model.User) error) error {
+			return s.users(c).ExportUsers(username, realName, status, uint(orgID), maxRows, fn)
+		})
+		return
+	}
+
+	users, total, err := s.users(c).GetUsers(page, pageSize, username, realName, status, uint(orgID))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取用户列表失败")
 		return
@@ -59,7 +75,7 @@ func (s *SystemAPI) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := s.userService.GetUserByID(uint(id))
+	user, err := s.users(c).GetUserByID(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusNotFound, "用户不存在")
 		return
@@ -68,15 +84,55 @@ func (s *SystemAPI) GetUser(c *gin.Context) {
 	response.Success(c, user)
 }
 
+// GetUserHistory 获取用户变更记录
+func (s *SystemAPI) GetUserHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	logs, total, err := s.users(c).GetUserHistory(uint(id), page, pageSize)
+	if err != nil {
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "获取变更记录失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
 func (s *SystemAPI) CreateUser(c *gin.Context) {
-	var req model.User
+	var req struct {
+		model.User
+		Password string `json:"password" binding:"required"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, "请求参数错误")
 		return
 	}
+	req.User.Password = req.Password
 
-	user, err := s.userService.CreateUser(&req)
+	user, err := s.users(c).CreateUser(&req.User)
 	if err != nil {
+		if errors.Is(err, service.ErrPasswordPolicy) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrOutOfDataScope) {
+			response.Error(c, http.StatusForbidden, err.Error())
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "创建用户失败")
 		return
 	}
@@ -97,9 +153,16 @@ func (s *SystemAPI) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := s.userService.UpdateUser(uint(id), &req)
+	user, err := s.users(c).UpdateUser(uint(id), &req)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "更新用户失败")
+		switch {
+		case database.IsRecordNotFoundError(err):
+			response.Error(c, http.StatusNotFound, "用户不存在")
+		case errors.Is(err, service.ErrOutOfDataScope):
+			response.Error(c, http.StatusForbidden, err.Error())
+		default:
+			response.Error(c, http.StatusInternalServerError, "更新用户失败")
+		}
 		return
 	}
 
@@ -113,7 +176,11 @@ func (s *SystemAPI) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := s.userService.DeleteUser(uint(id)); err != nil {
+	if err := s.users(c).DeleteUser(uint(id)); err != nil {
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "删除用户失败")
 		return
 	}
@@ -136,7 +203,15 @@ func (s *SystemAPI) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	if err := s.userService.ResetPassword(uint(id), req.Password); err != nil {
+	if err := s.users(c).ResetPassword(uint(id), req.Password); err != nil {
+		if errors.Is(err, service.ErrPasswordPolicy) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "重置密码失败")
 		return
 	}
@@ -144,6 +219,69 @@ func (s *SystemAPI) ResetPassword(c *gin.Context) {
 	response.Success(c, nil)
 }
 
+func (s *SystemAPI) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := s.users(c).UnlockUser(uint(id)); err != nil {
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "解锁用户失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+func (s *SystemAPI) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	if err := s.users(c).RevokeUserSessions(uint(id)); err != nil {
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "吊销用户会话失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+func (s *SystemAPI) ResetUserTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	// 确认用户在数据权限范围内
+	if _, err := s.users(c).GetUserByID(uint(id)); err != nil {
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+
+	if err := s.authService.ResetTOTP(uint(id)); err != nil {
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "重置两步验证失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 // Role management
 
 func (s *SystemAPI) GetRoles(c *gin.Context) {
@@ -191,6 +329,10 @@ func (s *SystemAPI) CreateRole(c *gin.Context) {
 
 	role, err := s.roleService.CreateRole(&req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidDataScope) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "创建角色失败")
 		return
 	}
@@ -213,6 +355,10 @@ func (s *SystemAPI) UpdateRole(c *gin.Context) {
 
 	role, err := s.roleService.UpdateRole(uint(id), &req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidDataScope) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "更新角色失败")
 		return
 	}
@@ -220,6 +366,33 @@ func (s *SystemAPI) UpdateRole(c *gin.Context) {
 	response.Success(c, role)
 }
 
+func (s *SystemAPI) UpdateRoleTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的角色ID")
+		return
+	}
+
+	var req struct {
+		Required bool `json:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	if err := s.roleService.SetRoleTwoFactor(uint(id), req.Required); err != nil {
+		if database.IsRecordNotFoundError(err) {
+			response.Error(c, http.StatusNotFound, "角色不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "更新角色两步验证要求失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 func (s *SystemAPI) DeleteRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -425,6 +598,13 @@ func (s *SystemAPI) GetOperationLogs(c *gin.Context) {
 		end = &t
 	}
 
+	if c.Query("format") != "" {
+		exportList(c, "operation_logs", operationLogExportColumns, func(maxRows int, fn func([]*model.OperationLog) error) error {
+			return s.logService.ExportOperationLogs(username, module, start, end, maxRows, fn)
+		})
+		return
+	}
+
 	logs, total, err := s.logService.GetOperationLogs(page, pageSize, username, module, start, end)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取操作日志失败")
@@ -456,6 +636,13 @@ func (s *SystemAPI) GetLoginLogs(c *gin.Context) {
 		end = &t
 	}
 
+	if c.Query("format") != "" {
+		exportList(c, "login_logs", loginLogExportColumns, func(maxRows int, fn func([]*model.LoginLog) error) error {
+			return s.logService.ExportLoginLogs(username, start, end, maxRows, fn)
+		})
+		return
+	}
+
 	logs, total, err := s.logService.GetLoginLogs(page, pageSize, username, start, end)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取登录日志失败")
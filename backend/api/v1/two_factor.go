@@ -0,0 +1,174 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyTwoFactor 登录时提交两步验证码或恢复码
+func (a *AuthAPI) VerifyTwoFactor(c *gin.Context) {
+	var req struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	tokens, user, err := a.authService.VerifyTwoFactor(req.MFAToken, req.Code, req.RecoveryCode, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if user != nil {
+			a.logService.LogTwoFactor(user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), "failed", err.Error())
+		}
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	message := "两步验证成功"
+	if req.RecoveryCode != "" {
+		message = "使用恢复码完成两步验证"
+	}
+	a.logService.LogTwoFactor(user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), "success", message)
+
+	response.Success(c, loginResponse(tokens, user))
+}
+
+// BeginRequiredTwoFactorSetup 角色要求两步验证时，登录过程中获取绑定密钥
+func (a *AuthAPI) BeginRequiredTwoFactorSetup(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	enrollment, err := a.authService.BeginRequiredTOTPEnrollment(req.MFAToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, enrollment)
+}
+
+// ConfirmRequiredTwoFactorSetup 登录过程中确认绑定两步验证并完成登录
+func (a *AuthAPI) ConfirmRequiredTwoFactorSetup(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	tokens, user, codes, err := a.authService.ConfirmRequiredTOTPEnrollment(req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	a.logService.LogTwoFactor(user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), "success", "启用两步验证并登录")
+
+	data := loginResponse(tokens, user)
+	data["recovery_codes"] = codes
+	response.Success(c, data)
+}
+
+// SetupTwoFactor 当前用户获取两步验证绑定密钥
+func (a *AuthAPI) SetupTwoFactor(c *gin.Context) {
+	enrollment, err := a.authService.BeginTOTPEnrollment(c.GetUint("userID"))
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, enrollment)
+}
+
+// ConfirmTwoFactor 当前用户确认启用两步验证
+func (a *AuthAPI) ConfirmTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	codes, err := a.authService.ConfirmTOTPEnrollment(c.GetUint("userID"), req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor 当前用户关闭两步验证
+func (a *AuthAPI) DisableTwoFactor(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	if err := a.authService.DisableTOTP(c.GetUint("userID"), req.Password, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// RegenerateRecoveryCodes 当前用户重新生成恢复码
+func (a *AuthAPI) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	codes, err := a.authService.RegenerateRecoveryCodes(c.GetUint("userID"), req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// respondTwoFactorError 两步验证错误响应
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMFATokenInvalid):
+		response.Error(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		response.Error(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrAccountTemporarilyLocked), errors.Is(err, service.ErrIPBlocked):
+		response.Error(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrLoginGuardUnavailable):
+		response.Error(c, http.StatusServiceUnavailable, err.Error())
+	default:
+		response.Error(c, http.StatusBadRequest, err.Error())
+	}
+}
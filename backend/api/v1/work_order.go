@@ -0,0 +1,264 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkOrderAPI struct {
+	workOrderService *service.WorkOrderService
+}
+
+func NewWorkOrderAPI() *WorkOrderAPI {
+	return &WorkOrderAPI{
+		workOrderService: service.NewWorkOrderService(),
+	}
+}
+
+// scoped 返回应用当前请求数据权限的工单服务
+func (w *WorkOrderAPI) scoped(c *gin.Context) *service.WorkOrderService {
+	return w.workOrderService.WithContext(c.Request.Context())
+}
+
+// respondWorkOrderError 工单相关操作的错误响应
+func respondWorkOrderError(c *gin.Context, err error, failed string) {
+	switch {
+	case database.IsRecordNotFoundError(err):
+		response.Error(c, http.StatusNotFound, "工单不存在")
+	case errors.Is(err, service.ErrInvalidWorkOrder):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWorkOrderTransition):
+		response.Error(c, http.StatusConflict, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, failed)
+	}
+}
+
+// workOrderID 读取路径中的工单ID，无效时已写入响应
+func workOrderID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "无效的工单ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetWorkOrders 获取工单列表，overdue=true/false 按是否超出SLA筛选
+func (w *WorkOrderAPI) GetWorkOrders(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	filter := service.WorkOrderFilter{
+		Keyword:  c.Query("keyword"),
+		Status:   c.Query("status"),
+		Priority: c.Query("priority"),
+		Category: c.Query("category"),
+	}
+	for param, target := range map[string]*uint{
+		"building_id": &filter.BuildingID, "floor_id": &filter.FloorID, "room_id": &filter.RoomID,
+		"assignee_id": &filter.AssigneeID, "reporter_id": &filter.ReporterID,
+	} {
+		id, _ := strconv.ParseUint(c.Query(param), 10, 64)
+		*target = uint(id)
+	}
+	if overdue, err := strconv.ParseBool(c.Query("overdue")); err == nil {
+		filter.Overdue = &overdue
+	}
+
+	orders, total, err := w.scoped(c).GetWorkOrders(page, pageSize, filter)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取工单列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":      orders,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetWorkOrder 获取工单详情，包含评论和状态变更记录
+func (w *WorkOrderAPI) GetWorkOrder(c *gin.Context) {
+	id, ok := workOrderID(c)
+	if !ok {
+		return
+	}
+
+	order, err := w.scoped(c).GetWorkOrderByID(id)
+	if err != nil {
+		respondWorkOrderError(c, err, "获取工单失败")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// workOrderRequest 创建、修改工单的请求参数，位置只需传最细一级
+type workOrderRequest struct {
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	Category        string `json:"category"`
+	Priority        string `json:"priority"`
+	BuildingID      uint   `json:"building_id"`
+	FloorID         *uint  `json:"floor_id"`
+	RoomID          *uint  `json:"room_id"`
+	ReporterName    string `json:"reporter_name"`
+	ReporterPhone   string `json:"reporter_phone"`
+	AssigneeID      *uint  `json:"assignee_id"`
+	RoomMaintenance bool   `json:"room_maintenance"`
+}
+
+func (r *workOrderRequest) toModel() *model.WorkOrder {
+	return &model.WorkOrder{
+		Title:           r.Title,
+		Description:     r.Description,
+		Category:        r.Category,
+		Priority:        r.Priority,
+		BuildingID:      r.BuildingID,
+		FloorID:         r.FloorID,
+		RoomID:          r.RoomID,
+		ReporterName:    r.ReporterName,
+		ReporterPhone:   r.ReporterPhone,
+		AssigneeID:      r.AssigneeID,
+		RoomMaintenance: r.RoomMaintenance,
+	}
+}
+
+// CreateWorkOrder 创建工单
+func (w *WorkOrderAPI) CreateWorkOrder(c *gin.Context) {
+	var req workOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Title == "" {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	order, err := w.scoped(c).CreateWorkOrder(req.toModel())
+	if err != nil {
+		respondWorkOrderError(c, err, "创建工单失败")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// UpdateWorkOrder 修改工单基本信息
+func (w *WorkOrderAPI) UpdateWorkOrder(c *gin.Context) {
+	id, ok := workOrderID(c)
+	if !ok {
+		return
+	}
+
+	var req workOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	order, err := w.scoped(c).UpdateWorkOrder(id, req.toModel())
+	if err != nil {
+		respondWorkOrderError(c, err, "更新工单失败")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// AssignWorkOrder 派单或改派
+func (w *WorkOrderAPI) AssignWorkOrder(c *gin.Context) {
+	id, ok := workOrderID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AssigneeID uint   `json:"assignee_id" binding:"required"`
+		Comment    string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	order, err := w.scoped(c).AssignWorkOrder(id, req.AssigneeID, req.Comment)
+	if err != nil {
+		respondWorkOrderError(c, err, "派单失败")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// ChangeWorkOrderStatus 变更工单状态，status为in_progress、resolved或closed
+func (w *WorkOrderAPI) ChangeWorkOrderStatus(c *gin.Context) {
+	id, ok := workOrderID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status  string `json:"status" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	order, err := w.scoped(c).ChangeWorkOrderStatus(id, req.Status, req.Comment)
+	if err != nil {
+		respondWorkOrderError(c, err, "变更工单状态失败")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// AddWorkOrderComment 添加工单评论
+func (w *WorkOrderAPI) AddWorkOrderComment(c *gin.Context) {
+	id, ok := workOrderID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	comment, err := w.scoped(c).AddWorkOrderComment(id, req.Content)
+	if err != nil {
+		respondWorkOrderError(c, err, "添加评论失败")
+		return
+	}
+
+	response.Success(c, comment)
+}
+
+// DeleteWorkOrder 删除已关闭的工单
+func (w *WorkOrderAPI) DeleteWorkOrder(c *gin.Context) {
+	id, ok := workOrderID(c)
+	if !ok {
+		return
+	}
+
+	if err := w.scoped(c).DeleteWorkOrder(id); err != nil {
+		respondWorkOrderError(c, err, "删除工单失败")
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/logger"
+)
+
+// dataCommands work on application data and run after setup
+var dataCommands = map[string]func(args []string) error{
+	"user":              runUserCommand,
+	"role":              runRoleCommand,
+	"seed":              seed,
+	"logs":              runLogsCommand,
+	"snapshot-backfill": backfillSnapshots,
+}
+
+// usage prints the command line help
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), `Usage: server [-config file] [command]
+
+Commands:
+  serve                                  run the HTTP server (default)
+  migrate up|down|status|create          manage schema migrations
+  user create -username NAME [-name NAME] [-password PASS] [-org CODE] [-role CODE,...] [-email EMAIL] [-phone PHONE]
+  user reset-password [-password PASS] USERNAME
+  user disable USERNAME
+  user assign-role USERNAME ROLE...
+  role sync-permissions                  create missing built-in permissions and grant all to the admin role
+  seed [--demo]                          create default data, with --demo also a sample asset hierarchy
+  logs purge --days N                    delete operation and login logs older than N days
+  config validate                        check the configuration file
+  snapshot-backfill -from DATE [-to DATE]
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+// runCommand runs the server or a one-off maintenance command
+func runCommand(args []string) error {
+	switch args[0] {
+	case "serve":
+		return serve()
+	case "migrate":
+		return runMigrate(args[1:])
+	case "config":
+		return runConfigCommand(args[1:])
+	}
+
+	command, ok := dataCommands[args[0]]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	defer closeConnections()
+	return command(args[1:])
+}
+
+// runConfigCommand handles `config validate`
+func runConfigCommand(args []string) error {
+	if len(args) != 1 || args[0] != "validate" {
+		return fmt.Errorf("usage: config validate")
+	}
+	if err := config.Get().Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	fmt.Println("Configuration OK")
+	return nil
+}
+
+// runUserCommand handles `user create|reset-password|disable|assign-role`
+func runUserCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: user create|reset-password|disable|assign-role")
+	}
+	switch args[0] {
+	case "create":
+		return createUser(args[1:])
+	case "reset-password":
+		return resetPassword(args[1:])
+	case "disable":
+		return disableUser(args[1:])
+	case "assign-role":
+		return assignRoles(args[1:])
+	}
+	return fmt.Errorf("unknown user command %q", args[0])
+}
+
+// createUser creates a user, generating a one-time password when none is given
+//
+//	user create -username admin2 -role admin
+func createUser(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := fs.String("username", "", "login name")
+	name := fs.String("name", "", "display name (defaults to the username)")
+	password := fs.String("password", "", "password (generated and must be changed on first login when empty)")
+	org := fs.String("org", "", "organization code (defaults to the top-level organization)")
+	roles := fs.String("role", "", "comma separated role codes")
+	email := fs.String("email", "", "email address")
+	phone := fs.String("phone", "", "phone number")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+	if *name == "" {
+		*name = *username
+	}
+
+	orgID, err := organizationID(*org)
+	if err != nil {
+		return err
+	}
+	user := &model.User{
+		Username: *username,
+		Name:     *name,
+		Email:    *email,
+		Phone:    *phone,
+		OrgID:    orgID,
+		Status:   "active",
+	}
+	if *roles != "" {
+		if user.Roles, err = rolesByCode(strings.Split(*roles, ",")); err != nil {
+			return err
+		}
+	}
+
+	generated := *password == ""
+	user.Password = *password
+	if generated {
+		if user.Password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	plain := user.Password
+
+	userService := service.NewUserService()
+	created, err := userService.CreateUser(user)
+	if err != nil {
+		return err
+	}
+	if generated {
+		if _, err := userService.UpdateUser(created.ID, &model.User{MustChangePassword: true}); err != nil {
+			return err
+		}
+		fmt.Printf("Created user %s with one-time password: %s\n", created.Username, plain)
+		return nil
+	}
+	fmt.Printf("Created user %s\n", created.Username)
+	return nil
+}
+
+// resetPassword resets a password, unlocks the account and revokes its sessions
+//
+//	user reset-password [-password PASS] USERNAME
+func resetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password (generated when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: user reset-password [-password PASS] USERNAME")
+	}
+
+	userService := service.NewUserService()
+	user, err := userService.GetUserByUsername(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("user %s: %w", fs.Arg(0), err)
+	}
+
+	plain := *password
+	if plain == "" {
+		if plain, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	if err := userService.ResetPassword(user.ID, plain); err != nil {
+		return err
+	}
+	if err := userService.UnlockUser(user.ID); err != nil {
+		return err
+	}
+
+	if *password == "" {
+		fmt.Printf("Password of %s reset to: %s\n", user.Username, plain)
+	} else {
+		fmt.Printf("Password of %s reset\n", user.Username)
+	}
+	fmt.Println("The user must change it on next login")
+	return nil
+}
+
+// disableUser disables a user and revokes its sessions
+func disableUser(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: user disable USERNAME")
+	}
+
+	userService := service.NewUserService()
+	user, err := userService.GetUserByUsername(args[0])
+	if err != nil {
+		return fmt.Errorf("user %s: %w", args[0], err)
+	}
+	if _, err := userService.UpdateUser(user.ID, &model.User{Status: "inactive"}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Disabled user %s\n", user.Username)
+	return nil
+}
+
+// assignRoles adds roles to a user, keeping the roles it already has
+func assignRoles(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: user assign-role USERNAME ROLE...")
+	}
+
+	userService := service.NewUserService()
+	user, err := userService.GetUserByUsername(args[0])
+	if err != nil {
+		return fmt.Errorf("user %s: %w", args[0], err)
+	}
+	roles, err := rolesByCode(args[1:])
+	if err != nil {
+		return err
+	}
+
+	merged := user.Roles
+	for _, role := range roles {
+		exists := false
+		for _, current := range user.Roles {
+			exists = exists || current.ID == role.ID
+		}
+		if !exists {
+			merged = append(merged, role)
+		}
+	}
+	if _, err := userService.UpdateUser(user.ID, &model.User{Roles: merged}); err != nil {
+		return err
+	}
+
+	codes := make([]string, 0, len(merged))
+	for _, role := range merged {
+		codes = append(codes, role.Code)
+	}
+	fmt.Printf("Roles of %s: %s\n", user.Username, strings.Join(codes, ", "))
+	return nil
+}
+
+// runRoleCommand handles `role sync-permissions`
+func runRoleCommand(args []string) error {
+	if len(args) != 1 || args[0] != "sync-permissions" {
+		return fmt.Errorf("usage: role sync-permissions")
+	}
+
+	created, err := service.NewRoleService().SyncPermissions()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %d permission(s), admin role has all permissions\n", created)
+	return nil
+}
+
+// runLogsCommand handles `logs purge --days N`
+func runLogsCommand(args []string) error {
+	if len(args) == 0 || args[0] != "purge" {
+		return fmt.Errorf("usage: logs purge --days N")
+	}
+
+	fs := flag.NewFlagSet("logs purge", flag.ContinueOnError)
+	days := fs.Int("days", 0, "delete logs older than this many days")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *days <= 0 {
+		return fmt.Errorf("--days must be positive")
+	}
+
+	if err := service.NewLogService().CleanOldLogs(*days); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted operation and login logs older than %d day(s)\n", *days)
+	return nil
+}
+
+// backfillSnapshots regenerates daily occupancy snapshots for a date range
+//
+//	snapshot-backfill -from 2026-01-01 [-to 2026-01-31]
+func backfillSnapshots(args []string) error {
+	fs := flag.NewFlagSet("snapshot-backfill", flag.ContinueOnError)
+	from := fs.String("from", "", "first day to snapshot (YYYY-MM-DD)")
+	to := fs.String("to", time.Now().Format("2006-01-02"), "last day to snapshot (YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return fmt.Errorf("-from is required")
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", *from, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	end, err := time.ParseInLocation("2006-01-02", *to, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	total, err := service.NewSnapshotService().Backfill(start, end, func(day time.Time, rows int) {
+		logger.Info(fmt.Sprintf("Snapshot %s: %d rows", day.Format("2006-01-02"), rows))
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Snapshot backfill finished: %d rows", total))
+	return nil
+}
+
+// organizationID resolves an organization code, or the first top-level organization when empty
+func organizationID(code string) (uint, error) {
+	orgs, err := service.NewUserService().GetAllOrganizations()
+	if err != nil {
+		return 0, err
+	}
+	for _, org := range orgs {
+		if (code == "" && org.ParentID == nil) || (code != "" && org.Code == code) {
+			return org.ID, nil
+		}
+	}
+	if code == "" {
+		return 0, errors.New("no top-level organization, use -org")
+	}
+	return 0, fmt.Errorf("organization %q not found", code)
+}
+
+// rolesByCode looks up roles by their codes
+func rolesByCode(codes []string) ([]model.Role, error) {
+	roleService := service.NewRoleService()
+	roles := make([]model.Role, 0, len(codes))
+	for _, code := range codes {
+		role, err := roleService.GetRoleByCode(strings.TrimSpace(code))
+		if err != nil {
+			return nil, fmt.Errorf("role %q: %w", code, err)
+		}
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+// generatePassword returns a random password that satisfies any password policy
+func generatePassword() (string, error) {
+	groups := []string{"ABCDEFGHJKLMNPQRSTUVWXYZ", "abcdefghijkmnopqrstuvwxyz", "23456789", "!@#$%^&*-_+="}
+	length := config.Get().Security.Password.MinLength
+	if length < 16 {
+		length = 16
+	}
+
+	password := make([]byte, 0, length)
+	for i := 0; i < length; i++ {
+		// One character from each group first, then from all groups
+		group := groups[i%len(groups)]
+		if i >= len(groups) {
+			group = strings.Join(groups, "")
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(group))))
+		if err != nil {
+			return "", err
+		}
+		password = append(password, group[n.Int64()])
+	}
+
+	// Shuffle so the required characters are not always in front
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[n.Int64()] = password[n.Int64()], password[i]
+	}
+	return string(password), nil
+}
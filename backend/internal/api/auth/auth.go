@@ -1,9 +1,10 @@
 package auth
 
 import (
-	"time"
+	"errors"
 
 	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
 	"building-asset-backend/pkg/auth"
 	"building-asset-backend/pkg/response"
 	"github.com/gin-gonic/gin"
@@ -25,12 +26,16 @@ type LoginResponse struct {
 
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	// TODO: 添加服务依赖
+	userService *service.UserService
+	authService *service.AuthService
 }
 
 // NewAuthHandler 创建认证处理器
 func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+	return &AuthHandler{
+		userService: service.NewUserService(),
+		authService: service.NewAuthService(),
+	}
 }
 
 // Login 用户登录
@@ -49,54 +54,51 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// TODO: 实际项目中应该从数据库查询用户
-	// 这里暂时使用硬编码的用户信息进行演示
-	if req.Username != "admin" || req.Password != "admin123" {
-		response.Unauthorized(c, "用户名或密码错误")
+	user, err := h.authService.Authenticate(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			response.Unauthorized(c, "用户名或密码错误")
+			return
+		}
+		response.Forbidden(c, err.Error())
 		return
 	}
 
-	// 创建模拟用户
-	user := model.User{
-		BaseModel: model.BaseModel{
-			ID:        1,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		Username: "admin",
-		Name:     "管理员",
-		Status:   "active",
-		Roles: []model.Role{
-			{
-				BaseModel: model.BaseModel{ID: 1},
-				Code:      "admin",
-				Name:      "系统管理员",
-			},
-		},
+	// 必须修改密码时只返回临时凭证
+	if reason := h.userService.PasswordChangeReason(user, req.Password); reason != "" {
+		changeToken, err := h.authService.IssuePasswordChangeToken(user)
+		if err != nil {
+			response.InternalError(c, "生成Token失败")
+			return
+		}
+		response.Success(c, gin.H{
+			"mustChangePassword": true,
+			"reason":             reason,
+			"changeToken":        changeToken,
+		})
+		return
 	}
 
-	// 生成Token
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Name, []string{"admin"})
+	// 需要两步验证时只返回临时凭证
+	result, err := h.authService.CompleteLogin(user)
 	if err != nil {
 		response.InternalError(c, "生成Token失败")
 		return
 	}
-
-	// 生成RefreshToken
-	refreshToken, err := auth.GenerateRefreshToken(user.ID)
-	if err != nil {
-		response.InternalError(c, "生成RefreshToken失败")
+	if result.Tokens == nil {
+		response.Success(c, gin.H{
+			"mfaRequired":      result.MFARequired,
+			"mfaSetupRequired": result.MFASetupRequired,
+			"mfaToken":         result.MFAToken,
+		})
 		return
 	}
 
-	// 清除密码字段
-	user.Password = ""
-
 	response.Success(c, LoginResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresIn:    7200,
-		User:         user,
+		Token:        result.Tokens.Token,
+		RefreshToken: result.Tokens.RefreshToken,
+		ExpiresIn:    result.Tokens.ExpiresIn,
+		User:         *user,
 	})
 }
 
@@ -108,7 +110,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Success 200 {object} response.Response
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// TODO: 实际项目中可能需要将Token加入黑名单
+	claims, ok := c.MustGet("claims").(*auth.Claims)
+	if !ok {
+		response.Unauthorized(c, "无效的认证信息")
+		return
+	}
+
+	// 吊销当前token及其会话
+	if err := h.authService.Logout(claims); err != nil {
+		response.InternalError(c, "登出失败")
+		return
+	}
+
 	response.Success(c, nil)
 }
 
@@ -131,52 +144,22 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// 解析RefreshToken
-	userID, err := auth.ParseRefreshToken(req.RefreshToken)
+	// 轮换RefreshToken，角色信息从数据库重新加载
+	tokens, user, err := h.authService.RefreshTokens(req.RefreshToken)
 	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			response.Unauthorized(c, "RefreshToken已被使用，请重新登录")
+			return
+		}
 		response.Unauthorized(c, "无效的RefreshToken")
 		return
 	}
 
-	// TODO: 从数据库获取用户信息
-	// 这里暂时使用硬编码的用户信息
-	user := model.User{
-		BaseModel: model.BaseModel{
-			ID:        userID,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		Username: "admin",
-		Name:     "管理员",
-		Status:   "active",
-		Roles: []model.Role{
-			{
-				BaseModel: model.BaseModel{ID: 1},
-				Code:      "admin",
-				Name:      "系统管理员",
-			},
-		},
-	}
-
-	// 生成新的Token
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Name, []string{"admin"})
-	if err != nil {
-		response.InternalError(c, "生成Token失败")
-		return
-	}
-
-	// 生成新的RefreshToken
-	refreshToken, err := auth.GenerateRefreshToken(user.ID)
-	if err != nil {
-		response.InternalError(c, "生成RefreshToken失败")
-		return
-	}
-
 	response.Success(c, LoginResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresIn:    7200,
-		User:         user,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
 	})
 }
 
@@ -188,24 +171,10 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 // @Success 200 {object} model.User
 // @Router /auth/current [get]
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
-	// TODO: 从context中获取用户信息
-	// 这里暂时返回硬编码的用户信息
-	user := model.User{
-		BaseModel: model.BaseModel{
-			ID:        1,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		Username: "admin",
-		Name:     "管理员",
-		Status:   "active",
-		Roles: []model.Role{
-			{
-				BaseModel: model.BaseModel{ID: 1},
-				Code:      "admin",
-				Name:      "系统管理员",
-			},
-		},
+	user, err := h.userService.GetUserByID(c.GetUint("userID"))
+	if err != nil {
+		response.NotFound(c, "用户不存在")
+		return
 	}
 
 	response.Success(c, user)
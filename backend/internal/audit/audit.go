@@ -0,0 +1,154 @@
+// Package audit 自动维护审计字段
+//
+// 请求上下文中携带当前用户时，新增记录自动填充创建人和更新人，更新（含批量更新）
+// 自动填充更新人，查询结果自动补充创建人、更新人姓名。
+package audit
+
+import (
+	"context"
+	"reflect"
+
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/auth"
+
+	"gorm.io/gorm"
+)
+
+// Plugin GORM插件，注册审计字段回调
+type Plugin struct{}
+
+// Name 插件名称
+func (Plugin) Name() string {
+	return "audit"
+}
+
+// Initialize 注册新增、更新前及查询后的回调
+func (Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("audit:create", setCreator); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("audit:update", setUpdater); err != nil {
+		return err
+	}
+	return cb.Query().After("gorm:query").Register("audit:names", fillNames)
+}
+
+// setCreator 新增时填充创建人和更新人
+func setCreator(db *gorm.DB) {
+	user, ok := currentUser(db)
+	if !ok || db.Statement.Schema.LookUpField("CreatedBy") == nil {
+		return
+	}
+
+	db.Statement.SetColumn("CreatedBy", user.ID, true)
+	if db.Statement.Schema.LookUpField("UpdatedBy") != nil {
+		db.Statement.SetColumn("UpdatedBy", user.ID, true)
+	}
+}
+
+// setUpdater 更新时填充更新人，UpdateColumn等跳过钩子的更新不处理
+func setUpdater(db *gorm.DB) {
+	user, ok := currentUser(db)
+	if !ok || db.Statement.SkipHooks || db.Statement.Schema.LookUpField("UpdatedBy") == nil {
+		return
+	}
+
+	db.Statement.SetColumn("UpdatedBy", user.ID, true)
+	// 创建人在新增后不可修改
+	db.Statement.Omits = append(db.Statement.Omits, "CreatedBy")
+}
+
+// fillNames 查询后补充创建人、更新人姓名
+func fillNames(db *gorm.DB) {
+	if _, ok := currentUser(db); !ok || db.Statement.Schema.LookUpField("CreatedBy") == nil {
+		return
+	}
+
+	records := collect(db.Statement.ReflectValue)
+	if len(records) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(records)*2)
+	seen := make(map[uint]bool)
+	for _, record := range records {
+		for _, id := range []uint{record.CreatedBy, record.UpdatedBy} {
+			if id > 0 && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// 使用不带请求上下文的会话，姓名查询不受数据权限限制
+	var users []struct {
+		ID   uint
+		Name string
+	}
+	err := db.Session(&gorm.Session{NewDB: true, Context: context.Background()}).
+		Table(model.User{}.TableName()).
+		Select("id", "name").
+		Where("id IN ?", ids).
+		Scan(&users).Error
+	if err != nil {
+		db.Logger.Warn(db.Statement.Context, "查询审计人员姓名失败: %v", err)
+		return
+	}
+
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+	for _, record := range records {
+		record.CreatedByName = names[record.CreatedBy]
+		record.UpdatedByName = names[record.UpdatedBy]
+	}
+}
+
+// collect 收集查询结果中的审计字段
+func collect(value reflect.Value) []*model.AuditModel {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	var records []*model.AuditModel
+	add := func(v reflect.Value) {
+		if v.Kind() != reflect.Ptr {
+			if !v.CanAddr() {
+				return
+			}
+			v = v.Addr()
+		}
+		if v.IsNil() {
+			return
+		}
+		if a, ok := v.Interface().(model.Auditable); ok {
+			records = append(records, a.AuditFields())
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			add(value.Index(i))
+		}
+	case reflect.Struct:
+		add(value)
+	}
+	return records
+}
+
+// currentUser 读取语句上下文中的当前用户
+func currentUser(db *gorm.DB) (auth.CurrentUser, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return auth.CurrentUser{}, false
+	}
+	return auth.CurrentUserFromContext(db.Statement.Context)
+}
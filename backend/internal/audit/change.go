@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/auth"
+
+	"gorm.io/gorm"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID 在上下文中保存请求ID，用于关联操作日志与数据变更记录
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 从上下文中获取请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FieldChange 单个字段的变更前后值
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ignoredFields 不参与差异比较的字段（自动维护的时间和审计字段）
+var ignoredFields = map[string]bool{
+	"created_at":      true,
+	"updated_at":      true,
+	"updated_by":      true,
+	"created_by_name": true,
+	"updated_by_name": true,
+}
+
+// Snapshot 将实体转换为按JSON字段名索引的快照，关联对象不计入快照
+func Snapshot(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+
+	for key, value := range fields {
+		if ignoredFields[key] || isAssociation(value) {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
+// isAssociation 判断快照值是否为关联对象或关联对象列表
+func isAssociation(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Diff 比较两个快照，返回发生变化的字段
+func Diff(before, after map[string]interface{}) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for key, old := range before {
+		if value, ok := after[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes[key] = FieldChange{Before: old, After: after[key]}
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = FieldChange{After: value}
+		}
+	}
+	return changes
+}
+
+// RecordUpdate 记录实体修改前后的字段差异，没有字段变化时不记录
+func RecordUpdate(tx *gorm.DB, entityType string, entityID uint, before, after map[string]interface{}) error {
+	changes := Diff(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return record(tx, entityType, entityID, "update", changes)
+}
+
+// RecordDelete 记录被删除实体的完整快照
+func RecordDelete(tx *gorm.DB, entityType string, entityID uint, before map[string]interface{}) error {
+	return record(tx, entityType, entityID, "delete", Diff(before, nil))
+}
+
+func record(tx *gorm.DB, entityType string, entityID uint, action string, changes map[string]FieldChange) error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	ctx := tx.Statement.Context
+	log := &model.ChangeLog{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Changes:    data,
+		RequestID:  RequestIDFromContext(ctx),
+		ChangedAt:  time.Now(),
+	}
+	if user, ok := auth.CurrentUserFromContext(ctx); ok {
+		log.UserID = user.ID
+		log.Username = user.Username
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Create(log).Error
+}
+
+// History 按时间倒序分页查询实体的变更记录
+func History(db *gorm.DB, entityType string, entityID uint, page, pageSize int) ([]*model.ChangeLog, int64, error) {
+	var logs []*model.ChangeLog
+	var total int64
+
+	query := db.Model(&model.ChangeLog{}).Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("changed_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
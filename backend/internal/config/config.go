@@ -9,12 +9,19 @@ import (
 
 // Config 应用配置
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Upload   UploadConfig   `mapstructure:"upload"`
-	CORS     CORSConfig     `mapstructure:"cors"`
+	App          AppConfig          `mapstructure:"app"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Redis        RedisConfig        `mapstructure:"redis"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	Upload       UploadConfig       `mapstructure:"upload"`
+	CORS         CORSConfig         `mapstructure:"cors"`
+	Security     SecurityConfig     `mapstructure:"security"`
+	OperationLog OperationLogConfig `mapstructure:"operation_log"`
+	Lease        LeaseConfig        `mapstructure:"lease"`
+	Statistics   StatisticsConfig   `mapstructure:"statistics"`
+	Export       ExportConfig       `mapstructure:"export"`
+	WorkOrder    WorkOrderConfig    `mapstructure:"work_order"`
+	Metrics      MetricsConfig      `mapstructure:"metrics"`
 }
 
 // AppConfig 应用配置
@@ -23,6 +30,15 @@ type AppConfig struct {
 	Port     int    `mapstructure:"port"`
 	Mode     string `mapstructure:"mode"`
 	LogLevel string `mapstructure:"log_level"`
+
+	ReadTimeout     int `mapstructure:"read_timeout"`     // 读取请求（含请求体）的超时时间(秒)，0表示不限制
+	WriteTimeout    int `mapstructure:"write_timeout"`    // 写入响应的超时时间(秒)，导出和下载需留足时间，0表示不限制
+	IdleTimeout     int `mapstructure:"idle_timeout"`     // 空闲长连接的超时时间(秒)
+	ShutdownTimeout int `mapstructure:"shutdown_timeout"` // 收到停止信号后等待处理中请求完成的最长时间(秒)
+
+	// TrustedProxies 可信反向代理的IP或CIDR，只有来自这些地址的请求才按 X-Forwarded-For 取客户端IP
+	// 为空时不信任任何代理，客户端IP取连接的对端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig 数据库配置
@@ -62,8 +78,21 @@ type JWTConfig struct {
 // UploadConfig 文件上传配置
 type UploadConfig struct {
 	MaxSize      int64    `mapstructure:"max_size"`
-	AllowedTypes []string `mapstructure:"allowed_types"`
-	Path         string   `mapstructure:"path"`
+	AllowedTypes []string `mapstructure:"allowed_types"` // 允许的文件类型，按文件内容识别，为空时不限制
+	Path         string   `mapstructure:"path"`          // 本地存储目录
+	Storage      string   `mapstructure:"storage"`       // 存储方式：local-本地文件系统，s3-S3兼容对象存储
+	S3           S3Config `mapstructure:"s3"`
+}
+
+// S3Config S3兼容对象存储配置
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`   // 服务地址，如 s3.amazonaws.com 或 localhost:9000
+	Region    string `mapstructure:"region"`     // 区域
+	Bucket    string `mapstructure:"bucket"`     // 存储桶，不存在时自动创建
+	AccessKey string `mapstructure:"access_key"` // 访问密钥ID
+	SecretKey string `mapstructure:"secret_key"` // 访问密钥
+	UseSSL    bool   `mapstructure:"use_ssl"`    // 是否使用HTTPS
+	Prefix    string `mapstructure:"prefix"`     // 对象键前缀
 }
 
 // CORSConfig 跨域配置
@@ -76,6 +105,72 @@ type CORSConfig struct {
 	MaxAge           int      `mapstructure:"max_age"`
 }
 
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	Login    LoginSecurityConfig  `mapstructure:"login"`
+	Password PasswordPolicyConfig `mapstructure:"password"`
+}
+
+// LoginSecurityConfig 登录防暴力破解配置
+type LoginSecurityConfig struct {
+	MaxFailures   int `mapstructure:"max_failures"`    // 同一用户名在窗口内允许的失败次数，0表示不限制
+	IPMaxFailures int `mapstructure:"ip_max_failures"` // 同一IP在窗口内允许的失败次数，0表示不限制
+	FailureWindow int `mapstructure:"failure_window"`  // 失败计数窗口(秒)
+	LockDuration  int `mapstructure:"lock_duration"`   // 临时锁定时长(秒)
+	MaxLockouts   int `mapstructure:"max_lockouts"`    // 窗口内临时锁定达到该次数后锁定账户，0表示不升级
+	LockoutWindow int `mapstructure:"lockout_window"`  // 临时锁定次数统计窗口(秒)
+}
+
+// PasswordPolicyConfig 密码策略配置
+type PasswordPolicyConfig struct {
+	MinLength       int      `mapstructure:"min_length"`       // 最小长度
+	RequireUpper    bool     `mapstructure:"require_upper"`    // 必须包含大写字母
+	RequireLower    bool     `mapstructure:"require_lower"`    // 必须包含小写字母
+	RequireDigit    bool     `mapstructure:"require_digit"`    // 必须包含数字
+	RequireSpecial  bool     `mapstructure:"require_special"`  // 必须包含特殊字符
+	BannedPasswords []string `mapstructure:"banned_passwords"` // 禁用密码列表（不区分大小写）
+	HistoryCount    int      `mapstructure:"history_count"`    // 不得与最近N次使用过的密码相同，0表示不限制
+	MaxAgeDays      int      `mapstructure:"max_age_days"`     // 密码最长使用天数，0表示不过期
+}
+
+// OperationLogConfig 操作日志配置
+type OperationLogConfig struct {
+	Enabled         bool `mapstructure:"enabled"`           // 是否记录操作日志
+	BufferSize      int  `mapstructure:"buffer_size"`       // 异步写入缓冲区大小，写满后丢弃新日志
+	BatchSize       int  `mapstructure:"batch_size"`        // 批量写入条数
+	FlushInterval   int  `mapstructure:"flush_interval"`    // 最长写入间隔(秒)
+	MaxParamsLength int  `mapstructure:"max_params_length"` // 请求参数最大记录长度
+}
+
+// LeaseConfig 租赁合同配置
+type LeaseConfig struct {
+	RoomStatusSyncInterval int `mapstructure:"room_status_sync_interval"` // 按合同起止日刷新房间状态的间隔(秒)
+}
+
+// StatisticsConfig 统计配置
+type StatisticsConfig struct {
+	SnapshotEnabled       bool `mapstructure:"snapshot_enabled"`        // 是否生成每日出租情况快照
+	SnapshotHour          int  `mapstructure:"snapshot_hour"`           // 每日生成快照的时刻（0-23点），到点后首次检查时生成
+	SnapshotCheckInterval int  `mapstructure:"snapshot_check_interval"` // 检查当日快照是否已生成的间隔(秒)
+}
+
+// ExportConfig 列表导出配置
+type ExportConfig struct {
+	MaxRows int `mapstructure:"max_rows"` // 单次导出的最大行数，超过时拒绝导出，0表示不限制
+}
+
+// WorkOrderConfig 维修工单配置
+type WorkOrderConfig struct {
+	SLAHours map[string]int `mapstructure:"sla_hours"` // 各优先级从报修到解决的时限(小时)
+}
+
+// MetricsConfig Prometheus指标配置
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // 是否提供 /metrics 抓取接口
+	Token   string `mapstructure:"token"`   // 访问令牌，抓取时携带 Authorization: Bearer <token>，为空时不校验
+	Listen  string `mapstructure:"listen"`  // 独立监听地址，如 127.0.0.1:9090，为空时由业务端口提供且必须设置令牌
+}
+
 var cfg *Config
 
 // Load 加载配置
@@ -96,6 +191,7 @@ func Load(configPath string) error {
 	viper.BindEnv("database.mysql.password", "DB_PASSWORD")
 	viper.BindEnv("redis.host", "REDIS_HOST")
 	viper.BindEnv("redis.port", "REDIS_PORT")
+	viper.BindEnv("app.trusted_proxies", "TRUSTED_PROXIES")
 
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
@@ -143,6 +239,10 @@ func setDefaults() {
 	viper.SetDefault("app.port", 8080)
 	viper.SetDefault("app.mode", "development")
 	viper.SetDefault("app.log_level", "debug")
+	viper.SetDefault("app.read_timeout", 60)
+	viper.SetDefault("app.write_timeout", 300)
+	viper.SetDefault("app.idle_timeout", 120)
+	viper.SetDefault("app.shutdown_timeout", 30)
 
 	// 数据库默认配置
 	viper.SetDefault("database.mysql.host", "localhost")
@@ -165,10 +265,53 @@ func setDefaults() {
 	// 上传默认配置
 	viper.SetDefault("upload.max_size", 10485760)
 	viper.SetDefault("upload.path", "./uploads")
+	viper.SetDefault("upload.allowed_types", []string{"image/jpeg", "image/png", "image/gif", "application/pdf"})
+	viper.SetDefault("upload.storage", "local")
+	viper.SetDefault("upload.s3.use_ssl", true)
 
 	// CORS默认配置
 	viper.SetDefault("cors.allow_credentials", true)
 	viper.SetDefault("cors.max_age", 86400)
+
+	// 登录安全默认配置
+	viper.SetDefault("security.login.max_failures", 5)
+	viper.SetDefault("security.login.ip_max_failures", 20)
+	viper.SetDefault("security.login.failure_window", 900)
+	viper.SetDefault("security.login.lock_duration", 900)
+	viper.SetDefault("security.login.max_lockouts", 3)
+	viper.SetDefault("security.login.lockout_window", 86400)
+
+	// 密码策略默认配置
+	viper.SetDefault("security.password.min_length", 8)
+	viper.SetDefault("security.password.require_upper", false)
+	viper.SetDefault("security.password.require_lower", true)
+	viper.SetDefault("security.password.require_digit", true)
+	viper.SetDefault("security.password.require_special", false)
+	viper.SetDefault("security.password.banned_passwords", []string{"admin123", "123456", "12345678", "password", "password123", "qwerty123"})
+	viper.SetDefault("security.password.history_count", 5)
+	viper.SetDefault("security.password.max_age_days", 90)
+
+	// 操作日志默认配置
+	viper.SetDefault("operation_log.enabled", true)
+	viper.SetDefault("operation_log.buffer_size", 1024)
+	viper.SetDefault("operation_log.batch_size", 100)
+	viper.SetDefault("operation_log.flush_interval", 2)
+	viper.SetDefault("operation_log.max_params_length", 2000)
+
+	viper.SetDefault("lease.room_status_sync_interval", 600)
+
+	viper.SetDefault("statistics.snapshot_enabled", true)
+	viper.SetDefault("statistics.snapshot_hour", 23)
+	viper.SetDefault("statistics.snapshot_check_interval", 600)
+
+	// 导出默认配置
+	viper.SetDefault("export.max_rows", 50000)
+
+	// 维修工单默认配置
+	viper.SetDefault("work_order.sla_hours", map[string]int{"urgent": 4, "high": 24, "medium": 72, "low": 168})
+
+	// 指标默认配置
+	viper.SetDefault("metrics.enabled", false)
 }
 
 // IsDevelopment 是否为开发模式
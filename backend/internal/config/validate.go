@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// exampleJWTSecret 示例配置中的JWT密钥，生产环境不得使用
+const exampleJWTSecret = "your-secret-key-here"
+
+// Validate 校验配置的取值范围和必填项，返回全部问题
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.App.Port >= 0 && c.App.Port <= 65535, "app.port %d out of range", c.App.Port)
+	check(oneOf(c.App.Mode, "development", "test", "production"),
+		"app.mode %q must be development, test or production", c.App.Mode)
+	check(c.App.ReadTimeout >= 0 && c.App.WriteTimeout >= 0 && c.App.IdleTimeout >= 0,
+		"app.read_timeout, write_timeout and idle_timeout must not be negative")
+	check(c.App.ShutdownTimeout > 0, "app.shutdown_timeout must be positive")
+	check(oneOf(c.App.LogLevel, "debug", "info", "warn", "error"),
+		"app.log_level %q must be debug, info, warn or error", c.App.LogLevel)
+	for _, proxy := range c.App.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "app.trusted_proxies %q is not an IP or CIDR", proxy)
+	}
+
+	mysql := c.Database.MySQL
+	check(mysql.Host != "", "database.mysql.host is required")
+	check(mysql.Port > 0 && mysql.Port <= 65535, "database.mysql.port %d out of range", mysql.Port)
+	check(mysql.Username != "", "database.mysql.username is required")
+	check(mysql.Database != "", "database.mysql.database is required")
+	check(mysql.MaxOpenConns >= mysql.MaxIdleConns, "database.mysql.max_open_conns must not be less than max_idle_conns")
+
+	check(c.Redis.Host != "", "redis.host is required")
+	check(c.Redis.Port > 0 && c.Redis.Port <= 65535, "redis.port %d out of range", c.Redis.Port)
+
+	check(c.JWT.Secret != "", "jwt.secret is required")
+	if c.App.Mode == "production" {
+		check(c.JWT.Secret != exampleJWTSecret, "jwt.secret must be changed from the example value in production")
+		check(len(c.JWT.Secret) >= 32, "jwt.secret must be at least 32 characters in production")
+	}
+	check(c.JWT.Expire > 0, "jwt.expire must be positive")
+	check(c.JWT.RefreshExpire >= c.JWT.Expire, "jwt.refresh_expire must not be less than jwt.expire")
+
+	switch c.Upload.Storage {
+	case "", "local":
+		check(c.Upload.Path != "", "upload.path is required for local storage")
+	case "s3":
+		check(c.Upload.S3.Endpoint != "", "upload.s3.endpoint is required for s3 storage")
+		check(c.Upload.S3.Bucket != "", "upload.s3.bucket is required for s3 storage")
+	default:
+		errs = append(errs, fmt.Errorf("upload.storage %q must be local or s3", c.Upload.Storage))
+	}
+	check(c.Upload.MaxSize >= 0, "upload.max_size must not be negative")
+
+	check(c.Security.Password.MinLength > 0, "security.password.min_length must be positive")
+	check(c.OperationLog.BufferSize > 0 && c.OperationLog.BatchSize > 0,
+		"operation_log.buffer_size and batch_size must be positive")
+	check(c.Lease.RoomStatusSyncInterval > 0, "lease.room_status_sync_interval must be positive")
+	check(c.Statistics.SnapshotHour >= 0 && c.Statistics.SnapshotHour <= 23,
+		"statistics.snapshot_hour %d must be between 0 and 23", c.Statistics.SnapshotHour)
+	check(c.Export.MaxRows >= 0, "export.max_rows must not be negative")
+
+	for priority, hours := range c.WorkOrder.SLAHours {
+		check(oneOf(priority, "low", "medium", "high", "urgent"), "work_order.sla_hours has unknown priority %q", priority)
+		check(hours > 0, "work_order.sla_hours.%s must be positive", priority)
+	}
+
+	if c.Metrics.Enabled {
+		check(c.Metrics.Token != "" || c.Metrics.Listen != "",
+			"metrics.token or metrics.listen is required when metrics are enabled")
+	}
+
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+// Package datascope 实现基于组织的行级数据权限
+//
+// 请求上下文中携带 Scope 时，对资产、楼宇、楼层、房间、租赁合同、出租快照、维修工单和用户表的查询、更新、删除
+// 会自动追加过滤条件；上下文中没有 Scope（如后台任务、登录流程）时不做限制。
+// 使用 Table("t_room r") 等带别名的表达式时按表名匹配，条件中的列使用别名；无法识别表名的原生SQL
+// 或子查询表达式如果涉及上述表，在携带 Scope 时直接返回 ErrUnscopedQuery，需改用模型查询或显式 Skip。
+package datascope
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"building-asset-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type contextKey struct{}
+
+// skipKey 跳过数据权限过滤的语句设置
+const skipKey = "datascope:skip"
+
+// ErrUnscopedQuery 携带数据权限的语句涉及受控表，但无法确定要过滤的表
+var ErrUnscopedQuery = errors.New("datascope: 语句涉及受数据权限控制的表但无法追加过滤条件，请使用模型查询或 datascope.Skip")
+
+// scopedTables 受数据权限控制的表
+var scopedTables = []string{
+	model.User{}.TableName(),
+	model.Asset{}.TableName(),
+	model.Building{}.TableName(),
+	model.Floor{}.TableName(),
+	model.Room{}.TableName(),
+	model.Lease{}.TableName(),
+	model.OccupancySnapshot{}.TableName(),
+	model.WorkOrder{}.TableName(),
+}
+
+var (
+	tableIdentifier    = regexp.MustCompile("^`?\\w+`?$")
+	scopedTablePattern = func() *regexp.Regexp {
+		names := make([]string, len(scopedTables))
+		for i, name := range scopedTables {
+			names[i] = regexp.QuoteMeta(name)
+		}
+		return regexp.MustCompile(`(?i)\b(` + strings.Join(names, "|") + `)\b`)
+	}()
+)
+
+// Scope 当前请求的数据权限
+type Scope struct {
+	UserID   uint
+	All      bool   // 不限制
+	OrgIDs   []uint // 可访问的组织ID
+	SelfOnly bool   // 仅本人创建的数据
+}
+
+// AllowsOrg 是否允许访问指定组织的数据
+func (s *Scope) AllowsOrg(orgID uint) bool {
+	if s == nil || s.All {
+		return true
+	}
+	for _, id := range s.OrgIDs {
+		if id == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+// WithScope 将数据权限写入上下文
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope)
+}
+
+// FromContext 从上下文读取数据权限
+func FromContext(ctx context.Context) (*Scope, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	scope, ok := ctx.Value(contextKey{}).(*Scope)
+	return scope, ok && scope != nil
+}
+
+// Skip 返回不应用数据权限的会话，用于唯一性校验等需要看到全部数据的场景
+func Skip(db *gorm.DB) *gorm.DB {
+	return db.Set(skipKey, true)
+}
+
+// Plugin GORM插件，注册数据权限回调
+type Plugin struct{}
+
+// Name 插件名称
+func (Plugin) Name() string {
+	return "datascope"
+}
+
+// Initialize 注册查询、更新、删除和原生SQL执行前的过滤回调
+func (Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Raw().Before("gorm:raw").Register("datascope:raw", apply); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("datascope:query", apply); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("datascope:row", apply); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("datascope:update", apply); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("datascope:delete", apply)
+}
+
+// apply 根据表名追加数据权限条件
+func apply(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if skip, ok := db.Get(skipKey); ok && skip == true {
+		return
+	}
+
+	scope, ok := FromContext(db.Statement.Context)
+	if !ok || scope.All {
+		return
+	}
+
+	// Raw、Exec 的SQL在回调前已经生成，无法追加条件
+	if db.Statement.SQL.Len() > 0 {
+		if scopedTablePattern.MatchString(db.Statement.SQL.String()) {
+			_ = db.AddError(ErrUnscopedQuery)
+		}
+		return
+	}
+
+	table, ok := baseTable(db.Statement)
+	if !ok {
+		if scopedTablePattern.MatchString(db.Statement.TableExpr.SQL) {
+			_ = db.AddError(ErrUnscopedQuery)
+		}
+		return
+	}
+	if expr, ok := condition(table, scope); ok {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{expr}})
+	}
+}
+
+// baseTable 语句操作的表名，Table("t_room r") 时取表达式中的表名而不是别名，子查询等表达式无法识别
+func baseTable(stmt *gorm.Statement) (string, bool) {
+	if stmt.TableExpr == nil {
+		return stmt.Table, true
+	}
+	fields := strings.Fields(stmt.TableExpr.SQL)
+	if len(fields) == 0 || !tableIdentifier.MatchString(fields[0]) {
+		return "", false
+	}
+	return strings.Trim(fields[0], "`"), true
+}
+
+// condition 各表的数据权限条件
+func condition(table string, scope *Scope) (clause.Expression, bool) {
+	column := func(name string) clause.Column {
+		return clause.Column{Table: clause.CurrentTable, Name: name}
+	}
+
+	switch table {
+	case model.User{}.TableName():
+		if scope.SelfOnly {
+			return clause.Eq{Column: column("id"), Value: scope.UserID}, true
+		}
+		return clause.Expr{SQL: "? IN ?", Vars: []interface{}{column("org_id"), scope.OrgIDs}}, true
+
+	case model.Asset{}.TableName():
+		if scope.SelfOnly {
+			return clause.Eq{Column: column("created_by"), Value: scope.UserID}, true
+		}
+		return clause.Expr{SQL: "? IN ?", Vars: []interface{}{column("street_id"), scope.OrgIDs}}, true
+
+	case model.Building{}.TableName():
+		if scope.SelfOnly {
+			return clause.Eq{Column: column("created_by"), Value: scope.UserID}, true
+		}
+		return clause.Expr{
+			SQL:  "? IN (SELECT a.id FROM t_asset a WHERE a.street_id IN ?)",
+			Vars: []interface{}{column("asset_id"), scope.OrgIDs},
+		}, true
+
+	case model.Floor{}.TableName():
+		if scope.SelfOnly {
+			return clause.Eq{Column: column("created_by"), Value: scope.UserID}, true
+		}
+		return clause.Expr{
+			SQL: "? IN (SELECT b.id FROM t_building b JOIN t_asset a ON a.id = b.asset_id" +
+				" WHERE a.street_id IN ?)",
+			Vars: []interface{}{column("building_id"), scope.OrgIDs},
+		}, true
+
+	case model.Room{}.TableName():
+		if scope.SelfOnly {
+			return clause.Eq{Column: column("created_by"), Value: scope.UserID}, true
+		}
+		return clause.Expr{
+			SQL: "? IN (SELECT f.id FROM t_floor f JOIN t_building b ON b.id = f.building_id" +
+				" JOIN t_asset a ON a.id = b.asset_id WHERE a.street_id IN ?)",
+			Vars: []interface{}{column("floor_id"), scope.OrgIDs},
+		}, true
+
+	case model.Lease{}.TableName():
+		if scope.SelfOnly {
+			return clause.Eq{Column: column("created_by"), Value: scope.UserID}, true
+		}
+		return clause.Expr{
+			SQL: "? IN (SELECT lr.lease_id FROM t_lease_rooms lr JOIN t_room r ON r.id = lr.room_id" +
+				" JOIN t_floor f ON f.id = r.floor_id JOIN t_building b ON b.id = f.building_id" +
+				" JOIN t_asset a ON a.id = b.asset_id WHERE a.street_id IN ?)",
+			Vars: []interface{}{column("id"), scope.OrgIDs},
+		}, true
+
+	case model.OccupancySnapshot{}.TableName():
+		if scope.SelfOnly {
+			return clause.Expr{
+				SQL:  "? IN (SELECT a.id FROM t_asset a WHERE a.created_by = ?)",
+				Vars: []interface{}{column("asset_id"), scope.UserID},
+			}, true
+		}
+		return clause.Expr{SQL: "? IN ?", Vars: []interface{}{column("street_id"), scope.OrgIDs}}, true
+
+	case model.WorkOrder{}.TableName():
+		if scope.SelfOnly {
+			// 仅本人数据时可以看到本人创建和派给本人的工单
+			return clause.Expr{
+				SQL:  "(? = ? OR ? = ?)",
+				Vars: []interface{}{column("created_by"), scope.UserID, column("assignee_id"), scope.UserID},
+			}, true
+		}
+		return clause.Expr{
+			SQL: "? IN (SELECT b.id FROM t_building b JOIN t_asset a ON a.id = b.asset_id" +
+				" WHERE a.street_id IN ?)",
+			Vars: []interface{}{column("building_id"), scope.OrgIDs},
+		}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,164 @@
+package datascope
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"building-asset-backend/internal/model"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dryRunDB 只生成SQL不连接数据库的会话，已注册数据权限插件
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestBaseTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     string
+		tableExpr string
+		want      string
+		wantOK    bool
+	}{
+		{"model table", "t_room", "", "t_room", true},
+		{"alias", "", "t_room r", "t_room", true},
+		{"quoted alias", "", "`t_room` AS r", "t_room", true},
+		{"subquery", "", "(SELECT * FROM t_room) r", "", false},
+		{"empty expression", "", " ", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := &gorm.Statement{Table: tt.table}
+			if tt.tableExpr != "" {
+				stmt.TableExpr = &clause.Expr{SQL: tt.tableExpr}
+			}
+			got, ok := baseTable(stmt)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("baseTable = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCondition(t *testing.T) {
+	db := dryRunDB(t)
+	orgs := &Scope{UserID: 7, OrgIDs: []uint{3, 4}}
+	self := &Scope{UserID: 7, OrgIDs: []uint{3}, SelfOnly: true}
+
+	tests := []struct {
+		table    string
+		scope    *Scope
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{"t_user", orgs, "`t_user`.`org_id` IN (?,?)", []interface{}{uint(3), uint(4)}},
+		{"t_user", self, "`t_user`.`id` = ?", []interface{}{uint(7)}},
+		{"t_asset", orgs, "`t_asset`.`street_id` IN (?,?)", []interface{}{uint(3), uint(4)}},
+		{"t_asset", self, "`t_asset`.`created_by` = ?", []interface{}{uint(7)}},
+		{"t_building", orgs, "`t_building`.`asset_id` IN (SELECT a.id FROM t_asset a WHERE a.street_id IN (?,?))", []interface{}{uint(3), uint(4)}},
+		{"t_floor", orgs, "`t_floor`.`building_id` IN (SELECT b.id FROM t_building b JOIN t_asset a ON a.id = b.asset_id WHERE a.street_id IN (?,?))", []interface{}{uint(3), uint(4)}},
+		{"t_room", orgs, "`t_room`.`floor_id` IN (SELECT f.id FROM t_floor f JOIN t_building b ON b.id = f.building_id JOIN t_asset a ON a.id = b.asset_id WHERE a.street_id IN (?,?))", []interface{}{uint(3), uint(4)}},
+		{"t_room", self, "`t_room`.`created_by` = ?", []interface{}{uint(7)}},
+		{"t_lease", self, "`t_lease`.`created_by` = ?", []interface{}{uint(7)}},
+		{"t_occupancy_snapshot", orgs, "`t_occupancy_snapshot`.`street_id` IN (?,?)", []interface{}{uint(3), uint(4)}},
+		{"t_occupancy_snapshot", self, "`t_occupancy_snapshot`.`asset_id` IN (SELECT a.id FROM t_asset a WHERE a.created_by = ?)", []interface{}{uint(7)}},
+		{"t_work_order", self, "(`t_work_order`.`created_by` = ? OR `t_work_order`.`assignee_id` = ?)", []interface{}{uint(7), uint(7)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			expr, ok := condition(tt.table, tt.scope)
+			if !ok {
+				t.Fatalf("condition(%s) not found", tt.table)
+			}
+			stmt := &gorm.Statement{DB: db, Table: tt.table, Clauses: map[string]clause.Clause{}}
+			expr.Build(stmt)
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("sql = %s\nwant  %s", got, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+
+	for _, table := range []string{"t_tenant", "t_organization", "t_attachment"} {
+		if _, ok := condition(table, orgs); ok {
+			t.Errorf("condition(%s) should not restrict an unscoped table", table)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	db := dryRunDB(t)
+	scoped := WithScope(context.Background(), &Scope{UserID: 7, OrgIDs: []uint{3}})
+
+	tests := []struct {
+		name    string
+		query   func(db *gorm.DB) *gorm.DB
+		want    string // 期望SQL包含的片段，为空时不应追加条件
+		wantErr error
+	}{
+		{"model query", func(db *gorm.DB) *gorm.DB {
+			return db.WithContext(scoped).Find(&[]model.Room{})
+		}, "`t_room`.`floor_id` IN (SELECT", nil},
+		{"aliased table", func(db *gorm.DB) *gorm.DB {
+			return db.WithContext(scoped).Table("t_room r").Select("r.id").Find(&[]model.Room{})
+		}, "`r`.`floor_id` IN (SELECT", nil},
+		{"update", func(db *gorm.DB) *gorm.DB {
+			return db.WithContext(scoped).Model(&model.Asset{}).Where("id = ?", 1).Update("asset_name", "a")
+		}, "`t_asset`.`street_id` IN (?)", nil},
+		{"skip", func(db *gorm.DB) *gorm.DB {
+			return Skip(db.WithContext(scoped)).Find(&[]model.Room{})
+		}, "", nil},
+		{"no scope", func(db *gorm.DB) *gorm.DB {
+			return db.Find(&[]model.Room{})
+		}, "", nil},
+		{"unscoped table", func(db *gorm.DB) *gorm.DB {
+			return db.WithContext(scoped).Find(&[]model.Tenant{})
+		}, "", nil},
+		{"raw sql on scoped table", func(db *gorm.DB) *gorm.DB {
+			return db.WithContext(scoped).Raw("SELECT id FROM t_room").Scan(&[]uint{})
+		}, "", ErrUnscopedQuery},
+		{"subquery table expression", func(db *gorm.DB) *gorm.DB {
+			return db.WithContext(scoped).Table("(SELECT * FROM t_room) r").Find(&[]model.Room{})
+		}, "", ErrUnscopedQuery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.query(db.Session(&gorm.Session{}))
+			if !errors.Is(result.Error, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", result.Error, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			sql := result.Statement.SQL.String()
+			if tt.want == "" {
+				if strings.Contains(sql, "floor_id` IN") || strings.Contains(sql, "street_id` IN") {
+					t.Errorf("unexpected scope condition: %s", sql)
+				}
+			} else if !strings.Contains(sql, tt.want) {
+				t.Errorf("sql = %s, want it to contain %s", sql, tt.want)
+			}
+		})
+	}
+}
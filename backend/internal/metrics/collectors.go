@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// businessQueryTimeout 抓取时业务指标查询的超时时间
+const businessQueryTimeout = 5 * time.Second
+
+// redisCollector Redis连接池状态
+type redisCollector struct {
+	rdb *redis.Client
+
+	hits, misses, timeouts     *prometheus.Desc
+	totalConns, idleConns      *prometheus.Desc
+	staleConns, maxConnections *prometheus.Desc
+}
+
+func newRedisCollector(rdb *redis.Client) *redisCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("redis_pool_"+name, help, nil, nil)
+	}
+	return &redisCollector{
+		rdb:            rdb,
+		hits:           desc("hits_total", "Times a free connection was found in the pool."),
+		misses:         desc("misses_total", "Times a free connection was not found in the pool."),
+		timeouts:       desc("timeouts_total", "Times a wait for a connection timed out."),
+		totalConns:     desc("connections", "Number of connections in the pool."),
+		idleConns:      desc("idle_connections", "Number of idle connections in the pool."),
+		staleConns:     desc("stale_connections_total", "Stale connections removed from the pool."),
+		maxConnections: desc("max_connections", "Maximum number of connections in the pool."),
+	}
+}
+
+func (c *redisCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+	ch <- c.maxConnections
+}
+
+func (c *redisCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.rdb.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
+	ch <- prometheus.MustNewConstMetric(c.maxConnections, prometheus.GaugeValue, float64(c.rdb.Options().PoolSize))
+}
+
+// businessCollector 业务指标，不受数据权限限制，统计全部数据
+type businessCollector struct {
+	db *gorm.DB
+
+	rooms, activeLeases *prometheus.Desc
+}
+
+func newBusinessCollector(db *gorm.DB) *businessCollector {
+	return &businessCollector{
+		db:           db,
+		rooms:        prometheus.NewDesc("building_asset_rooms", "Rooms by status.", []string{"status"}, nil),
+		activeLeases: prometheus.NewDesc("building_asset_active_leases", "Leases in effect today.", nil, nil),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rooms
+	ch <- c.activeLeases
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessQueryTimeout)
+	defer cancel()
+	db := datascope.Skip(c.db.WithContext(ctx))
+
+	var rooms []struct {
+		Status string
+		Count  int64
+	}
+	err := db.Model(&model.Room{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rooms).Error
+	if err != nil {
+		logger.Warnf("Failed to collect room metrics: %v", err)
+		ch <- prometheus.NewInvalidMetric(c.rooms, err)
+	} else {
+		// 没有房间的状态也输出0，便于告警规则计算
+		counts := map[string]int64{
+			model.RoomStatusAvailable:   0,
+			model.RoomStatusRented:      0,
+			model.RoomStatusMaintenance: 0,
+		}
+		for _, room := range rooms {
+			counts[room.Status] = room.Count
+		}
+		for status, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.rooms, prometheus.GaugeValue, float64(count), status)
+		}
+	}
+
+	var leases int64
+	today := time.Now().Format("2006-01-02")
+	err = db.Model(&model.Lease{}).
+		Where("status = ? AND start_date <= ? AND end_date >= ?", model.LeaseStatusActive, today, today).
+		Count(&leases).Error
+	if err != nil {
+		logger.Warnf("Failed to collect lease metrics: %v", err)
+		ch <- prometheus.NewInvalidMetric(c.activeLeases, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.activeLeases, prometheus.GaugeValue, float64(leases))
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start"
+
+var tableIdentifier = regexp.MustCompile("^`?[\\w.]+`?$")
+
+// Plugin GORM插件，记录每条语句的耗时和错误
+type Plugin struct{}
+
+// Name 插件名称
+func (Plugin) Name() string {
+	return "metrics"
+}
+
+// Initialize 在各类操作的全部回调前后注册计时回调，耗时包含其他插件的回调
+func (Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("metrics:before_create", startTimer),
+		cb.Create().After("*").Register("metrics:after_create", observe("create")),
+		cb.Query().Before("*").Register("metrics:before_query", startTimer),
+		cb.Query().After("*").Register("metrics:after_query", observe("query")),
+		cb.Update().Before("*").Register("metrics:before_update", startTimer),
+		cb.Update().After("*").Register("metrics:after_update", observe("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", startTimer),
+		cb.Delete().After("*").Register("metrics:after_delete", observe("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", startTimer),
+		cb.Row().After("*").Register("metrics:after_row", observe("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", startTimer),
+		cb.Raw().After("*").Register("metrics:after_raw", observe("raw")),
+	)
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func observe(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := tableName(db.Statement)
+		queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// tableName 语句操作的表名，Table("t_lease_rooms lr") 时取表达式中的表名而不是别名，原生SQL没有表名
+func tableName(stmt *gorm.Statement) string {
+	if stmt.TableExpr != nil {
+		if fields := strings.Fields(stmt.TableExpr.SQL); len(fields) > 0 && tableIdentifier.MatchString(fields[0]) {
+			return strings.Trim(fields[0], "`")
+		}
+	}
+	if stmt.Table != "" {
+		return stmt.Table
+	}
+	return "unknown"
+}
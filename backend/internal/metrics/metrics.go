@@ -0,0 +1,127 @@
+// Package metrics Prometheus指标
+//
+// 包括HTTP请求数和耗时（按路由模板和状态码）、GORM语句耗时和错误数、MySQL和Redis连接池状态，
+// 以及房间数、生效合同数等业务指标。业务指标在抓取时查询数据库。
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// unmatchedRoute 未匹配路由的请求统一使用的路由标签，避免任意路径产生大量时间序列
+const unmatchedRoute = "unmatched"
+
+// otherMethod 非标准请求方法统一使用的方法标签，原因同上
+const otherMethod = "OTHER"
+
+// standardMethods RFC 9110 和 PATCH 定义的请求方法
+var standardMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gorm_query_duration_seconds",
+		Help:    "GORM statement latency by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gorm_query_errors_total",
+		Help: "GORM statement errors by operation and table, record not found excluded.",
+	}, []string{"operation", "table"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, queryDuration, queryErrors,
+	)
+}
+
+// Register 注册MySQL、Redis连接池和业务指标，数据库和Redis初始化后调用一次
+func Register(db *gorm.DB, rdb *redis.Client) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return registerAll(
+		collectors.NewDBStatsCollector(sqlDB, db.Migrator().CurrentDatabase()),
+		newRedisCollector(rdb),
+		newBusinessCollector(db),
+	)
+}
+
+func registerAll(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ObserveHTTP 记录一次HTTP请求，route为空表示未匹配任何路由，非标准方法记为OTHER
+func ObserveHTTP(method, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = unmatchedRoute
+	}
+	if !standardMethods[method] {
+		method = otherMethod
+	}
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// Handler 返回指标抓取接口，token不为空时要求请求携带 Authorization: Bearer <token>
+func Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		// 业务指标查询失败时仍返回其余指标
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	if token == "" {
+		return handler
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
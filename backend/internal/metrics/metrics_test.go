@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveHTTPLimitsLabels(t *testing.T) {
+	ObserveHTTP(http.MethodGet, "/api/v1/assets/:id", http.StatusOK, time.Millisecond)
+	ObserveHTTP("PROPFIND", "", http.StatusNotFound, time.Millisecond)
+	ObserveHTTP("X-RANDOM-1", "", http.StatusNotFound, time.Millisecond)
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/api/v1/assets/:id", "200")); got != 1 {
+		t.Errorf("GET requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(otherMethod, unmatchedRoute, "404")); got != 2 {
+		t.Errorf("OTHER requests = %v, want 2", got)
+	}
+	if got := testutil.CollectAndCount(httpRequests); got != 2 {
+		t.Errorf("series = %d, want 2", got)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/logger"
+	"building-asset-backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// DataScope 计算当前用户的数据权限并写入请求上下文，需在JWTAuth之后使用
+func DataScope() gin.HandlerFunc {
+	roleService := service.NewRoleService()
+
+	return func(c *gin.Context) {
+		userID := c.GetUint("userID")
+		if userID == 0 {
+			response.Unauthorized(c, "请登录")
+			c.Abort()
+			return
+		}
+
+		scope, err := roleService.GetUserDataScope(userID)
+		if err != nil {
+			logger.Errorf("查询用户数据权限失败: %v", err)
+			response.Forbidden(c, "无权限访问")
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(datascope.WithScope(c.Request.Context(), scope))
+		c.Next()
+	}
+}
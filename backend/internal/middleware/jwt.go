@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"building-asset-backend/internal/service"
 	"building-asset-backend/pkg/auth"
+	"building-asset-backend/pkg/logger"
 	"building-asset-backend/pkg/response"
 	"github.com/gin-gonic/gin"
 )
@@ -37,11 +39,31 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// 检查token是否已被吊销
+		revoked, err := auth.IsTokenRevoked(claims)
+		if err != nil {
+			logger.Errorf("检查token吊销状态失败: %v", err)
+			response.InternalError(c, "认证服务暂不可用")
+			c.Abort()
+			return
+		}
+		if revoked {
+			response.Unauthorized(c, "登录已失效，请重新登录")
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存入context
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("name", claims.Name)
 		c.Set("roles", claims.Roles)
+		c.Set("claims", claims)
+		c.Request = c.Request.WithContext(auth.WithCurrentUser(c.Request.Context(), auth.CurrentUser{
+			ID:       claims.UserID,
+			Username: claims.Username,
+			Name:     claims.Name,
+		}))
 
 		c.Next()
 	}
@@ -75,11 +97,42 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 	}
 }
 
+// permissionChecker 查询用户是否拥有指定权限
+type permissionChecker interface {
+	HasPermission(userID uint, permission string) (bool, error)
+}
+
+// newPermissionChecker 创建权限查询服务，测试中替换为不依赖数据库的实现
+var newPermissionChecker = func() permissionChecker {
+	return service.NewRoleService()
+}
+
 // RequirePermission 需要特定权限的中间件
 func RequirePermission(permission string) gin.HandlerFunc {
+	roleService := newPermissionChecker()
+
 	return func(c *gin.Context) {
-		// TODO: 实现权限检查逻辑
-		// 这里可以从context中获取用户信息，然后查询用户的权限
+		userID := c.GetUint("userID")
+		if userID == 0 {
+			response.Forbidden(c, "无权限访问")
+			c.Abort()
+			return
+		}
+
+		allowed, err := roleService.HasPermission(userID, permission)
+		if err != nil {
+			logger.Errorf("查询用户权限失败: %v", err)
+			response.Forbidden(c, "无权限访问")
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			response.Forbidden(c, "无权限访问")
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakePermissions struct {
+	codes map[uint][]string
+	err   error
+}
+
+func (f fakePermissions) HasPermission(userID uint, permission string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, code := range f.codes[userID] {
+		if code == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		checker  fakePermissions
+		wantCode int
+	}{
+		{"anonymous", 0, fakePermissions{}, http.StatusForbidden},
+		{"granted", 1, fakePermissions{codes: map[uint][]string{1: {"asset:list", "asset:view"}}}, http.StatusOK},
+		{"denied", 2, fakePermissions{codes: map[uint][]string{2: {"asset:list"}}}, http.StatusForbidden},
+		{"lookup error", 1, fakePermissions{err: errors.New("redis down")}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := newPermissionChecker
+			newPermissionChecker = func() permissionChecker { return tt.checker }
+			defer func() { newPermissionChecker = original }()
+
+			reached := false
+			r := gin.New()
+			r.GET("/assets/:id", func(c *gin.Context) {
+				if tt.userID > 0 {
+					c.Set("userID", tt.userID)
+				}
+			}, RequirePermission("asset:view"), func(c *gin.Context) {
+				reached = true
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/1", nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if reached != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler reached = %v", reached)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"time"
+
+	"building-asset-backend/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 按路由模板记录请求数和耗时，路径参数不会产生新的时间序列
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTP(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
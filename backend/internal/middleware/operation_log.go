@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"building-asset-backend/internal/audit"
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	operationModuleKey = "operation_module"
+	operationActionKey = "operation_action"
+
+	// maxLoggedBodySize 超过该大小的请求体不记录
+	maxLoggedBodySize = 64 << 10
+	redactedValue     = "******"
+
+	requestIDHeader = "X-Request-ID"
+)
+
+// validRequestID 客户端传入的请求ID只接受字母、数字和连字符，避免日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// OperationModule 声明路由（组）所属的操作日志模块
+func OperationModule(module string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(operationModuleKey, module)
+		c.Next()
+	}
+}
+
+// OperationAction 声明路由的操作类型，覆盖按请求方法推导的默认值
+// GET请求默认不记录，声明了操作类型的GET请求（如导出）才会记录
+func OperationAction(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(operationActionKey, action)
+		c.Next()
+	}
+}
+
+// OperationLog 操作日志中间件，需在JWTAuth之后使用
+// 日志通过异步写入器落库，不影响请求耗时和结果；请求ID写入上下文，用于关联数据变更记录
+func OperationLog() gin.HandlerFunc {
+	cfg := config.Get().OperationLog
+
+	return func(c *gin.Context) {
+		requestID := requestIDFrom(c)
+		c.Header(requestIDHeader, requestID)
+		c.Request = c.Request.WithContext(audit.WithRequestID(c.Request.Context(), requestID))
+
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		params := requestParams(c, cfg.MaxParamsLength)
+
+		c.Next()
+
+		action := c.GetString(operationActionKey)
+		if action == "" {
+			action = defaultOperationAction(c.Request.Method)
+		}
+		if action == "" {
+			return
+		}
+
+		module := c.GetString(operationModuleKey)
+		if module == "" {
+			module = moduleFromPath(c.FullPath())
+		}
+
+		description := c.Request.Method + " " + c.FullPath()
+		if len(c.Errors) > 0 {
+			description = c.Errors.String()
+		}
+
+		service.WriteOperationLog(&model.OperationLog{
+			UserID:         c.GetUint("userID"),
+			Username:       c.GetString("username"),
+			Module:         module,
+			Action:         action,
+			Description:    truncate(description, 200),
+			RequestURL:     truncate(c.Request.URL.RequestURI(), 200),
+			RequestMethod:  c.Request.Method,
+			RequestParams:  params,
+			ResponseStatus: c.Writer.Status(),
+			ResponseTime:   time.Since(start).Milliseconds(),
+			ClientIP:       c.ClientIP(),
+			UserAgent:      truncate(c.Request.UserAgent(), 500),
+			OperationTime:  start,
+			RequestID:      requestID,
+		})
+	}
+}
+
+// requestIDFrom 优先使用客户端传入的合法请求ID，缺失或格式不合法时生成新的请求ID
+func requestIDFrom(c *gin.Context) string {
+	if id := c.GetHeader(requestIDHeader); validRequestID.MatchString(id) {
+		return id
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// defaultOperationAction 按请求方法推导操作类型，查询请求返回空表示不记录
+func defaultOperationAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return ""
+}
+
+// moduleFromPath 未声明模块时取路由模板的第一段，如 /api/v1/assets/:id -> assets
+func moduleFromPath(path string) string {
+	path = strings.TrimPrefix(path, "/api/v1")
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			return segment
+		}
+	}
+	return "unknown"
+}
+
+// requestParams 读取并脱敏请求参数，读取后恢复请求体供后续处理
+func requestParams(c *gin.Context, maxLength int) string {
+	params := make(map[string]interface{})
+	sensitive := sensitiveKeys(c.FullPath())
+
+	if query := c.Request.URL.Query(); len(query) > 0 {
+		params["query"] = redact(flattenValues(query), sensitive)
+	}
+
+	if body := requestBody(c, sensitive); body != nil {
+		params["body"] = body
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	if maxLength <= 0 {
+		maxLength = 2000
+	}
+	return truncate(string(data), maxLength)
+}
+
+// requestBody 读取JSON或表单请求体，其他类型只记录类型
+func requestBody(c *gin.Context, sensitive func(string) bool) interface{} {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return nil
+	}
+
+	contentType := c.ContentType()
+	switch contentType {
+	case gin.MIMEJSON, gin.MIMEPOSTForm:
+	default:
+		return "[" + contentType + "]"
+	}
+	if c.Request.ContentLength > maxLoggedBodySize {
+		return "[请求体过大，未记录]"
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodySize+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	if len(data) > maxLoggedBodySize {
+		return "[请求体过大，未记录]"
+	}
+
+	if contentType == gin.MIMEPOSTForm {
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			return "[无法解析的请求体]"
+		}
+		return redact(flattenValues(form), sensitive)
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return "[无法解析的请求体]"
+	}
+	return redact(body, sensitive)
+}
+
+// flattenValues 单值参数展开为字符串
+func flattenValues(values url.Values) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, v := range values {
+		if len(v) == 1 {
+			result[key] = v[0]
+		} else {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// redact 递归隐藏密码、令牌、密钥等敏感字段
+func redact(value interface{}, sensitive func(string) bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if sensitive(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redact(item, sensitive)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item, sensitive)
+		}
+		return v
+	}
+	return value
+}
+
+// sensitiveKeys 路由的敏感字段判断，两步验证接口的 code 为动态验证码，其他接口的 code 是普通编码
+func sensitiveKeys(path string) func(string) bool {
+	if !strings.Contains(path, "/2fa/") {
+		return isSensitiveKey
+	}
+	return func(key string) bool {
+		return strings.EqualFold(key, "code") || isSensitiveKey(key)
+	}
+}
+
+// isSensitiveKey 是否为敏感字段
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "token", "secret"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return key == "recovery_code"
+}
+
+// truncate 按字节截断字符串，不截断多字节字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
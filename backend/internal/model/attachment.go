@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Attachment 附件，归属于资产、建筑、楼层或房间
+// 文件内容按SHA-256存储，内容相同的附件共用同一个文件
+type Attachment struct {
+	AuditModel
+	OwnerType   string `gorm:"size:20;not null;index:idx_attachment_owner" json:"owner_type"` // 所属实体类型：asset、building、floor、room
+	OwnerID     uint   `gorm:"not null;index:idx_attachment_owner" json:"owner_id"`           // 所属实体ID
+	Category    string `gorm:"size:20;not null" json:"category"`                              // 分类
+	FileName    string `gorm:"size:255;not null" json:"file_name"`                            // 原始文件名
+	ContentType string `gorm:"size:100" json:"content_type"`                                  // 按文件内容识别的类型
+	Size        int64  `json:"size"`                                                          // 文件大小(字节)
+	SHA256      string `gorm:"column:sha256;size:64;not null;index" json:"sha256"`            // 文件内容的SHA-256
+	Description string `gorm:"size:500" json:"description"`                                   // 说明
+}
+
+// TableName 设置表名
+func (Attachment) TableName() string {
+	return "t_attachment"
+}
+
+// AttachmentBlob 附件文件，每个内容哈希一行，上传和删除附件时锁定该行以串行化同一文件的写入与删除
+type AttachmentBlob struct {
+	SHA256    string    `gorm:"column:sha256;primaryKey;size:64" json:"sha256"` // 文件内容的SHA-256
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (AttachmentBlob) TableName() string {
+	return "t_attachment_blob"
+}
+
+// 附件分类
+const (
+	AttachmentCategoryPhoto     = "photo"      // 照片
+	AttachmentCategoryFloorPlan = "floor_plan" // 平面图
+	AttachmentCategoryTitleDeed = "title_deed" // 权属证书
+	AttachmentCategoryOther     = "other"      // 其他
+)
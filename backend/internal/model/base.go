@@ -17,6 +17,18 @@ type BaseModel struct {
 // AuditModel 审计模型（包含创建人和更新人）
 type AuditModel struct {
 	BaseModel
-	CreatedBy uint `json:"created_by"` // 创建人ID
-	UpdatedBy uint `json:"updated_by"` // 更新人ID
-}
\ No newline at end of file
+	CreatedBy     uint   `json:"created_by"`                         // 创建人ID
+	UpdatedBy     uint   `json:"updated_by"`                         // 更新人ID
+	CreatedByName string `gorm:"-" json:"created_by_name,omitempty"` // 创建人姓名（查询时填充）
+	UpdatedByName string `gorm:"-" json:"updated_by_name,omitempty"` // 更新人姓名（查询时填充）
+}
+
+// Auditable 包含审计字段的模型
+type Auditable interface {
+	AuditFields() *AuditModel
+}
+
+// AuditFields 返回审计字段
+func (m *AuditModel) AuditFields() *AuditModel {
+	return m
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+)
+
+// Tenant 租户模型
+type Tenant struct {
+	AuditModel
+	TenantType    string `gorm:"size:20;default:'company'" json:"tenant_type"` // 租户类型：company-企业，individual-个人
+	Name          string `gorm:"size:100;not null;index" json:"name"`          // 租户名称
+	CertificateNo string `gorm:"size:50;index" json:"certificate_no"`          // 统一社会信用代码或身份证号
+	ContactPerson string `gorm:"size:50" json:"contact_person"`                // 联系人
+	ContactPhone  string `gorm:"size:20" json:"contact_phone"`                 // 联系电话
+	Email         string `gorm:"size:100" json:"email"`                        // 邮箱
+	Address       string `gorm:"size:200" json:"address"`                      // 地址
+	Description   string `gorm:"type:text" json:"description"`                 // 描述
+	Status        string `gorm:"size:20;default:'active'" json:"status"`       // 状态：active-正常，disabled-禁用
+}
+
+// TableName 设置表名
+func (Tenant) TableName() string {
+	return "t_tenant"
+}
+
+// Lease 租赁合同模型，一份合同可包含多个楼层的多个房间
+type Lease struct {
+	AuditModel
+	LeaseNo      string     `gorm:"uniqueIndex;size:50;not null" json:"lease_no"`   // 合同编号
+	TenantID     uint       `gorm:"index;not null" json:"tenant_id"`                // 承租方（租户ID）
+	Lessor       string     `gorm:"size:100" json:"lessor"`                         // 出租方
+	StartDate    time.Time  `gorm:"type:date;index" json:"start_date"`              // 租期开始日期
+	EndDate      time.Time  `gorm:"type:date;index" json:"end_date"`                // 租期结束日期（含当日）
+	MonthlyRent  float64    `json:"monthly_rent"`                                   // 月租金(元/月)
+	Deposit      float64    `json:"deposit"`                                        // 押金(元)
+	PaymentCycle string     `gorm:"size:20;default:'monthly'" json:"payment_cycle"` // 付款周期：monthly-月付，quarterly-季付，semiannual-半年付，annual-年付
+	Status       string     `gorm:"size:20;default:'active';index" json:"status"`   // 状态：active-正常，terminated-已终止
+	TerminatedAt *time.Time `json:"terminated_at"`                                  // 终止时间
+	Description  string     `gorm:"type:text" json:"description"`                   // 描述
+	Tenant       *Tenant    `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`    // 租户信息
+	Rooms        []Room     `gorm:"many2many:lease_rooms;" json:"rooms,omitempty"`  // 租赁房间
+}
+
+// TableName 设置表名
+func (Lease) TableName() string {
+	return "t_lease"
+}
+
+// 合同状态
+const (
+	LeaseStatusActive     = "active"
+	LeaseStatusTerminated = "terminated"
+)
+
+// 房间状态
+const (
+	RoomStatusAvailable   = "available"
+	RoomStatusRented      = "rented"
+	RoomStatusMaintenance = "maintenance"
+)
+
+// IsValidPaymentCycle 校验付款周期
+func IsValidPaymentCycle(cycle string) bool {
+	switch cycle {
+	case "monthly", "quarterly", "semiannual", "annual":
+		return true
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -20,6 +21,7 @@ type OperationLog struct {
 	ClientIP       string    `gorm:"size:50" json:"client_ip"`        // 客户端IP
 	UserAgent      string    `gorm:"size:500" json:"user_agent"`      // User-Agent
 	OperationTime  time.Time `gorm:"index" json:"operation_time"`     // 操作时间
+	RequestID      string    `gorm:"size:64;index" json:"request_id"` // 请求ID，关联数据变更记录
 }
 
 // TableName 设置表名
@@ -32,8 +34,8 @@ type LoginLog struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	UserID    uint      `gorm:"index" json:"user_id"`          // 用户ID
 	Username  string    `gorm:"size:50;index" json:"username"` // 用户名
-	LoginType string    `gorm:"size:20" json:"login_type"`     // 登录类型：login-登录，logout-登出
-	Status    string    `gorm:"size:20" json:"status"`         // 状态：success-成功，fail-失败
+	LoginType string    `gorm:"size:20" json:"login_type"`     // 登录类型：login-登录，2fa-两步验证，logout-登出
+	Status    string    `gorm:"size:20" json:"status"`         // 状态：success-成功，failed-失败，locked/locked_account/ip_blocked-锁定事件
 	Message   string    `gorm:"size:200" json:"message"`       // 消息
 	ClientIP  string    `gorm:"size:50" json:"client_ip"`      // 客户端IP
 	UserAgent string    `gorm:"size:500" json:"user_agent"`    // User-Agent
@@ -44,3 +46,30 @@ type LoginLog struct {
 func (LoginLog) TableName() string {
 	return "t_login_log"
 }
+
+// ChangeLog 数据变更记录，保存实体修改、删除前后的字段差异
+type ChangeLog struct {
+	ID         uint            `gorm:"primarykey" json:"id"`
+	EntityType string          `gorm:"size:50;index:idx_change_entity" json:"entity_type"` // 实体类型
+	EntityID   uint            `gorm:"index:idx_change_entity" json:"entity_id"`           // 实体ID
+	Action     string          `gorm:"size:20" json:"action"`                              // 操作：update-修改，delete-删除
+	Changes    json.RawMessage `gorm:"type:json" json:"changes"`                           // 字段差异：{"字段": {"before": 旧值, "after": 新值}}
+	UserID     uint            `gorm:"index" json:"user_id"`                               // 操作人ID
+	Username   string          `gorm:"size:50" json:"username"`                            // 操作人用户名
+	RequestID  string          `gorm:"size:64;index" json:"request_id"`                    // 请求ID，关联操作日志
+	ChangedAt  time.Time       `gorm:"index" json:"changed_at"`                            // 变更时间
+}
+
+// TableName 设置表名
+func (ChangeLog) TableName() string {
+	return "t_change_log"
+}
+
+// 变更记录实体类型
+const (
+	EntityAsset    = "asset"
+	EntityBuilding = "building"
+	EntityFloor    = "floor"
+	EntityRoom     = "room"
+	EntityUser     = "user"
+)
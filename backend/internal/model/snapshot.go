@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// OccupancySnapshot 出租情况每日快照，按资产、建筑、楼层分别记录
+type OccupancySnapshot struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	SnapshotDate     time.Time `gorm:"type:date;not null;uniqueIndex:idx_snapshot_entity" json:"snapshot_date"` // 快照日期
+	EntityType       string    `gorm:"size:20;not null;uniqueIndex:idx_snapshot_entity" json:"entity_type"`     // 实体类型：asset、building、floor
+	EntityID         uint      `gorm:"not null;uniqueIndex:idx_snapshot_entity" json:"entity_id"`               // 实体ID
+	StreetID         uint      `gorm:"index" json:"street_id"`                                                  // 所属街道ID
+	AssetID          uint      `gorm:"index" json:"asset_id"`                                                   // 所属资产ID
+	BuildingID       uint      `gorm:"index" json:"building_id"`                                                // 所属建筑ID（楼层快照）
+	RoomCount        int64     `json:"room_count"`                                                              // 房间数
+	AvailableRooms   int64     `json:"available_rooms"`                                                         // 可租房间数
+	RentedRooms      int64     `json:"rented_rooms"`                                                            // 已租房间数
+	MaintenanceRooms int64     `json:"maintenance_rooms"`                                                       // 维护中房间数
+	RentableArea     float64   `json:"rentable_area"`                                                           // 可租面积(平方米)
+	RentedArea       float64   `json:"rented_area"`                                                             // 已租面积(平方米)
+	RentedIncome     float64   `json:"rented_income"`                                                           // 已租房间月租金合计(元/月)
+	AvgRent          float64   `json:"avg_rent"`                                                                // 平均租金(元/平方米/月)
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (OccupancySnapshot) TableName() string {
+	return "t_occupancy_snapshot"
+}
@@ -15,9 +15,14 @@ type User struct {
 	Phone         string     `gorm:"size:20" json:"phone"`                             // 手机号
 	Email         string     `gorm:"size:100" json:"email"`                            // 邮箱
 	OrgID         uint       `gorm:"index" json:"org_id"`                              // 组织ID
-	Status        string     `gorm:"size:20;default:'active'" json:"status"`           // 状态：active-正常，inactive-禁用
+	Status        string     `gorm:"size:20;default:'active'" json:"status"`           // 状态：active-正常，inactive-禁用，locked-锁定
 	LastLoginTime *time.Time `json:"last_login_time"`                                  // 最后登录时间
 	LastLoginIP   string     `gorm:"size:50" json:"last_login_ip"`                     // 最后登录IP
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`                    // 密码最后修改时间
+	MustChangePassword bool       `gorm:"default:false" json:"must_change_password"` // 下次登录必须修改密码
+	TOTPSecret         string     `gorm:"size:64" json:"-"`                          // 两步验证密钥
+	TOTPEnabled        bool       `gorm:"default:false" json:"totp_enabled"`         // 是否已启用两步验证
+	TOTPLastStep       int64      `json:"-"`                                         // 最近一次使用的验证码时间步（防重放）
 	Roles         []Role     `gorm:"many2many:user_roles;" json:"roles"`               // 用户角色
 	Organization  *Organization `gorm:"foreignKey:OrgID" json:"organization,omitempty"` // 组织信息
 }
@@ -48,6 +53,33 @@ func (u *User) IsActive() bool {
 	return u.Status == "active"
 }
 
+// PasswordHistory 密码历史模型
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	UserID       uint      `gorm:"index;not null" json:"user_id"`  // 用户ID
+	PasswordHash string    `gorm:"size:100;not null" json:"-"`     // 密码（加密）
+	CreatedAt    time.Time `json:"created_at"`                     // 创建时间
+}
+
+// TableName 设置表名
+func (PasswordHistory) TableName() string {
+	return "t_password_history"
+}
+
+// RecoveryCode 两步验证恢复码模型
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"` // 用户ID
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`     // 恢复码哈希
+	UsedAt    *time.Time `json:"used_at"`                       // 使用时间
+	CreatedAt time.Time  `json:"created_at"`                    // 创建时间
+}
+
+// TableName 设置表名
+func (RecoveryCode) TableName() string {
+	return "t_recovery_code"
+}
+
 // Organization 组织模型
 type Organization struct {
 	BaseModel
@@ -76,6 +108,8 @@ type Role struct {
 	Description string       `gorm:"size:200" json:"description"`                    // 描述
 	Status      string       `gorm:"size:20;default:'active'" json:"status"`         // 状态
 	Sort        int          `gorm:"default:0" json:"sort"`                          // 排序
+	RequireTwoFactor bool    `gorm:"default:false" json:"require_two_factor"`        // 该角色用户是否必须启用两步验证
+	DataScope   string       `gorm:"size:30;default:'all'" json:"data_scope"`        // 数据权限：all-全部，org_and_children-本组织及下级，org-仅本组织，self-仅本人创建
 	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"` // 权限
 	Users       []User       `gorm:"many2many:user_roles;" json:"-"`                 // 用户
 }
@@ -85,6 +119,23 @@ func (Role) TableName() string {
 	return "t_role"
 }
 
+// 角色数据权限范围
+const (
+	DataScopeAll            = "all"              // 全部数据
+	DataScopeOrgAndChildren = "org_and_children" // 本组织及下级组织
+	DataScopeOrg            = "org"              // 仅本组织
+	DataScopeSelf           = "self"             // 仅本人创建
+)
+
+// IsValidDataScope 数据权限范围是否合法
+func IsValidDataScope(scope string) bool {
+	switch scope {
+	case DataScopeAll, DataScopeOrgAndChildren, DataScopeOrg, DataScopeSelf:
+		return true
+	}
+	return false
+}
+
 // Permission 权限模型
 type Permission struct {
 	BaseModel
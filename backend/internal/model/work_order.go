@@ -0,0 +1,97 @@
+package model
+
+import (
+	"time"
+)
+
+// WorkOrder 维修工单，位置可以是建筑、楼层或房间
+type WorkOrder struct {
+	AuditModel
+	OrderNo            string             `gorm:"uniqueIndex;size:50;not null" json:"order_no"`     // 工单编号
+	Title              string             `gorm:"size:200;not null" json:"title"`                   // 标题
+	Description        string             `gorm:"type:text" json:"description"`                     // 问题描述
+	Category           string             `gorm:"size:20;not null;index" json:"category"`           // 分类
+	Priority           string             `gorm:"size:20;not null;index" json:"priority"`           // 优先级：low-低，medium-中，high-高，urgent-紧急
+	Status             string             `gorm:"size:20;not null;index" json:"status"`             // 状态
+	BuildingID         uint               `gorm:"index;not null" json:"building_id"`                // 建筑ID
+	FloorID            *uint              `gorm:"index" json:"floor_id"`                            // 楼层ID
+	RoomID             *uint              `gorm:"index" json:"room_id"`                             // 房间ID
+	ReporterID         uint               `gorm:"index" json:"reporter_id"`                         // 报修人ID
+	ReporterName       string             `gorm:"size:50" json:"reporter_name"`                     // 报修人姓名
+	ReporterPhone      string             `gorm:"size:20" json:"reporter_phone"`                    // 报修人电话
+	AssigneeID         *uint              `gorm:"index" json:"assignee_id"`                         // 处理人ID
+	RoomMaintenance    bool               `json:"room_maintenance"`                                 // 是否将房间设为维护中
+	PreviousRoomStatus string             `gorm:"size:20" json:"previous_room_status"`              // 设为维护中之前的房间状态
+	DueAt              time.Time          `gorm:"index" json:"due_at"`                              // SLA截止时间，按优先级计算
+	AssignedAt         *time.Time         `json:"assigned_at"`                                      // 派单时间
+	StartedAt          *time.Time         `json:"started_at"`                                       // 开始处理时间
+	ResolvedAt         *time.Time         `json:"resolved_at"`                                      // 解决时间
+	ClosedAt           *time.Time         `json:"closed_at"`                                        // 关闭时间
+	Resolution         string             `gorm:"type:text" json:"resolution"`                      // 处理结果
+	Overdue            bool               `gorm:"-" json:"overdue"`                                 // 是否超出SLA（查询时填充）
+	Building           *Building          `gorm:"foreignKey:BuildingID" json:"building,omitempty"`  // 建筑信息
+	Floor              *Floor             `gorm:"foreignKey:FloorID" json:"floor,omitempty"`        // 楼层信息
+	Room               *Room              `gorm:"foreignKey:RoomID" json:"room,omitempty"`          // 房间信息
+	Assignee           *User              `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`  // 处理人信息
+	Comments           []WorkOrderComment `gorm:"foreignKey:WorkOrderID" json:"comments,omitempty"` // 评论
+}
+
+// TableName 设置表名
+func (WorkOrder) TableName() string {
+	return "t_work_order"
+}
+
+// IsOverdue 未完成的工单当前已超过截止时间，或已完成的工单完成时超过截止时间
+func (w *WorkOrder) IsOverdue(now time.Time) bool {
+	done := w.ResolvedAt
+	if done == nil {
+		done = w.ClosedAt
+	}
+	if done != nil {
+		return done.After(w.DueAt)
+	}
+	return now.After(w.DueAt)
+}
+
+// WorkOrderComment 工单评论，状态变更时也会记录一条评论
+type WorkOrderComment struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	WorkOrderID uint      `gorm:"index;not null" json:"work_order_id"` // 工单ID
+	UserID      uint      `gorm:"index" json:"user_id"`                // 评论人ID
+	Username    string    `gorm:"size:50" json:"username"`             // 评论人用户名
+	Content     string    `gorm:"type:text" json:"content"`            // 内容
+	Status      string    `gorm:"size:20" json:"status"`               // 状态变更后的工单状态，普通评论为空
+	CreatedAt   time.Time `json:"created_at"`                          // 评论时间
+}
+
+// TableName 设置表名
+func (WorkOrderComment) TableName() string {
+	return "t_work_order_comment"
+}
+
+// 工单状态
+const (
+	WorkOrderStatusOpen       = "open"
+	WorkOrderStatusAssigned   = "assigned"
+	WorkOrderStatusInProgress = "in_progress"
+	WorkOrderStatusResolved   = "resolved"
+	WorkOrderStatusClosed     = "closed"
+)
+
+// 工单优先级
+const (
+	WorkOrderPriorityLow    = "low"
+	WorkOrderPriorityMedium = "medium"
+	WorkOrderPriorityHigh   = "high"
+	WorkOrderPriorityUrgent = "urgent"
+)
+
+// IsValidWorkOrderCategory 校验工单分类：plumbing-给排水，electrical-电气，hvac-暖通空调，
+// structure-土建结构，elevator-电梯，fire-消防，cleaning-保洁，other-其他
+func IsValidWorkOrderCategory(category string) bool {
+	switch category {
+	case "plumbing", "electrical", "hvac", "structure", "elevator", "fire", "cleaning", "other":
+		return true
+	}
+	return false
+}
@@ -1,15 +1,27 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"time"
 
+	"building-asset-backend/internal/datascope"
 	"building-asset-backend/internal/model"
 	"building-asset-backend/pkg/database"
 
 	"gorm.io/gorm"
 )
 
+// ErrOutOfDataScope 超出当前用户的数据权限
+var ErrOutOfDataScope = errors.New("超出数据权限范围")
+
+// ParentNotFoundError 上级资产、建筑或楼层不存在或不在数据权限范围内
+type ParentNotFoundError string
+
+func (e ParentNotFoundError) Error() string {
+	return string(e)
+}
+
 type AssetService struct {
 	db *gorm.DB
 }
@@ -20,12 +32,37 @@ func NewAssetService() *AssetService {
 	}
 }
 
-// Asset operations
+// WithContext 返回绑定请求上下文的服务，查询时自动应用数据权限
+func (s *AssetService) WithContext(ctx context.Context) *AssetService {
+	return &AssetService{db: s.db.WithContext(ctx)}
+}
 
-func (s *AssetService) GetAssets(page, pageSize int, name, assetType, status string) ([]*model.Asset, int64, error) {
-	var assets []*model.Asset
-	var total int64
+// WithTx 返回在指定事务中执行的服务，用于将多个操作放在同一事务中
+func (s *AssetService) WithTx(tx *gorm.DB) *AssetService {
+	return &AssetService{db: tx}
+}
+
+// checkStreetScope 校验当前用户是否可以操作指定街道的资产
+func (s *AssetService) checkStreetScope(streetID uint) error {
+	if scope, ok := datascope.FromContext(s.db.Statement.Context); ok && !scope.AllowsOrg(streetID) {
+		return ErrOutOfDataScope
+	}
+	return nil
+}
+
+// checkParent 校验上级记录存在且在数据权限范围内，不存在时返回 ParentNotFoundError
+func (s *AssetService) checkParent(parent interface{}, id uint, notFound string) error {
+	err := s.db.Select("id").First(parent, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ParentNotFoundError(notFound)
+	}
+	return err
+}
+
+// Asset operations
 
+// assetQuery 资产列表查询条件
+func (s *AssetService) assetQuery(name, assetType, status string) *gorm.DB {
 	query := s.db.Model(&model.Asset{})
 
 	if name != "" {
@@ -37,6 +74,14 @@ func (s *AssetService) GetAssets(page, pageSize int, name, assetType, status str
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	return query
+}
+
+func (s *AssetService) GetAssets(page, pageSize int, name, assetType, status string) ([]*model.Asset, int64, error) {
+	var assets []*model.Asset
+	var total int64
+
+	query := s.assetQuery(name, assetType, status)
 
 	err := query.Count(&total).Error
 	if err != nil {
@@ -52,6 +97,11 @@ func (s *AssetService) GetAssets(page, pageSize int, name, assetType, status str
 	return assets, total, nil
 }
 
+// ExportAssets 按列表筛选条件分批读取全部资产
+func (s *AssetService) ExportAssets(name, assetType, status string, maxRows int, fn func([]*model.Asset) error) error {
+	return exportRows(s.assetQuery(name, assetType, status), maxRows, fn, "Street")
+}
+
 func (s *AssetService) GetAssetByID(id uint) (*model.Asset, error) {
 	var asset model.Asset
 	err := s.db.Preload("Buildings.Floors.Rooms").First(&asset, id).Error
@@ -62,35 +112,70 @@ func (s *AssetService) GetAssetByID(id uint) (*model.Asset, error) {
 }
 
 func (s *AssetService) CreateAsset(asset *model.Asset) (*model.Asset, error) {
+	if err := validateCoordinates(asset.Longitude, asset.Latitude); err != nil {
+		return nil, err
+	}
+	if err := s.checkStreetScope(asset.StreetID); err != nil {
+		return nil, err
+	}
+
 	// 检查名称是否重复
 	var count int64
-	s.db.Model(&model.Asset{}).Where("asset_name = ?", asset.AssetName).Count(&count)
+	datascope.Skip(s.db).Model(&model.Asset{}).Where("asset_name = ?", asset.AssetName).Count(&count)
 	if count > 0 {
 		return nil, errors.New("资产名称已存在")
 	}
 
-	if err := s.db.Create(asset).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(asset).Error; err != nil {
+			return err
+		}
+		if err := refreshRollups(tx, nil, nil, []uint{asset.ID}); err != nil {
+			return err
+		}
+		return tx.First(asset, asset.ID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return asset, nil
 }
 
 func (s *AssetService) UpdateAsset(id uint, updates *model.Asset) (*model.Asset, error) {
+	return s.updateAsset(id, updates, updates)
+}
+
+// updateAsset 按updates校验后写入values；导入时values为文件中填写了的列，以便写入0和否等零值
+func (s *AssetService) updateAsset(id uint, updates *model.Asset, values interface{}) (*model.Asset, error) {
+	if err := validateCoordinates(updates.Longitude, updates.Latitude); err != nil {
+		return nil, err
+	}
+
 	var asset model.Asset
 	if err := s.db.First(&asset, id).Error; err != nil {
 		return nil, err
 	}
 
+	if updates.StreetID > 0 && updates.StreetID != asset.StreetID {
+		if err := s.checkStreetScope(updates.StreetID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查名称是否重复
 	if updates.AssetName != "" && updates.AssetName != asset.AssetName {
 		var count int64
-		s.db.Model(&model.Asset{}).Where("asset_name = ? AND id != ?", updates.AssetName, id).Count(&count)
+		datascope.Skip(s.db).Model(&model.Asset{}).Where("asset_name = ? AND id != ?", updates.AssetName, id).Count(&count)
 		if count > 0 {
 			return nil, errors.New("资产名称已存在")
 		}
 	}
 
-	if err := s.db.Model(&asset).Updates(updates).Error; err != nil {
+	// 资产可租面积由建筑汇总，不接受手工修改
+	err := updateWithHistory(s.db, model.EntityAsset, id, &asset, values, func(tx *gorm.DB) error {
+		return refreshRollups(tx, nil, nil, []uint{id})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -98,22 +183,28 @@ func (s *AssetService) UpdateAsset(id uint, updates *model.Asset) (*model.Asset,
 }
 
 func (s *AssetService) DeleteAsset(id uint) error {
+	// 确认记录存在且在数据权限范围内
+	var asset model.Asset
+	if err := s.db.First(&asset, id).Error; err != nil {
+		return err
+	}
+
 	// 检查是否有关联的建筑
 	var count int64
-	s.db.Model(&model.Building{}).Where("asset_id = ?", id).Count(&count)
+	datascope.Skip(s.db).Model(&model.Building{}).Where("asset_id = ?", id).Count(&count)
 	if count > 0 {
 		return errors.New("该资产下存在建筑，无法删除")
 	}
 
-	return s.db.Delete(&model.Asset{}, id).Error
+	return deleteWithHistory(s.db, model.EntityAsset, id, &asset, func(tx *gorm.DB) error {
+		return deleteOwnerAttachments(tx, model.EntityAsset, id)
+	})
 }
 
 // Building operations
 
-func (s *AssetService) GetBuildings(page, pageSize int, assetID uint, name string) ([]*model.Building, int64, error) {
-	var buildings []*model.Building
-	var total int64
-
+// buildingQuery 建筑列表查询条件
+func (s *AssetService) buildingQuery(assetID uint, name string) *gorm.DB {
 	query := s.db.Model(&model.Building{})
 
 	if assetID > 0 {
@@ -122,6 +213,14 @@ func (s *AssetService) GetBuildings(page, pageSize int, assetID uint, name strin
 	if name != "" {
 		query = query.Where("building_name LIKE ?", "%"+name+"%")
 	}
+	return query
+}
+
+func (s *AssetService) GetBuildings(page, pageSize int, assetID uint, name string) ([]*model.Building, int64, error) {
+	var buildings []*model.Building
+	var total int64
+
+	query := s.buildingQuery(assetID, name)
 
 	err := query.Count(&total).Error
 	if err != nil {
@@ -137,6 +236,11 @@ func (s *AssetService) GetBuildings(page, pageSize int, assetID uint, name strin
 	return buildings, total, nil
 }
 
+// ExportBuildings 按列表筛选条件分批读取全部建筑
+func (s *AssetService) ExportBuildings(assetID uint, name string, maxRows int, fn func([]*model.Building) error) error {
+	return exportRows(s.buildingQuery(assetID, name), maxRows, fn, "Asset")
+}
+
 func (s *AssetService) GetBuildingByID(id uint) (*model.Building, error) {
 	var building model.Building
 	err := s.db.Preload("Asset").Preload("Floors.Rooms").First(&building, id).Error
@@ -148,40 +252,65 @@ func (s *AssetService) GetBuildingByID(id uint) (*model.Building, error) {
 
 func (s *AssetService) CreateBuilding(building *model.Building) (*model.Building, error) {
 	// 验证资产是否存在
-	var asset model.Asset
-	if err := s.db.First(&asset, building.AssetID).Error; err != nil {
-		return nil, errors.New("资产不存在")
+	if err := s.checkParent(&model.Asset{}, building.AssetID, "资产不存在"); err != nil {
+		return nil, err
 	}
 
 	// 检查名称是否重复
 	var count int64
-	s.db.Model(&model.Building{}).Where("asset_id = ? AND building_name = ?", building.AssetID, building.BuildingName).Count(&count)
+	datascope.Skip(s.db).Model(&model.Building{}).Where("asset_id = ? AND building_name = ?", building.AssetID, building.BuildingName).Count(&count)
 	if count > 0 {
 		return nil, errors.New("该资产下建筑名称已存在")
 	}
 
-	if err := s.db.Create(building).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(building).Error; err != nil {
+			return err
+		}
+		if err := refreshRollups(tx, nil, []uint{building.ID}, nil); err != nil {
+			return err
+		}
+		return tx.First(building, building.ID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return building, nil
 }
 
 func (s *AssetService) UpdateBuilding(id uint, updates *model.Building) (*model.Building, error) {
+	return s.updateBuilding(id, updates, updates)
+}
+
+// updateBuilding 同updateAsset，values为实际写入的内容
+func (s *AssetService) updateBuilding(id uint, updates *model.Building, values interface{}) (*model.Building, error) {
 	var building model.Building
 	if err := s.db.First(&building, id).Error; err != nil {
 		return nil, err
 	}
 
+	// 调整上级时校验新上级是否存在且在数据权限范围内
+	if updates.AssetID > 0 && updates.AssetID != building.AssetID {
+		if err := s.checkParent(&model.Asset{}, updates.AssetID, "资产不存在"); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查名称是否重复
 	if updates.BuildingName != "" && updates.BuildingName != building.BuildingName {
 		var count int64
-		s.db.Model(&model.Building{}).Where("asset_id = ? AND building_name = ? AND id != ?", building.AssetID, updates.BuildingName, id).Count(&count)
+		datascope.Skip(s.db).Model(&model.Building{}).Where("asset_id = ? AND building_name = ? AND id != ?", building.AssetID, updates.BuildingName, id).Count(&count)
 		if count > 0 {
 			return nil, errors.New("该资产下建筑名称已存在")
 		}
 	}
 
-	if err := s.db.Model(&building).Updates(updates).Error; err != nil {
+	// 建筑调整资产时原资产也需要重算，新资产随建筑重算
+	oldAssetID := building.AssetID
+	err := updateWithHistory(s.db, model.EntityBuilding, id, &building, values, func(tx *gorm.DB) error {
+		return refreshRollups(tx, nil, []uint{id}, []uint{oldAssetID})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -189,19 +318,37 @@ func (s *AssetService) UpdateBuilding(id uint, updates *model.Building) (*model.
 }
 
 func (s *AssetService) DeleteBuilding(id uint) error {
+	// 确认记录存在且在数据权限范围内
+	var building model.Building
+	if err := s.db.First(&building, id).Error; err != nil {
+		return err
+	}
+
 	// 检查是否有关联的楼层
 	var count int64
-	s.db.Model(&model.Floor{}).Where("building_id = ?", id).Count(&count)
+	datascope.Skip(s.db).Model(&model.Floor{}).Where("building_id = ?", id).Count(&count)
 	if count > 0 {
 		return errors.New("该建筑下存在楼层，无法删除")
 	}
 
-	return s.db.Delete(&model.Building{}, id).Error
+	return deleteWithHistory(s.db, model.EntityBuilding, id, &building, func(tx *gorm.DB) error {
+		if err := deleteOwnerAttachments(tx, model.EntityBuilding, id); err != nil {
+			return err
+		}
+		return refreshRollups(tx, nil, nil, []uint{building.AssetID})
+	})
 }
 
 // Floor operations
 
 func (s *AssetService) GetFloorsByBuildingID(buildingID uint) ([]*model.Floor, error) {
+	// 上级记录不在数据权限范围内时视为不存在
+	if buildingID > 0 {
+		if err := s.db.Select("id").First(&model.Building{}, buildingID).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	var floors []*model.Floor
 	err := s.db.Where("building_id = ?", buildingID).Order("floor_number").Find(&floors).Error
 	if err != nil {
@@ -210,42 +357,79 @@ func (s *AssetService) GetFloorsByBuildingID(buildingID uint) ([]*model.Floor, e
 	return floors, nil
 }
 
+// ExportFloors 分批读取楼层，buildingID为0时导出数据权限范围内的全部楼层
+func (s *AssetService) ExportFloors(buildingID uint, maxRows int, fn func([]*model.Floor) error) error {
+	query := s.db.Model(&model.Floor{})
+	if buildingID > 0 {
+		if err := s.db.Select("id").First(&model.Building{}, buildingID).Error; err != nil {
+			return err
+		}
+		query = query.Where("building_id = ?", buildingID)
+	}
+	return exportRows(query, maxRows, fn, "Building")
+}
+
 func (s *AssetService) CreateFloor(floor *model.Floor) (*model.Floor, error) {
 	// 验证建筑是否存在
-	var building model.Building
-	if err := s.db.First(&building, floor.BuildingID).Error; err != nil {
-		return nil, errors.New("建筑不存在")
+	if err := s.checkParent(&model.Building{}, floor.BuildingID, "建筑不存在"); err != nil {
+		return nil, err
 	}
 
 	// 检查楼层号是否重复
 	var count int64
-	s.db.Model(&model.Floor{}).Where("building_id = ? AND floor_number = ?", floor.BuildingID, floor.FloorNumber).Count(&count)
+	datascope.Skip(s.db).Model(&model.Floor{}).Where("building_id = ? AND floor_number = ?", floor.BuildingID, floor.FloorNumber).Count(&count)
 	if count > 0 {
 		return nil, errors.New("该建筑下楼层号已存在")
 	}
 
-	if err := s.db.Create(floor).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(floor).Error; err != nil {
+			return err
+		}
+		if err := refreshRollups(tx, []uint{floor.ID}, nil, nil); err != nil {
+			return err
+		}
+		return tx.First(floor, floor.ID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return floor, nil
 }
 
 func (s *AssetService) UpdateFloor(id uint, updates *model.Floor) (*model.Floor, error) {
+	return s.updateFloor(id, updates, updates)
+}
+
+// updateFloor 同updateAsset，values为实际写入的内容
+func (s *AssetService) updateFloor(id uint, updates *model.Floor, values interface{}) (*model.Floor, error) {
 	var floor model.Floor
 	if err := s.db.First(&floor, id).Error; err != nil {
 		return nil, err
 	}
 
+	// 调整上级时校验新上级是否存在且在数据权限范围内
+	if updates.BuildingID > 0 && updates.BuildingID != floor.BuildingID {
+		if err := s.checkParent(&model.Building{}, updates.BuildingID, "建筑不存在"); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查楼层号是否重复
 	if updates.FloorNumber > 0 && updates.FloorNumber != floor.FloorNumber {
 		var count int64
-		s.db.Model(&model.Floor{}).Where("building_id = ? AND floor_number = ? AND id != ?", floor.BuildingID, updates.FloorNumber, id).Count(&count)
+		datascope.Skip(s.db).Model(&model.Floor{}).Where("building_id = ? AND floor_number = ? AND id != ?", floor.BuildingID, updates.FloorNumber, id).Count(&count)
 		if count > 0 {
 			return nil, errors.New("该建筑下楼层号已存在")
 		}
 	}
 
-	if err := s.db.Model(&floor).Updates(updates).Error; err != nil {
+	// 楼层调整建筑时原建筑也需要重算，新建筑随楼层重算
+	oldBuildingID := floor.BuildingID
+	err := updateWithHistory(s.db, model.EntityFloor, id, &floor, values, func(tx *gorm.DB) error {
+		return refreshRollups(tx, []uint{id}, []uint{oldBuildingID}, nil)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -253,19 +437,37 @@ func (s *AssetService) UpdateFloor(id uint, updates *model.Floor) (*model.Floor,
 }
 
 func (s *AssetService) DeleteFloor(id uint) error {
+	// 确认记录存在且在数据权限范围内
+	var floor model.Floor
+	if err := s.db.First(&floor, id).Error; err != nil {
+		return err
+	}
+
 	// 检查是否有关联的房间
 	var count int64
-	s.db.Model(&model.Room{}).Where("floor_id = ?", id).Count(&count)
+	datascope.Skip(s.db).Model(&model.Room{}).Where("floor_id = ?", id).Count(&count)
 	if count > 0 {
 		return errors.New("该楼层下存在房间，无法删除")
 	}
 
-	return s.db.Delete(&model.Floor{}, id).Error
+	return deleteWithHistory(s.db, model.EntityFloor, id, &floor, func(tx *gorm.DB) error {
+		if err := deleteOwnerAttachments(tx, model.EntityFloor, id); err != nil {
+			return err
+		}
+		return refreshRollups(tx, nil, []uint{floor.BuildingID}, nil)
+	})
 }
 
 // Room operations
 
 func (s *AssetService) GetRoomsByFloorID(floorID uint) ([]*model.Room, error) {
+	// 上级记录不在数据权限范围内时视为不存在
+	if floorID > 0 {
+		if err := s.db.Select("id").First(&model.Floor{}, floorID).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	var rooms []*model.Room
 	err := s.db.Where("floor_id = ?", floorID).Order("room_number").Find(&rooms).Error
 	if err != nil {
@@ -274,42 +476,103 @@ func (s *AssetService) GetRoomsByFloorID(floorID uint) ([]*model.Room, error) {
 	return rooms, nil
 }
 
+// ExportRooms 分批读取房间，floorID为0时导出数据权限范围内的全部房间
+func (s *AssetService) ExportRooms(floorID uint, maxRows int, fn func([]*model.Room) error) error {
+	query := s.db.Model(&model.Room{})
+	if floorID > 0 {
+		if err := s.db.Select("id").First(&model.Floor{}, floorID).Error; err != nil {
+			return err
+		}
+		query = query.Where("floor_id = ?", floorID)
+	}
+	return exportRows(query, maxRows, fn, "Floor.Building")
+}
+
 func (s *AssetService) CreateRoom(room *model.Room) (*model.Room, error) {
 	// 验证楼层是否存在
-	var floor model.Floor
-	if err := s.db.First(&floor, room.FloorID).Error; err != nil {
-		return nil, errors.New("楼层不存在")
+	if err := s.checkParent(&model.Floor{}, room.FloorID, "楼层不存在"); err != nil {
+		return nil, err
 	}
 
 	// 检查房间号是否重复
 	var count int64
-	s.db.Model(&model.Room{}).Where("floor_id = ? AND room_number = ?", room.FloorID, room.RoomNumber).Count(&count)
+	datascope.Skip(s.db).Model(&model.Room{}).Where("floor_id = ? AND room_number = ?", room.FloorID, room.RoomNumber).Count(&count)
 	if count > 0 {
 		return nil, errors.New("该楼层下房间号已存在")
 	}
 
-	if err := s.db.Create(room).Error; err != nil {
+	// 出租状态由租赁合同决定，新房间只能为可租或维护中
+	if room.Status != model.RoomStatusMaintenance {
+		room.Status = model.RoomStatusAvailable
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(room).Error; err != nil {
+			return err
+		}
+		if err := refreshRollups(tx, []uint{room.FloorID}, nil, nil); err != nil {
+			return err
+		}
+		return tx.First(room, room.ID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return room, nil
 }
 
 func (s *AssetService) UpdateRoom(id uint, updates *model.Room) (*model.Room, error) {
+	return s.updateRoom(id, updates, updates)
+}
+
+// updateRoom 同updateAsset，values为实际写入的内容
+func (s *AssetService) updateRoom(id uint, updates *model.Room, values interface{}) (*model.Room, error) {
 	var room model.Room
 	if err := s.db.First(&room, id).Error; err != nil {
 		return nil, err
 	}
 
+	// 调整上级时校验新上级是否存在且在数据权限范围内
+	if updates.FloorID > 0 && updates.FloorID != room.FloorID {
+		if err := s.checkParent(&model.Floor{}, updates.FloorID, "楼层不存在"); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查房间号是否重复
 	if updates.RoomNumber != "" && updates.RoomNumber != room.RoomNumber {
 		var count int64
-		s.db.Model(&model.Room{}).Where("floor_id = ? AND room_number = ? AND id != ?", room.FloorID, updates.RoomNumber, id).Count(&count)
+		datascope.Skip(s.db).Model(&model.Room{}).Where("floor_id = ? AND room_number = ? AND id != ?", room.FloorID, updates.RoomNumber, id).Count(&count)
 		if count > 0 {
 			return nil, errors.New("该楼层下房间号已存在")
 		}
 	}
 
-	if err := s.db.Model(&room).Updates(updates).Error; err != nil {
+	// 可租、已租状态由租赁合同决定，手工只能设置或解除维护状态
+	if updates.Status != "" && updates.Status != model.RoomStatusMaintenance {
+		updates.Status = ""
+		if room.Status == model.RoomStatusMaintenance {
+			status, err := roomLeaseStatus(s.db, id)
+			if err != nil {
+				return nil, err
+			}
+			updates.Status = status
+		}
+	}
+	if fields, ok := values.(map[string]interface{}); ok && fields["status"] != nil {
+		if updates.Status == "" {
+			delete(fields, "status")
+		} else {
+			fields["status"] = updates.Status
+		}
+	}
+
+	// 房间调整楼层时原楼层和新楼层都需要重算
+	floorIDs := []uint{room.FloorID, updates.FloorID}
+	err := updateWithHistory(s.db, model.EntityRoom, id, &room, values, func(tx *gorm.DB) error {
+		return refreshRollups(tx, floorIDs, nil, nil)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -317,70 +580,27 @@ func (s *AssetService) UpdateRoom(id uint, updates *model.Room) (*model.Room, er
 }
 
 func (s *AssetService) DeleteRoom(id uint) error {
-	return s.db.Delete(&model.Room{}, id).Error
-}
-
-// Statistics
-
-func (s *AssetService) GetAssetStatistics() (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
-
-	// 资产统计
-	var assetStats []struct {
-		Type   string `json:"type"`
-		Count  int64  `json:"count"`
-		Status string `json:"status"`
-	}
-
-	s.db.Model(&model.Asset{}).
-		Select("type, status, count(*) as count").
-		Group("type, status").
-		Find(&assetStats)
-
-	// 建筑统计
-	var buildingCount int64
-	s.db.Model(&model.Building{}).Count(&buildingCount)
-
-	// 楼层统计
-	var floorCount int64
-	s.db.Model(&model.Floor{}).Count(&floorCount)
-
-	// 房间统计
-	var roomStats []struct {
-		Type  string `json:"type"`
-		Count int64  `json:"count"`
-	}
-	s.db.Model(&model.Room{}).
-		Select("type, count(*) as count").
-		Group("type").
-		Find(&roomStats)
-
-	// 面积统计
-	var totalArea struct {
-		BuildingArea float64 `json:"building_area"`
-		RoomArea     float64 `json:"room_area"`
+	// 确认记录存在且在数据权限范围内
+	var room model.Room
+	if err := s.db.First(&room, id).Error; err != nil {
+		return err
 	}
-	s.db.Model(&model.Building{}).Select("sum(floor_area) as building_area").Find(&totalArea)
-	s.db.Model(&model.Room{}).Select("sum(area) as room_area").Find(&totalArea.RoomArea)
-
-	// 使用率统计
-	var occupiedRooms int64
-	s.db.Model(&model.Room{}).Where("status = ?", "occupied").Count(&occupiedRooms)
-
-	var totalRooms int64
-	s.db.Model(&model.Room{}).Count(&totalRooms)
 
-	occupancyRate := float64(0)
-	if totalRooms > 0 {
-		occupancyRate = float64(occupiedRooms) / float64(totalRooms) * 100
+	// 检查是否有未到期的租赁合同
+	var count int64
+	datascope.Skip(s.db).Table("t_lease_rooms lr").
+		Joins("JOIN t_lease l ON l.id = lr.lease_id").
+		Where("lr.room_id = ? AND l.deleted_at IS NULL AND l.status = ? AND l.end_date >= ?",
+			id, model.LeaseStatusActive, time.Now().Format(dateLayout)).
+		Count(&count)
+	if count > 0 {
+		return errors.New("该房间存在未到期的租赁合同，无法删除")
 	}
 
-	stats["assets"] = assetStats
-	stats["building_count"] = buildingCount
-	stats["floor_count"] = floorCount
-	stats["room_stats"] = roomStats
-	stats["total_area"] = totalArea
-	stats["occupancy_rate"] = fmt.Sprintf("%.2f%%", occupancyRate)
-
-	return stats, nil
+	return deleteWithHistory(s.db, model.EntityRoom, id, &room, func(tx *gorm.DB) error {
+		if err := deleteOwnerAttachments(tx, model.EntityRoom, id); err != nil {
+			return err
+		}
+		return refreshRollups(tx, []uint{room.FloorID}, nil, nil)
+	})
 }
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"building-asset-backend/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// earthRadius 与MySQL ST_Distance_Sphere默认使用的地球半径一致(米)
+const earthRadius = 6370986
+
+// maxSearchRadius 半径查询的最大半径(米)
+const maxSearchRadius = 100000
+
+var (
+	// ErrInvalidCoordinates 经纬度超出有效范围
+	ErrInvalidCoordinates = errors.New("经纬度无效，经度范围为-180~180，纬度范围为-90~90")
+	// ErrInvalidGeoQuery 空间查询参数无效
+	ErrInvalidGeoQuery = errors.New("空间查询参数无效")
+)
+
+// geoQueryError 空间查询参数错误，匹配 ErrInvalidGeoQuery 并携带原因
+type geoQueryError struct {
+	reason string
+}
+
+func (e geoQueryError) Error() string {
+	return e.reason
+}
+
+func (e geoQueryError) Is(target error) bool {
+	return target == ErrInvalidGeoQuery
+}
+
+// validateCoordinates 校验经纬度范围
+func validateCoordinates(lng, lat float64) error {
+	if math.IsNaN(lng) || math.IsNaN(lat) || lng < -180 || lng > 180 || lat < -90 || lat > 90 {
+		return ErrInvalidCoordinates
+	}
+	return nil
+}
+
+// validateBBox 校验矩形范围
+func validateBBox(minLng, minLat, maxLng, maxLat float64) error {
+	for _, point := range [][2]float64{{minLng, minLat}, {maxLng, maxLat}} {
+		if err := validateCoordinates(point[0], point[1]); err != nil {
+			return err
+		}
+	}
+	if minLng > maxLng || minLat > maxLat {
+		return geoQueryError{"矩形范围的最小经纬度不能大于最大经纬度"}
+	}
+	return nil
+}
+
+// GeoAsset 空间查询命中的资产及其汇总指标
+type GeoAsset struct {
+	*model.Asset
+	Distance      *float64       `json:"distance,omitempty"` // 与查询中心点的距离(米)，仅半径查询返回
+	BuildingCount int64          `json:"building_count"`     // 建筑数
+	Statistics    StatisticsItem `json:"statistics"`         // 出租指标，与资产自身字段区分
+}
+
+// GeoSummary 空间查询结果汇总
+type GeoSummary struct {
+	AssetCount    int64   `json:"asset_count"`    // 资产数
+	BuildingCount int64   `json:"building_count"` // 建筑数
+	TotalArea     float64 `json:"total_area"`     // 资产总面积(平方米)
+	StatisticsItem
+}
+
+// GeoSearchResult 空间查询结果
+type GeoSearchResult struct {
+	Summary GeoSummary  `json:"summary"`
+	Assets  []*GeoAsset `json:"assets"`
+}
+
+// SearchAssetsInBBox 查询矩形范围内的资产，包含边界上的资产
+func (s *AssetService) SearchAssetsInBBox(minLng, minLat, maxLng, maxLat float64) (*GeoSearchResult, error) {
+	if err := validateBBox(minLng, minLat, maxLng, maxLat); err != nil {
+		return nil, err
+	}
+
+	var assets []*model.Asset
+	err := s.db.Where("MBRCovers(ST_GeomFromText(?), location)", envelopeWKT(minLng, minLat, maxLng, maxLat)).
+		Order("id").Find(&assets).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.geoResult(assets, nil)
+}
+
+// SearchAssetsInRadius 查询距中心点radius米内的资产，按距离由近到远排序
+func (s *AssetService) SearchAssetsInRadius(lng, lat, radius float64) (*GeoSearchResult, error) {
+	if err := validateCoordinates(lng, lat); err != nil {
+		return nil, err
+	}
+	if !(radius > 0 && radius <= maxSearchRadius) {
+		return nil, geoQueryError{fmt.Sprintf("半径须大于0且不超过%d米", maxSearchRadius)}
+	}
+
+	// 先按外接矩形走空间索引，再按球面距离精确过滤
+	dLat := radius / earthRadius * 180 / math.Pi
+	dLng := 180.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > 1e-6 {
+		dLng = math.Min(dLat/cos, 180)
+	}
+	envelope := envelopeWKT(math.Max(lng-dLng, -180), math.Max(lat-dLat, -90), math.Min(lng+dLng, 180), math.Min(lat+dLat, 90))
+
+	var assets []*model.Asset
+	err := s.db.Where("MBRCovers(ST_GeomFromText(?), location)", envelope).
+		Where("ST_Distance_Sphere(location, POINT(?, ?)) <= ?", lng, lat, radius).
+		Find(&assets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	distances := make(map[uint]float64, len(assets))
+	for _, asset := range assets {
+		distances[asset.ID] = round2(sphereDistance(lng, lat, asset.Longitude, asset.Latitude))
+	}
+	sort.SliceStable(assets, func(i, j int) bool {
+		return distances[assets[i].ID] < distances[assets[j].ID]
+	})
+	return s.geoResult(assets, distances)
+}
+
+// SearchAssetsInPolygon 查询GeoJSON多边形内的资产
+// 支持Polygon、MultiPolygon及包含它们的Feature
+func (s *AssetService) SearchAssetsInPolygon(geojson []byte) (*GeoSearchResult, error) {
+	wkt, err := polygonWKT(geojson)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []*model.Asset
+	err = s.db.Where("ST_Contains(ST_GeomFromText(?), location)", wkt).Order("id").Find(&assets).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.geoResult(assets, nil)
+}
+
+// geoResult 汇总命中资产的建筑数、面积和出租指标
+func (s *AssetService) geoResult(assets []*model.Asset, distances map[uint]float64) (*GeoSearchResult, error) {
+	result := &GeoSearchResult{Assets: make([]*GeoAsset, 0, len(assets))}
+	if len(assets) == 0 {
+		return result, nil
+	}
+
+	buildings, rooms, err := s.assetAggregates(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	var total roomAggregate
+	for _, asset := range assets {
+		item := &GeoAsset{
+			Asset:         asset,
+			BuildingCount: buildings[asset.ID],
+			Statistics:    rooms[asset.ID].item(),
+		}
+		if distance, ok := distances[asset.ID]; ok {
+			item.Distance = &distance
+		}
+		result.Assets = append(result.Assets, item)
+		result.Summary.BuildingCount += item.BuildingCount
+		result.Summary.TotalArea += asset.TotalArea
+		total.add(rooms[asset.ID])
+	}
+	result.Summary.AssetCount = int64(len(assets))
+	result.Summary.TotalArea = round2(result.Summary.TotalArea)
+	result.Summary.StatisticsItem = total.item()
+	return result, nil
+}
+
+// assetAggregates 按资产统计建筑数和房间出租情况，房间按当前状态统计
+func (s *AssetService) assetAggregates(assets []*model.Asset) (map[uint]int64, map[uint]roomAggregate, error) {
+	ids := make([]uint, len(assets))
+	for i, asset := range assets {
+		ids[i] = asset.ID
+	}
+
+	var buildingCounts []struct {
+		AssetID uint
+		Count   int64
+	}
+	err := s.db.Model(&model.Building{}).Select("asset_id, COUNT(*) AS count").
+		Where("asset_id IN ?", ids).Group("asset_id").Scan(&buildingCounts).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	buildings := make(map[uint]int64, len(buildingCounts))
+	for _, row := range buildingCounts {
+		buildings[row.AssetID] = row.Count
+	}
+
+	filter := AssetStatisticsFilter{AssetIDs: ids}
+	rented := clause.Expr{SQL: "t_room.status = ?", Vars: []interface{}{model.RoomStatusRented}}
+	var rows []roomAggregate
+	if err := s.roomAggregates(filter, time.Time{}, rented, clause.Expr{SQL: "a.id"}, clause.Expr{SQL: "''"}, &rows); err != nil {
+		return nil, nil, err
+	}
+	rooms := make(map[uint]roomAggregate, len(rows))
+	for _, row := range rows {
+		id, err := strconv.ParseUint(row.GroupKey, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		rooms[uint(id)] = row
+	}
+	return buildings, rooms, nil
+}
+
+// sphereDistance 按球面计算两点间距离(米)，与ST_Distance_Sphere一致
+func sphereDistance(lng1, lat1, lng2, lat2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLng := (lng2 - lng1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// envelopeWKT 生成矩形的WKT
+func envelopeWKT(minLng, minLat, maxLng, maxLat float64) string {
+	return "POLYGON((" + joinPositions([][2]float64{
+		{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+	}) + "))"
+}
+
+// geoJSONObject GeoJSON几何对象或Feature
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+}
+
+// polygonWKT 将GeoJSON多边形转换为WKT，校验坐标范围和环的闭合
+func polygonWKT(data []byte) (string, error) {
+	var object geoJSONObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return "", geoQueryError{"GeoJSON格式错误"}
+	}
+	if object.Type == "Feature" {
+		if object.Geometry == nil {
+			return "", geoQueryError{"Feature缺少geometry"}
+		}
+		object = *object.Geometry
+	}
+
+	switch object.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(object.Coordinates, &polygon); err != nil {
+			return "", geoQueryError{"Polygon坐标格式错误"}
+		}
+		body, err := polygonBody(polygon)
+		if err != nil {
+			return "", err
+		}
+		return "POLYGON" + body, nil
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(object.Coordinates, &polygons); err != nil {
+			return "", geoQueryError{"MultiPolygon坐标格式错误"}
+		}
+		if len(polygons) == 0 {
+			return "", geoQueryError{"MultiPolygon不能为空"}
+		}
+		bodies := make([]string, len(polygons))
+		for i, polygon := range polygons {
+			body, err := polygonBody(polygon)
+			if err != nil {
+				return "", err
+			}
+			bodies[i] = body
+		}
+		return "MULTIPOLYGON(" + strings.Join(bodies, ",") + ")", nil
+	}
+	return "", geoQueryError{"仅支持Polygon、MultiPolygon类型的GeoJSON"}
+}
+
+// polygonBody 生成多边形的WKT坐标部分，第一个环为外环，其余为内环
+func polygonBody(rings [][][]float64) (string, error) {
+	if len(rings) == 0 {
+		return "", geoQueryError{"多边形不能为空"}
+	}
+	parts := make([]string, len(rings))
+	for i, ring := range rings {
+		if len(ring) < 4 {
+			return "", geoQueryError{"多边形的每个环至少需要4个坐标"}
+		}
+		positions := make([][2]float64, len(ring))
+		for j, position := range ring {
+			// 坐标可带高程，只取经纬度
+			if len(position) < 2 {
+				return "", geoQueryError{"坐标须包含经度和纬度"}
+			}
+			if err := validateCoordinates(position[0], position[1]); err != nil {
+				return "", err
+			}
+			positions[j] = [2]float64{position[0], position[1]}
+		}
+		if positions[0] != positions[len(positions)-1] {
+			return "", geoQueryError{"多边形的环须首尾闭合"}
+		}
+		parts[i] = "(" + joinPositions(positions) + ")"
+	}
+	return "(" + strings.Join(parts, ",") + ")", nil
+}
+
+func joinPositions(positions [][2]float64) string {
+	parts := make([]string, len(positions))
+	for i, p := range positions {
+		parts[i] = strconv.FormatFloat(p[0], 'f', -1, 64) + " " + strconv.FormatFloat(p[1], 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestValidateBBox(t *testing.T) {
+	tests := []struct {
+		name                           string
+		minLng, minLat, maxLng, maxLat float64
+		wantErr                        error
+	}{
+		{"valid", 116.3, 39.8, 116.5, 40.0, nil},
+		{"single point", 116.3, 39.8, 116.3, 39.8, nil},
+		{"whole world", -180, -90, 180, 90, nil},
+		{"longitude out of range", 116.3, 39.8, 181, 40.0, ErrInvalidCoordinates},
+		{"latitude out of range", 116.3, -91, 116.5, 40.0, ErrInvalidCoordinates},
+		{"NaN", math.NaN(), 39.8, 116.5, 40.0, ErrInvalidCoordinates},
+		{"min longitude greater than max", 116.5, 39.8, 116.3, 40.0, ErrInvalidGeoQuery},
+		{"min latitude greater than max", 116.3, 40.0, 116.5, 39.8, ErrInvalidGeoQuery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBBox(tt.minLng, tt.minLat, tt.maxLng, tt.maxLat)
+			if (tt.wantErr == nil) != (err == nil) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolygonWKT(t *testing.T) {
+	tests := []struct {
+		name    string
+		geoJSON string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "polygon",
+			geoJSON: `{"type":"Polygon","coordinates":[[[116.3,39.8],[116.5,39.8],[116.5,40],[116.3,39.8]]]}`,
+			want:    "POLYGON((116.3 39.8,116.5 39.8,116.5 40,116.3 39.8))",
+		},
+		{
+			name: "polygon with hole and elevation",
+			geoJSON: `{"type":"Polygon","coordinates":[` +
+				`[[0,0,10],[10,0,10],[10,10,10],[0,10,10],[0,0,10]],` +
+				`[[2,2],[4,2],[4,4],[2,2]]]}`,
+			want: "POLYGON((0 0,10 0,10 10,0 10,0 0),(2 2,4 2,4 4,2 2))",
+		},
+		{
+			name:    "feature",
+			geoJSON: `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}`,
+			want:    "POLYGON((0 0,1 0,1 1,0 0))",
+		},
+		{
+			name:    "multipolygon",
+			geoJSON: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[5,5],[6,5],[6,6],[5,5]]]]}`,
+			want:    "MULTIPOLYGON(((0 0,1 0,1 1,0 0)),((5 5,6 5,6 6,5 5)))",
+		},
+		{name: "invalid json", geoJSON: `{`, wantErr: ErrInvalidGeoQuery},
+		{name: "feature without geometry", geoJSON: `{"type":"Feature"}`, wantErr: ErrInvalidGeoQuery},
+		{name: "point", geoJSON: `{"type":"Point","coordinates":[0,0]}`, wantErr: ErrInvalidGeoQuery},
+		{name: "empty multipolygon", geoJSON: `{"type":"MultiPolygon","coordinates":[]}`, wantErr: ErrInvalidGeoQuery},
+		{name: "empty polygon", geoJSON: `{"type":"Polygon","coordinates":[]}`, wantErr: ErrInvalidGeoQuery},
+		{name: "too few positions", geoJSON: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}`, wantErr: ErrInvalidGeoQuery},
+		{name: "open ring", geoJSON: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, wantErr: ErrInvalidGeoQuery},
+		{name: "position without latitude", geoJSON: `{"type":"Polygon","coordinates":[[[0],[1,0],[1,1],[0]]]}`, wantErr: ErrInvalidGeoQuery},
+		{name: "coordinates out of range", geoJSON: `{"type":"Polygon","coordinates":[[[0,0],[190,0],[1,1],[0,0]]]}`, wantErr: ErrInvalidCoordinates},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := polygonWKT([]byte(tt.geoJSON))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("wkt = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,937 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"building-asset-backend/internal/audit"
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/spreadsheet"
+
+	"gorm.io/gorm"
+)
+
+// 导入行处理结果
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionError     = "error"
+)
+
+// maxImportRows 单个工作表允许的最大数据行数
+const maxImportRows = 5000
+
+// ErrInvalidImportFile 导入文件无法解析或缺少必要的列
+var ErrInvalidImportFile = errors.New("导入文件无效")
+
+// importFileError 导入文件错误，匹配 ErrInvalidImportFile 并携带具体原因
+type importFileError string
+
+func (e importFileError) Error() string {
+	return string(e)
+}
+
+func (e importFileError) Is(target error) bool {
+	return target == ErrInvalidImportFile
+}
+
+// errImportRollback 预览或存在错误行时回滚导入事务
+var errImportRollback = errors.New("import rolled back")
+
+// importColumn 导入模板列
+type importColumn struct {
+	Key      string // 字段名
+	Label    string // 模板表头
+	Required bool   // 新建记录时必填，匹配字段始终必填
+	Hint     string // 填写说明
+}
+
+// importLevel 导入层级，按资产、建筑、楼层、房间的顺序处理，上级记录先于下级保存
+type importLevel struct {
+	Entity  string
+	Sheet   string   // 分层模板中的工作表名称
+	Keys    []string // 匹配已有记录的字段
+	Columns []importColumn
+}
+
+var importLevels = []*importLevel{
+	{
+		Entity: model.EntityAsset,
+		Sheet:  "资产",
+		Keys:   []string{"asset_code"},
+		Columns: []importColumn{
+			{Key: "asset_code", Label: "资产编码", Required: true, Hint: "唯一，已存在时更新该资产"},
+			{Key: "asset_name", Label: "资产名称", Required: true},
+			{Key: "street_code", Label: "街道编码", Required: true, Hint: "所属街道的组织代码"},
+			{Key: "address", Label: "详细地址"},
+			{Key: "longitude", Label: "经度"},
+			{Key: "latitude", Label: "纬度"},
+			{Key: "land_nature", Label: "土地性质"},
+			{Key: "total_area", Label: "资产总面积", Hint: "平方米"},
+			{Key: "asset_tags", Label: "资产标签", Hint: "多个标签以逗号分隔"},
+			{Key: "description", Label: "资产描述"},
+		},
+	},
+	{
+		Entity: model.EntityBuilding,
+		Sheet:  "建筑",
+		Keys:   []string{"building_code"},
+		Columns: []importColumn{
+			{Key: "asset_code", Label: "资产编码", Required: true, Hint: "所属资产的资产编码"},
+			{Key: "building_code", Label: "建筑编码", Required: true, Hint: "唯一，已存在时更新该建筑"},
+			{Key: "building_name", Label: "建筑名称", Required: true},
+			{Key: "building_type", Label: "建筑类型"},
+			{Key: "total_floors", Label: "总楼层数"},
+			{Key: "underground_floors", Label: "地下楼层数"},
+			{Key: "total_area", Label: "建筑总面积", Hint: "平方米"},
+			{Key: "construction_year", Label: "建造年份"},
+			{Key: "elevator_count", Label: "电梯数量"},
+			{Key: "parking_spaces", Label: "停车位数量"},
+			{Key: "green_rate", Label: "绿化率", Hint: "百分比"},
+			{Key: "property_company", Label: "物业公司"},
+			{Key: "property_phone", Label: "物业电话"},
+			{Key: "features", Label: "配套设施", Hint: "多项以逗号分隔"},
+			{Key: "description", Label: "建筑描述"},
+		},
+	},
+	{
+		Entity: model.EntityFloor,
+		Sheet:  "楼层",
+		Keys:   []string{"building_code", "floor_number"},
+		Columns: []importColumn{
+			{Key: "building_code", Label: "建筑编码", Required: true, Hint: "所属建筑的建筑编码"},
+			{Key: "floor_number", Label: "楼层号", Required: true, Hint: "整数，与建筑编码共同匹配已有楼层"},
+			{Key: "floor_name", Label: "楼层名称"},
+			{Key: "floor_area", Label: "楼层面积", Hint: "平方米"},
+			{Key: "description", Label: "楼层描述"},
+		},
+	},
+	{
+		Entity: model.EntityRoom,
+		Sheet:  "房间",
+		Keys:   []string{"building_code", "floor_number", "room_number"},
+		Columns: []importColumn{
+			{Key: "building_code", Label: "建筑编码", Required: true, Hint: "所属建筑的建筑编码"},
+			{Key: "floor_number", Label: "楼层号", Required: true, Hint: "所属楼层的楼层号"},
+			{Key: "room_number", Label: "房间号", Required: true, Hint: "与建筑编码、楼层号共同匹配已有房间"},
+			{Key: "room_type", Label: "房间类型", Hint: "office-办公室，meeting-会议室，other-其他"},
+			{Key: "room_area", Label: "房间面积", Hint: "平方米"},
+			{Key: "rent_price", Label: "租金", Hint: "元/月"},
+			{Key: "decoration", Label: "装修情况", Hint: "blank-毛坯，simple-简装，luxury-精装"},
+			{Key: "orientation", Label: "朝向", Hint: "east-东，south-南，west-西，north-北"},
+			{Key: "has_window", Label: "是否有窗", Hint: "是/否"},
+			{Key: "has_ac", Label: "是否有空调", Hint: "是/否"},
+			{Key: "status", Label: "房间状态", Hint: "可填maintenance设为维护中，出租状态由租赁合同决定"},
+			{Key: "description", Label: "房间描述"},
+		},
+	},
+}
+
+// column 按字段名查找列定义
+func (l *importLevel) column(key string) importColumn {
+	for _, column := range l.Columns {
+		if column.Key == key {
+			return column
+		}
+	}
+	return importColumn{Key: key, Label: key}
+}
+
+// ImportRowResult 导入行的处理结果
+type ImportRowResult struct {
+	Sheet   string                       `json:"sheet"`             // 工作表
+	Row     int                          `json:"row"`               // 行号，表头为第1行
+	Entity  string                       `json:"entity"`            // 实体类型：asset、building、floor、room
+	Key     string                       `json:"key"`               // 匹配键
+	Action  string                       `json:"action"`            // 处理结果：create、update、unchanged、error
+	ID      uint                         `json:"id,omitempty"`      // 保存后的记录ID，预览时为空
+	Changes map[string]audit.FieldChange `json:"changes,omitempty"` // 更新的字段
+	Error   string                       `json:"error,omitempty"`   // 错误原因
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	DryRun    bool              `json:"dry_run"`   // 是否为预览
+	Committed bool              `json:"committed"` // 是否已保存，预览或存在错误行时不保存任何记录
+	Created   int               `json:"created"`   // 新建记录数
+	Updated   int               `json:"updated"`   // 更新记录数
+	Unchanged int               `json:"unchanged"` // 无变化记录数
+	Failed    int               `json:"failed"`    // 错误行数
+	Rows      []ImportRowResult `json:"rows"`      // 逐行结果
+}
+
+func (r *ImportResult) add(row ImportRowResult) {
+	switch row.Action {
+	case ImportActionCreate:
+		r.Created++
+	case ImportActionUpdate:
+		r.Updated++
+	case ImportActionUnchanged:
+		r.Unchanged++
+	default:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// ImportAssets 从CSV或XLSX导入资产、建筑、楼层和房间，按编码匹配已有记录更新，否则新建
+// 每条记录按与单条维护相同的规则校验和保存，全部记录在同一事务中处理；预览或存在错误行时整体回滚
+func (s *AssetService) ImportAssets(r io.Reader, format string, dryRun bool) (*ImportResult, error) {
+	sheets, err := spreadsheet.Read(r, format)
+	if err != nil {
+		return nil, importFileError(err.Error())
+	}
+	records, conflicts, err := readImportRecords(sheets)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{DryRun: dryRun, Rows: []ImportRowResult{}}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		importer := &assetImporter{db: tx, streets: make(map[string]uint)}
+		for i := range importLevels {
+			for _, record := range records[i] {
+				result.add(importer.apply(record))
+			}
+		}
+		for _, conflict := range conflicts {
+			result.add(conflict)
+		}
+		if dryRun || result.Failed > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		result.Committed = true
+	case errors.Is(err, errImportRollback):
+		for i := range result.Rows {
+			result.Rows[i].ID = 0
+		}
+	default:
+		return nil, err
+	}
+
+	// 按层级和行号排列，与文件中的顺序对应
+	order := make(map[string]int, len(importLevels))
+	for i, level := range importLevels {
+		order[level.Entity] = i
+	}
+	sort.SliceStable(result.Rows, func(i, j int) bool {
+		a, b := result.Rows[i], result.Rows[j]
+		if order[a.Entity] != order[b.Entity] {
+			return order[a.Entity] < order[b.Entity]
+		}
+		return a.Row < b.Row
+	})
+
+	return result, nil
+}
+
+// ImportTemplate 生成导入模板
+// xlsx为资产、建筑、楼层、房间分工作表的模板，附填写说明；csv为每行一个房间、上级信息重复填写的平铺模板
+func ImportTemplate(format string) []spreadsheet.Sheet {
+	if format == spreadsheet.FormatCSV {
+		var header []string
+		seen := make(map[string]bool)
+		for _, level := range importLevels {
+			for _, column := range level.Columns {
+				if !seen[column.Key+column.Label] {
+					seen[column.Key+column.Label] = true
+					header = append(header, templateLabel(column))
+				}
+			}
+		}
+		return []spreadsheet.Sheet{{Rows: [][]string{header}}}
+	}
+
+	sheets := make([]spreadsheet.Sheet, 0, len(importLevels)+1)
+	guide := spreadsheet.Sheet{Name: "填写说明", Rows: [][]string{{"工作表", "表头", "字段", "必填", "说明"}}}
+	for _, level := range importLevels {
+		header := make([]string, 0, len(level.Columns))
+		for _, column := range level.Columns {
+			header = append(header, templateLabel(column))
+			required := ""
+			if column.Required {
+				required = "是"
+			}
+			guide.Rows = append(guide.Rows, []string{level.Sheet, column.Label, column.Key, required, column.Hint})
+		}
+		sheets = append(sheets, spreadsheet.Sheet{Name: level.Sheet, Rows: [][]string{header}})
+	}
+	return append(sheets, guide)
+}
+
+// templateLabel 模板表头，必填列以*标记
+func templateLabel(column importColumn) string {
+	if column.Required {
+		return column.Label + "*"
+	}
+	return column.Label
+}
+
+// importRecord 从文件中读取的一条记录
+type importRecord struct {
+	level  *importLevel
+	sheet  string
+	row    int
+	values map[string]string
+}
+
+func (r *importRecord) key() string {
+	parts := make([]string, len(r.level.Keys))
+	for i, key := range r.level.Keys {
+		parts[i] = r.values[key]
+	}
+	return strings.Join(parts, "/")
+}
+
+func (r *importRecord) result() ImportRowResult {
+	return ImportRowResult{Sheet: r.sheet, Row: r.row, Entity: r.level.Entity, Key: r.key()}
+}
+
+// requireAll 新建记录时校验必填列
+func (r *importRecord) requireAll() error {
+	var missing []string
+	for _, column := range r.level.Columns {
+		if column.Required && r.values[column.Key] == "" {
+			missing = append(missing, column.Label)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("新建%s缺少必填列：%s", r.level.Sheet, strings.Join(missing, "、"))
+	}
+	return nil
+}
+
+func (r *importRecord) float(key string) (float64, error) {
+	value := strings.ReplaceAll(r.values[key], ",", "")
+	if value == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s不是有效的数字", r.level.column(key).Label)
+	}
+	return v, nil
+}
+
+func (r *importRecord) integer(key string) (int, error) {
+	value := r.values[key]
+	if value == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s不是有效的整数", r.level.column(key).Label)
+	}
+	return v, nil
+}
+
+func (r *importRecord) boolean(key string) (bool, error) {
+	switch strings.ToLower(r.values[key]) {
+	case "", "否", "无", "0", "false", "no", "n":
+		return false, nil
+	case "是", "有", "1", "true", "yes", "y":
+		return true, nil
+	}
+	return false, fmt.Errorf("%s只能填写是或否", r.level.column(key).Label)
+}
+
+func (r *importRecord) list(key string) model.StringArray {
+	fields := strings.FieldsFunc(r.values[key], func(c rune) bool {
+		return c == ',' || c == '，' || c == '、' || c == ';' || c == '；'
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	items := make(model.StringArray, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			items = append(items, field)
+		}
+	}
+	return items
+}
+
+// fields 取出文件中填写了的列，用于更新已有记录：未填写的列保持原值，填写了0、否等零值的列照常写入
+func (r *importRecord) fields(values map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if r.values[key] != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// readImportRecords 读取各层级的记录，同一记录在多行出现时合并非空单元格，内容冲突的行作为错误行返回
+// 工作表名称为资产、建筑、楼层、房间（或asset、building、floor、room）时按分层模板读取，
+// 否则将第一个工作表作为平铺模板，每行按表头识别出的各层级分别读取
+func readImportRecords(sheets []spreadsheet.Sheet) ([][]*importRecord, []ImportRowResult, error) {
+	reader := &importReader{
+		records: make([][]*importRecord, len(importLevels)),
+		seen:    make([]map[string]*importRecord, len(importLevels)),
+	}
+	for i := range importLevels {
+		reader.seen[i] = make(map[string]*importRecord)
+	}
+
+	layered := false
+	for _, sheet := range sheets {
+		for i, level := range importLevels {
+			if strings.TrimSpace(sheet.Name) == level.Sheet || strings.EqualFold(strings.TrimSpace(sheet.Name), level.Entity) {
+				layered = true
+				if err := reader.read(sheet, []int{i}, true); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	if !layered {
+		if len(sheets) == 0 {
+			return nil, nil, importFileError("文件中没有数据")
+		}
+		all := make([]int, len(importLevels))
+		for i := range all {
+			all[i] = i
+		}
+		if err := reader.read(sheets[0], all, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	total := 0
+	for _, records := range reader.records {
+		total += len(records)
+	}
+	if total == 0 && len(reader.conflicts) == 0 {
+		return nil, nil, importFileError("文件中没有数据")
+	}
+	return reader.records, reader.conflicts, nil
+}
+
+type importReader struct {
+	records   [][]*importRecord
+	seen      []map[string]*importRecord
+	conflicts []ImportRowResult
+}
+
+// read 读取工作表，首个非空行为表头
+// 分层模板中每行都是该层级的记录，缺少匹配字段的行作为错误行；平铺模板中匹配字段不全的层级视为该行未填写
+func (r *importReader) read(sheet spreadsheet.Sheet, levels []int, layered bool) error {
+	headerRow := -1
+	for i, row := range sheet.Rows {
+		if !blankRow(row) {
+			headerRow = i
+			break
+		}
+	}
+	if headerRow < 0 {
+		if layered {
+			return nil
+		}
+		return importFileError("文件中没有数据")
+	}
+	if len(sheet.Rows)-headerRow-1 > maxImportRows {
+		return importFileError(fmt.Sprintf("单个工作表最多导入%d行", maxImportRows))
+	}
+
+	header := sheet.Rows[headerRow]
+	columns := make([]map[string]int, len(importLevels))
+	matched := false
+	for _, i := range levels {
+		level := importLevels[i]
+		columns[i] = make(map[string]int)
+		for c, title := range header {
+			if key, ok := matchImportColumn(level, title, layered); ok {
+				columns[i][key] = c
+			}
+		}
+
+		var missing []string
+		for _, key := range level.Keys {
+			if _, ok := columns[i][key]; !ok {
+				missing = append(missing, level.column(key).Label)
+			}
+		}
+		switch {
+		case len(missing) == 0:
+			matched = true
+		case layered:
+			return importFileError(fmt.Sprintf("工作表%s缺少列：%s", sheet.Name, strings.Join(missing, "、")))
+		default:
+			columns[i] = nil
+		}
+	}
+	if !matched {
+		return importFileError("未识别到资产编码、建筑编码等匹配列，请使用导入模板")
+	}
+
+	for n, row := range sheet.Rows[headerRow+1:] {
+		if blankRow(row) {
+			continue
+		}
+		rowNumber := headerRow + n + 2
+		for _, i := range levels {
+			if columns[i] == nil {
+				continue
+			}
+			record := &importRecord{level: importLevels[i], sheet: sheet.Name, row: rowNumber, values: make(map[string]string)}
+			for key, c := range columns[i] {
+				if c < len(row) {
+					record.values[key] = strings.TrimSpace(row[c])
+				}
+			}
+
+			var missing []string
+			for _, key := range record.level.Keys {
+				if record.values[key] == "" {
+					missing = append(missing, record.level.column(key).Label)
+				}
+			}
+			if len(missing) > 0 {
+				if layered {
+					result := record.result()
+					result.Action = ImportActionError
+					result.Error = "缺少" + strings.Join(missing, "、")
+					r.conflicts = append(r.conflicts, result)
+				}
+				continue
+			}
+			r.add(i, record)
+		}
+	}
+	return nil
+}
+
+// add 记录已出现时合并非空单元格，同一单元格内容不一致时作为错误行
+func (r *importReader) add(i int, record *importRecord) {
+	key := record.key()
+	existing, ok := r.seen[i][key]
+	if !ok {
+		r.seen[i][key] = record
+		r.records[i] = append(r.records[i], record)
+		return
+	}
+
+	for column, value := range record.values {
+		if value == "" {
+			continue
+		}
+		if current := existing.values[column]; current == "" {
+			existing.values[column] = value
+		} else if current != value {
+			result := record.result()
+			result.Action = ImportActionError
+			result.Error = fmt.Sprintf("%s与第%d行不一致", record.level.column(column).Label, existing.row)
+			r.conflicts = append(r.conflicts, result)
+			return
+		}
+	}
+}
+
+// matchImportColumn 按模板表头、字段名或“实体.字段名”识别列
+// 平铺模板中不同层级存在同名字段（如description），只按表头和“实体.字段名”识别
+func matchImportColumn(level *importLevel, title string, layered bool) (string, bool) {
+	title = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(title), "*"))
+	for _, column := range level.Columns {
+		switch title {
+		case strings.ToLower(column.Label), level.Entity + "." + column.Key:
+			return column.Key, true
+		case column.Key:
+			if layered {
+				return column.Key, true
+			}
+		}
+	}
+	return "", false
+}
+
+func blankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// assetImporter 在导入事务中逐条保存记录
+type assetImporter struct {
+	db      *gorm.DB
+	streets map[string]uint
+}
+
+// importChange 记录保存前后的快照，新建记录before为nil
+type importChange struct {
+	id            uint
+	before, after map[string]interface{}
+}
+
+// apply 在保存点中保存一条记录，失败时只回滚该记录
+func (imp *assetImporter) apply(record *importRecord) ImportRowResult {
+	result := record.result()
+
+	var change *importChange
+	err := imp.db.Transaction(func(tx *gorm.DB) error {
+		svc := &AssetService{db: tx}
+		var err error
+		switch record.level.Entity {
+		case model.EntityAsset:
+			change, err = imp.importAsset(svc, record)
+		case model.EntityBuilding:
+			change, err = imp.importBuilding(svc, record)
+		case model.EntityFloor:
+			change, err = imp.importFloor(svc, record)
+		case model.EntityRoom:
+			change, err = imp.importRoom(svc, record)
+		}
+		return err
+	})
+	if err != nil {
+		result.Action = ImportActionError
+		result.Error = err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result.Error = "记录不存在"
+		}
+		return result
+	}
+
+	result.ID = change.id
+	if change.before == nil {
+		result.Action = ImportActionCreate
+		return result
+	}
+	result.Changes = audit.Diff(change.before, change.after)
+	result.Action = ImportActionUpdate
+	if len(result.Changes) == 0 {
+		result.Action = ImportActionUnchanged
+		result.Changes = nil
+	}
+	return result
+}
+
+// findExisting 按匹配键查找已有记录，记录存在但不在数据权限范围内时返回 ErrOutOfDataScope
+func findExisting(db *gorm.DB, dest interface{}, query string, args ...interface{}) (bool, error) {
+	err := db.Where(query, args...).First(dest).Error
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	var count int64
+	if err := datascope.Skip(db).Model(dest).Where(query, args...).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, ErrOutOfDataScope
+	}
+	return false, nil
+}
+
+// streetID 按组织代码查找街道
+func (imp *assetImporter) streetID(code string) (uint, error) {
+	if id, ok := imp.streets[code]; ok {
+		return id, nil
+	}
+	var org model.Organization
+	if err := imp.db.Select("id").Where("code = ?", code).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("街道编码%s不存在", code)
+		}
+		return 0, err
+	}
+	imp.streets[code] = org.ID
+	return org.ID, nil
+}
+
+// buildingID 按建筑编码查找数据权限范围内的建筑
+func buildingID(db *gorm.DB, code string) (uint, error) {
+	var building model.Building
+	if err := db.Select("id").Where("building_code = ?", code).First(&building).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("建筑%s不存在", code)
+		}
+		return 0, err
+	}
+	return building.ID, nil
+}
+
+func (imp *assetImporter) importAsset(svc *AssetService, record *importRecord) (*importChange, error) {
+	v := record.values
+	asset := &model.Asset{
+		AssetCode:   v["asset_code"],
+		AssetName:   v["asset_name"],
+		Address:     v["address"],
+		LandNature:  v["land_nature"],
+		AssetTags:   record.list("asset_tags"),
+		Description: v["description"],
+	}
+	var err error
+	if asset.Longitude, err = record.float("longitude"); err != nil {
+		return nil, err
+	}
+	if asset.Latitude, err = record.float("latitude"); err != nil {
+		return nil, err
+	}
+	if asset.TotalArea, err = record.float("total_area"); err != nil {
+		return nil, err
+	}
+	if code := v["street_code"]; code != "" {
+		if asset.StreetID, err = imp.streetID(code); err != nil {
+			return nil, err
+		}
+	}
+
+	var existing model.Asset
+	found, err := findExisting(svc.db, &existing, "asset_code = ?", asset.AssetCode)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if err := record.requireAll(); err != nil {
+			return nil, err
+		}
+		created, err := svc.CreateAsset(asset)
+		if err != nil {
+			return nil, err
+		}
+		return &importChange{id: created.ID, after: audit.Snapshot(created)}, nil
+	}
+
+	fields := record.fields(map[string]interface{}{
+		"asset_name":  asset.AssetName,
+		"address":     asset.Address,
+		"longitude":   asset.Longitude,
+		"latitude":    asset.Latitude,
+		"land_nature": asset.LandNature,
+		"total_area":  asset.TotalArea,
+		"asset_tags":  asset.AssetTags,
+		"description": asset.Description,
+	})
+	if asset.StreetID > 0 {
+		fields["street_id"] = asset.StreetID
+	}
+	before := audit.Snapshot(&existing)
+	updated, err := svc.updateAsset(existing.ID, asset, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &importChange{id: updated.ID, before: before, after: audit.Snapshot(updated)}, nil
+}
+
+func (imp *assetImporter) importBuilding(svc *AssetService, record *importRecord) (*importChange, error) {
+	v := record.values
+	building := &model.Building{
+		BuildingCode:     v["building_code"],
+		BuildingName:     v["building_name"],
+		BuildingType:     v["building_type"],
+		ConstructionYear: v["construction_year"],
+		PropertyCompany:  v["property_company"],
+		PropertyPhone:    v["property_phone"],
+		Features:         record.list("features"),
+		Description:      v["description"],
+	}
+	var err error
+	if building.TotalFloors, err = record.integer("total_floors"); err != nil {
+		return nil, err
+	}
+	if building.UndergroundFloors, err = record.integer("underground_floors"); err != nil {
+		return nil, err
+	}
+	if building.TotalArea, err = record.float("total_area"); err != nil {
+		return nil, err
+	}
+	if building.ElevatorCount, err = record.integer("elevator_count"); err != nil {
+		return nil, err
+	}
+	if building.ParkingSpaces, err = record.integer("parking_spaces"); err != nil {
+		return nil, err
+	}
+	if building.GreenRate, err = record.float("green_rate"); err != nil {
+		return nil, err
+	}
+	if code := v["asset_code"]; code != "" {
+		var asset model.Asset
+		if err := svc.db.Select("id").Where("asset_code = ?", code).First(&asset).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("资产%s不存在", code)
+			}
+			return nil, err
+		}
+		building.AssetID = asset.ID
+	}
+
+	var existing model.Building
+	found, err := findExisting(svc.db, &existing, "building_code = ?", building.BuildingCode)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if err := record.requireAll(); err != nil {
+			return nil, err
+		}
+		created, err := svc.CreateBuilding(building)
+		if err != nil {
+			return nil, err
+		}
+		return &importChange{id: created.ID, after: audit.Snapshot(created)}, nil
+	}
+
+	fields := record.fields(map[string]interface{}{
+		"building_name":      building.BuildingName,
+		"building_type":      building.BuildingType,
+		"total_floors":       building.TotalFloors,
+		"underground_floors": building.UndergroundFloors,
+		"total_area":         building.TotalArea,
+		"construction_year":  building.ConstructionYear,
+		"elevator_count":     building.ElevatorCount,
+		"parking_spaces":     building.ParkingSpaces,
+		"green_rate":         building.GreenRate,
+		"property_company":   building.PropertyCompany,
+		"property_phone":     building.PropertyPhone,
+		"features":           building.Features,
+		"description":        building.Description,
+	})
+	if building.AssetID > 0 {
+		fields["asset_id"] = building.AssetID
+	}
+	before := audit.Snapshot(&existing)
+	updated, err := svc.updateBuilding(existing.ID, building, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &importChange{id: updated.ID, before: before, after: audit.Snapshot(updated)}, nil
+}
+
+func (imp *assetImporter) importFloor(svc *AssetService, record *importRecord) (*importChange, error) {
+	v := record.values
+	floor := &model.Floor{
+		FloorName:   v["floor_name"],
+		Description: v["description"],
+	}
+	var err error
+	if floor.FloorNumber, err = record.integer("floor_number"); err != nil {
+		return nil, err
+	}
+	if floor.FloorArea, err = record.float("floor_area"); err != nil {
+		return nil, err
+	}
+	if floor.BuildingID, err = buildingID(svc.db, v["building_code"]); err != nil {
+		return nil, err
+	}
+
+	var existing model.Floor
+	found, err := findExisting(svc.db, &existing, "building_id = ? AND floor_number = ?", floor.BuildingID, floor.FloorNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if err := record.requireAll(); err != nil {
+			return nil, err
+		}
+		created, err := svc.CreateFloor(floor)
+		if err != nil {
+			return nil, err
+		}
+		return &importChange{id: created.ID, after: audit.Snapshot(created)}, nil
+	}
+
+	fields := record.fields(map[string]interface{}{
+		"floor_name":  floor.FloorName,
+		"floor_area":  floor.FloorArea,
+		"description": floor.Description,
+	})
+	before := audit.Snapshot(&existing)
+	updated, err := svc.updateFloor(existing.ID, floor, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &importChange{id: updated.ID, before: before, after: audit.Snapshot(updated)}, nil
+}
+
+func (imp *assetImporter) importRoom(svc *AssetService, record *importRecord) (*importChange, error) {
+	v := record.values
+	room := &model.Room{
+		RoomNumber:  v["room_number"],
+		RoomType:    v["room_type"],
+		Decoration:  v["decoration"],
+		Orientation: v["orientation"],
+		Status:      v["status"],
+		Description: v["description"],
+	}
+	var err error
+	if room.RoomArea, err = record.float("room_area"); err != nil {
+		return nil, err
+	}
+	if room.RentPrice, err = record.float("rent_price"); err != nil {
+		return nil, err
+	}
+	if room.HasWindow, err = record.boolean("has_window"); err != nil {
+		return nil, err
+	}
+	if room.HasAC, err = record.boolean("has_ac"); err != nil {
+		return nil, err
+	}
+
+	floorNumber, err := record.integer("floor_number")
+	if err != nil {
+		return nil, err
+	}
+	building, err := buildingID(svc.db, v["building_code"])
+	if err != nil {
+		return nil, err
+	}
+	var floor model.Floor
+	if err := svc.db.Select("id").Where("building_id = ? AND floor_number = ?", building, floorNumber).First(&floor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("建筑%s的%d层不存在", v["building_code"], floorNumber)
+		}
+		return nil, err
+	}
+	room.FloorID = floor.ID
+
+	var existing model.Room
+	found, err := findExisting(svc.db, &existing, "floor_id = ? AND room_number = ?", room.FloorID, room.RoomNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if err := record.requireAll(); err != nil {
+			return nil, err
+		}
+		created, err := svc.CreateRoom(room)
+		if err != nil {
+			return nil, err
+		}
+		return &importChange{id: created.ID, after: audit.Snapshot(created)}, nil
+	}
+
+	fields := record.fields(map[string]interface{}{
+		"room_type":   room.RoomType,
+		"room_area":   room.RoomArea,
+		"rent_price":  room.RentPrice,
+		"decoration":  room.Decoration,
+		"orientation": room.Orientation,
+		"has_window":  room.HasWindow,
+		"has_ac":      room.HasAC,
+		"status":      room.Status,
+		"description": room.Description,
+	})
+	before := audit.Snapshot(&existing)
+	updated, err := svc.updateRoom(existing.ID, room, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &importChange{id: updated.ID, before: before, after: audit.Snapshot(updated)}, nil
+}
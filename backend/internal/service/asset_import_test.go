@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"building-asset-backend/pkg/spreadsheet"
+)
+
+func TestImportRecordFieldsKeepsZeroValues(t *testing.T) {
+	records, conflicts, err := readImportRecords([]spreadsheet.Sheet{{
+		Name: "房间",
+		Rows: [][]string{
+			{"建筑编码", "楼层号", "房间号", "房间面积", "是否有窗", "是否有空调"},
+			{"B001", "1", "101", "", "否", " "},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) > 0 {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+	rooms := records[3]
+	if len(rooms) != 1 {
+		t.Fatalf("got %d room records, want 1", len(rooms))
+	}
+
+	got := rooms[0].fields(map[string]interface{}{
+		"room_area":   0.0,
+		"has_window":  false,
+		"has_ac":      false,
+		"description": "",
+	})
+	want := map[string]interface{}{"has_window": false}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestReadImportRecordsFlatSheet(t *testing.T) {
+	records, conflicts, err := readImportRecords([]spreadsheet.Sheet{{
+		Name: "Sheet1",
+		Rows: [][]string{
+			{},
+			{"资产编码*", "资产名称", "建筑编码", "建筑名称", "楼层号", "房间号", "房间面积"},
+			{"A001", "一号园区", "B001", "一号楼", "1", "101", "50"},
+			{"A001", "", "B001", "", "1", "102", "60"},
+			{"", "", "", "", "", "", ""},
+			{"A001", "一号园区", "B002", "二号楼", "", "", ""},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) > 0 {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+
+	counts := make([]int, len(records))
+	for i := range records {
+		counts[i] = len(records[i])
+	}
+	// 资产合并为1条，建筑2条，楼层1条，房间2条；缺少楼层号的行不产生楼层和房间
+	if want := []int{1, 2, 1, 2}; !reflect.DeepEqual(counts, want) {
+		t.Fatalf("record counts = %v, want %v", counts, want)
+	}
+	asset := records[0][0]
+	if asset.row != 3 || asset.values["asset_name"] != "一号园区" {
+		t.Errorf("asset = row %d %v", asset.row, asset.values)
+	}
+	if key := records[3][1].key(); key != "B001/1/102" {
+		t.Errorf("room key = %q, want B001/1/102", key)
+	}
+}
+
+func TestReadImportRecordsLayeredSheets(t *testing.T) {
+	records, conflicts, err := readImportRecords([]spreadsheet.Sheet{
+		{Name: "说明", Rows: [][]string{{"填写说明"}}},
+		{Name: "资产", Rows: [][]string{
+			{"asset_code", "asset_name", "description"},
+			{"A001", "一号园区", "园区"},
+		}},
+		{Name: "building", Rows: [][]string{
+			{"资产编码", "建筑编码", "建筑名称", "description"},
+			{"A001", "B001", "一号楼", "主楼"},
+			{"A001", "", "缺编码", ""},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records[0]) != 1 || len(records[1]) != 1 || len(records[2]) != 0 || len(records[3]) != 0 {
+		t.Fatalf("unexpected record counts: %d %d %d %d", len(records[0]), len(records[1]), len(records[2]), len(records[3]))
+	}
+	// 分层模板中字段名也可作为表头
+	if got := records[1][0].values["description"]; got != "主楼" {
+		t.Errorf("building description = %q, want 主楼", got)
+	}
+	if len(conflicts) != 1 || conflicts[0].Row != 3 || conflicts[0].Action != ImportActionError {
+		t.Fatalf("conflicts = %+v, want row 3 missing building code", conflicts)
+	}
+}
+
+func TestReadImportRecordsConflictingRows(t *testing.T) {
+	records, conflicts, err := readImportRecords([]spreadsheet.Sheet{{
+		Name: "资产",
+		Rows: [][]string{
+			{"资产编码", "资产名称", "详细地址"},
+			{"A001", "一号园区", ""},
+			{"A001", "", "长安街1号"},
+			{"A001", "二号园区", ""},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records[0]) != 1 {
+		t.Fatalf("got %d asset records, want 1", len(records[0]))
+	}
+	if got := records[0][0].values["address"]; got != "长安街1号" {
+		t.Errorf("merged address = %q", got)
+	}
+	if len(conflicts) != 1 || conflicts[0].Row != 4 {
+		t.Fatalf("conflicts = %+v, want row 4", conflicts)
+	}
+}
+
+func TestReadImportRecordsRejectsUnknownLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		sheets []spreadsheet.Sheet
+	}{
+		{"no sheets", nil},
+		{"no key columns", []spreadsheet.Sheet{{Name: "Sheet1", Rows: [][]string{{"名称"}, {"x"}}}}},
+		{"header only", []spreadsheet.Sheet{{Name: "Sheet1", Rows: [][]string{{"资产编码"}}}}},
+		{"layered sheet missing key", []spreadsheet.Sheet{{Name: "楼层", Rows: [][]string{{"建筑编码"}, {"B001"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readImportRecords(tt.sheets)
+			if !errors.Is(err, ErrInvalidImportFile) {
+				t.Errorf("error = %v, want ErrInvalidImportFile", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"math"
+
+	"building-asset-backend/internal/model"
+)
+
+// 聚合的缩放级别范围，与Web地图瓦片级别一致
+const (
+	MinMapZoom = 0
+	MaxMapZoom = 22
+)
+
+// mapClusterCells 每个256像素瓦片在经纬度方向上划分的网格数，即约64像素内的资产聚合为一簇
+const mapClusterCells = 4
+
+// MapQuery 地图要素查询条件
+type MapQuery struct {
+	BBox *[4]float64 // 矩形范围：最小经度、最小纬度、最大经度、最大纬度，为空时不限范围
+	Zoom *int        // 缩放级别，不为空时按该级别的网格聚合资产
+}
+
+// MapFeature 地图要素：单个资产或按网格聚合的资产簇
+type MapFeature struct {
+	Longitude     float64        // 经度，资产簇为簇内资产的平均位置
+	Latitude      float64        // 纬度
+	Asset         *model.Asset   // 单个资产，资产簇为nil
+	Count         int            // 资产数
+	BuildingCount int64          // 建筑数
+	TotalArea     float64        // 资产总面积(平方米)
+	Statistics    StatisticsItem // 出租指标
+	BBox          [4]float64     // 簇内资产的范围
+}
+
+// GetMapFeatures 获取地图上展示的资产，经纬度均为0的资产视为未定位，不返回
+// 指定缩放级别时按网格聚合，网格内只有一个资产时仍返回该资产
+func (s *AssetService) GetMapFeatures(q MapQuery) ([]*MapFeature, error) {
+	query := s.db.Preload("Street").Where("NOT (longitude = 0 AND latitude = 0)")
+	if q.BBox != nil {
+		box := *q.BBox
+		if err := validateBBox(box[0], box[1], box[2], box[3]); err != nil {
+			return nil, err
+		}
+		query = query.Where("MBRCovers(ST_GeomFromText(?), location)", envelopeWKT(box[0], box[1], box[2], box[3]))
+	}
+	if q.Zoom != nil && (*q.Zoom < MinMapZoom || *q.Zoom > MaxMapZoom) {
+		return nil, geoQueryError{"缩放级别须在0到22之间"}
+	}
+
+	var assets []*model.Asset
+	if err := query.Order("id").Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	if len(assets) == 0 {
+		return []*MapFeature{}, nil
+	}
+
+	buildings, rooms, err := s.assetAggregates(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未指定缩放级别时每个资产单独成为一个要素
+	cell := 0.0
+	if q.Zoom != nil {
+		cell = 360 / math.Exp2(float64(*q.Zoom)) / mapClusterCells
+	}
+
+	type cluster struct {
+		assets []*model.Asset
+		rooms  roomAggregate
+	}
+	var clusters []*cluster
+	cells := make(map[[2]int64]*cluster)
+	for _, asset := range assets {
+		var c *cluster
+		if cell > 0 {
+			key := [2]int64{int64(math.Floor((asset.Longitude + 180) / cell)), int64(math.Floor((asset.Latitude + 90) / cell))}
+			if c = cells[key]; c == nil {
+				c = &cluster{}
+				cells[key] = c
+				clusters = append(clusters, c)
+			}
+		} else {
+			c = &cluster{}
+			clusters = append(clusters, c)
+		}
+		c.assets = append(c.assets, asset)
+		c.rooms.add(rooms[asset.ID])
+	}
+
+	features := make([]*MapFeature, 0, len(clusters))
+	for _, c := range clusters {
+		first := c.assets[0]
+		feature := &MapFeature{
+			Count:      len(c.assets),
+			Statistics: c.rooms.item(),
+			BBox:       [4]float64{first.Longitude, first.Latitude, first.Longitude, first.Latitude},
+		}
+		for _, asset := range c.assets {
+			feature.Longitude += asset.Longitude
+			feature.Latitude += asset.Latitude
+			feature.BuildingCount += buildings[asset.ID]
+			feature.TotalArea += asset.TotalArea
+			feature.BBox[0] = math.Min(feature.BBox[0], asset.Longitude)
+			feature.BBox[1] = math.Min(feature.BBox[1], asset.Latitude)
+			feature.BBox[2] = math.Max(feature.BBox[2], asset.Longitude)
+			feature.BBox[3] = math.Max(feature.BBox[3], asset.Latitude)
+		}
+		feature.Longitude /= float64(feature.Count)
+		feature.Latitude /= float64(feature.Count)
+		feature.TotalArea = round2(feature.TotalArea)
+		if feature.Count == 1 {
+			feature.Asset = first
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
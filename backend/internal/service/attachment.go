@@ -0,0 +1,297 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/logger"
+	"building-asset-backend/pkg/storage"
+
+	"github.com/gabriel-vasile/mimetype"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidAttachment 附件无效（为空、过大、类型不允许或分类无效）
+var ErrInvalidAttachment = errors.New("附件无效")
+
+// attachmentError 附件校验错误，匹配 ErrInvalidAttachment 并携带原因
+type attachmentError struct {
+	reason string
+}
+
+func (e attachmentError) Error() string {
+	return e.reason
+}
+
+func (e attachmentError) Is(target error) bool {
+	return target == ErrInvalidAttachment
+}
+
+var attachmentCategories = map[string]bool{
+	model.AttachmentCategoryPhoto:     true,
+	model.AttachmentCategoryFloorPlan: true,
+	model.AttachmentCategoryTitleDeed: true,
+	model.AttachmentCategoryOther:     true,
+}
+
+// attachmentKey 按内容哈希生成存储路径
+func attachmentKey(sum string) string {
+	return "attachments/" + sum[:2] + "/" + sum
+}
+
+// lockAttachmentBlob 在事务中锁定内容哈希对应的文件行，不存在时创建
+// 同一文件的写入与删除在各实例之间按该行串行执行，避免删除最后一个引用时与相同内容的上传交错
+func lockAttachmentBlob(tx *gorm.DB, sum string) error {
+	// 主键冲突时的更新对已有行加排他锁，插入和加锁在一条语句内完成
+	return tx.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"sha256"})}).
+		Create(&model.AttachmentBlob{SHA256: sum}).Error
+}
+
+// GetAttachments 获取实体的附件列表，实体不在数据权限范围内时视为不存在
+func (s *AssetService) GetAttachments(ownerType string, ownerID uint) ([]*model.Attachment, error) {
+	if err := s.checkEntity(ownerType, ownerID); err != nil {
+		return nil, err
+	}
+	var attachments []*model.Attachment
+	err := s.db.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Order("id DESC").Find(&attachments).Error
+	return attachments, err
+}
+
+// UploadAttachment 上传附件，按文件内容识别类型并校验大小和允许的类型
+// 内容与已有附件相同时不重复存储文件
+func (s *AssetService) UploadAttachment(ownerType string, ownerID uint, category, description, fileName string, r io.Reader) (*model.Attachment, error) {
+	if err := s.checkEntity(ownerType, ownerID); err != nil {
+		return nil, err
+	}
+	if category == "" {
+		category = model.AttachmentCategoryOther
+	}
+	if !attachmentCategories[category] {
+		return nil, attachmentError{"无效的附件分类"}
+	}
+
+	// 先写入临时文件，计算哈希并识别类型后再存储
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	cfg := config.Get().Upload
+	src := r
+	if cfg.MaxSize > 0 {
+		src = io.LimitReader(r, cfg.MaxSize+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, attachmentError{"文件为空"}
+	}
+	if cfg.MaxSize > 0 && size > cfg.MaxSize {
+		return nil, attachmentError{fmt.Sprintf("文件大小超过限制%dMB", cfg.MaxSize>>20)}
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	mtype, err := mimetype.DetectReader(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if !allowedAttachmentType(mtype, cfg.AllowedTypes) {
+		return nil, attachmentError{"不允许上传该类型的文件: " + mtype.String()}
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	attachment := &model.Attachment{
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		Category:    category,
+		FileName:    truncateRunes(filepath.Base(fileName), 255),
+		ContentType: mtype.String(),
+		Size:        size,
+		SHA256:      sum,
+		Description: truncateRunes(description, 500),
+	}
+
+	ctx := s.db.Statement.Context
+	key := attachmentKey(sum)
+	put := func() error {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return storage.Get().Put(ctx, key, tmp, size, attachment.ContentType)
+	}
+
+	// 文件在事务外写入，上传期间不持有文件行锁；按内容哈希存储，与并发的相同内容上传重复写入也不影响结果
+	exists, err := storage.Get().Exists(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := put(); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAttachmentBlob(tx, sum); err != nil {
+			return err
+		}
+		// 写入后、加锁前，删除最后一个引用的操作可能已删除文件，此时在锁内重新写入
+		exists, err := storage.Get().Exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err := put(); err != nil {
+				return err
+			}
+		}
+		return tx.Create(attachment).Error
+	})
+	if err != nil {
+		// 保存失败时本次写入的文件可能没有任何附件引用
+		if releaseErr := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := lockAttachmentBlob(tx, sum); err != nil {
+				return err
+			}
+			return releaseAttachmentBlob(tx, sum)
+		}); releaseErr != nil {
+			logger.Warnf("清理附件文件失败 %s: %v", sum, releaseErr)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// OpenAttachment 读取实体的附件内容，调用方负责关闭返回的文件
+func (s *AssetService) OpenAttachment(ownerType string, ownerID, id uint) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := s.attachment(ownerType, ownerID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := storage.Get().Open(s.db.Statement.Context, attachmentKey(attachment.SHA256))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, file, nil
+}
+
+// DeleteAttachment 删除附件，没有其他附件引用相同内容时同时删除文件
+func (s *AssetService) DeleteAttachment(ownerType string, ownerID, id uint) error {
+	attachment, err := s.attachment(ownerType, ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAttachmentBlob(tx, attachment.SHA256); err != nil {
+			return err
+		}
+		if err := tx.Delete(attachment).Error; err != nil {
+			return err
+		}
+		return releaseAttachmentBlob(tx, attachment.SHA256)
+	})
+}
+
+// deleteOwnerAttachments 在删除实体的事务中删除其全部附件，并删除不再被引用的文件
+func deleteOwnerAttachments(tx *gorm.DB, ownerType string, ownerID uint) error {
+	var sums []string
+	err := tx.Model(&model.Attachment{}).Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Distinct("sha256").Order("sha256").Pluck("sha256", &sums).Error
+	if err != nil {
+		return err
+	}
+	if len(sums) == 0 {
+		return nil
+	}
+
+	// 按哈希顺序锁定文件行，避免与其他删除相互等待
+	for _, sum := range sums {
+		if err := lockAttachmentBlob(tx, sum); err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Delete(&model.Attachment{}).Error; err != nil {
+		return err
+	}
+	for _, sum := range sums {
+		if err := releaseAttachmentBlob(tx, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseAttachmentBlob 没有附件引用该内容时删除文件行和文件，调用方须已锁定文件行
+func releaseAttachmentBlob(tx *gorm.DB, sum string) error {
+	var count int64
+	if err := tx.Model(&model.Attachment{}).Where("sha256 = ?", sum).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := tx.Delete(&model.AttachmentBlob{SHA256: sum}).Error; err != nil {
+		return err
+	}
+	// 文件删除失败只留下无引用的文件，不影响结果
+	if err := storage.Get().Delete(tx.Statement.Context, attachmentKey(sum)); err != nil {
+		logger.Warnf("删除附件文件失败 %s: %v", sum, err)
+	}
+	return nil
+}
+
+// attachment 获取实体下的附件，实体不在数据权限范围内时视为不存在
+func (s *AssetService) attachment(ownerType string, ownerID, id uint) (*model.Attachment, error) {
+	if err := s.checkEntity(ownerType, ownerID); err != nil {
+		return nil, err
+	}
+	var attachment model.Attachment
+	err := s.db.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).First(&attachment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// allowedAttachmentType 判断识别出的类型是否在允许列表中，列表为空时不限制
+func allowedAttachmentType(mtype *mimetype.MIME, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, t := range allowed {
+		if mtype.Is(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
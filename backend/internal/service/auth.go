@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/auth"
+	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// TokenPair 访问token与刷新token
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// passwordChangeTokenTTL 强制修改密码临时凭证有效期
+const passwordChangeTokenTTL = 10 * time.Minute
+
+type AuthService struct {
+	db          *gorm.DB
+	userService *UserService
+	logService  *LogService
+}
+
+func NewAuthService() *AuthService {
+	return &AuthService{
+		db:          database.GetDB(),
+		userService: NewUserService(),
+		logService:  NewLogService(),
+	}
+}
+
+// IssueTokens 为用户开启新会话并签发token对
+func (s *AuthService) IssueTokens(user *model.User) (*TokenPair, error) {
+	familyID, refreshToken, err := auth.NewRefreshFamily(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Name, roleNames(user.Roles), familyID)
+	if err != nil {
+		auth.RevokeRefreshFamily(familyID)
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    config.Get().JWT.Expire,
+	}, nil
+}
+
+// RefreshTokens 使用刷新token换取新的token对，角色信息从数据库重新加载
+func (s *AuthService) RefreshTokens(refreshToken string) (*TokenPair, *model.User, error) {
+	userID, familyID, newRefreshToken, err := auth.RotateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var user model.User
+	if err := s.db.Preload("Roles", "status = ?", "active").First(&user, userID).Error; err != nil {
+		auth.RevokeRefreshFamily(familyID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, auth.ErrRefreshTokenInvalid
+		}
+		return nil, nil, err
+	}
+
+	if !user.IsActive() {
+		auth.RevokeRefreshFamily(familyID)
+		return nil, nil, errors.New("用户已被禁用")
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Name, roleNames(user.Roles), familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user.Password = ""
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    config.Get().JWT.Expire,
+	}, &user, nil
+}
+
+// IssuePasswordChangeToken 为必须修改密码的用户签发临时凭证，仅可用于修改密码
+func (s *AuthService) IssuePasswordChangeToken(user *model.User) (string, error) {
+	return auth.GenerateChallengeToken(user.ID, auth.TokenTypePasswordChange, passwordChangeTokenTTL)
+}
+
+// ChangeExpiredPassword 使用临时凭证修改密码，成功后继续完成登录（可能需要两步验证）
+func (s *AuthService) ChangeExpiredPassword(changeToken, oldPassword, newPassword string) (*LoginResult, *model.User, error) {
+	claims, err := auth.ParseChallengeToken(changeToken, auth.TokenTypePasswordChange)
+	if err != nil {
+		return nil, nil, errors.New("修改密码凭证无效或已过期")
+	}
+
+	if err := s.userService.ChangePassword(claims.UserID, oldPassword, newPassword); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userService.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := s.CompleteLogin(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, user, nil
+}
+
+// Logout 吊销当前访问token及其所属会话的刷新token
+func (s *AuthService) Logout(claims *auth.Claims) error {
+	if err := auth.RevokeToken(claims); err != nil {
+		return err
+	}
+
+	if err := auth.RevokeRefreshFamily(claims.SessionID); err != nil {
+		logger.Warnf("吊销刷新token失败: %v", err)
+	}
+	return nil
+}
+
+// roleNames 获取角色名称列表
+func roleNames(roles []model.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"building-asset-backend/internal/audit"
+	"building-asset-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// updateWithHistory 在同一事务中更新实体、重新加载并记录字段差异
+// after 不为nil时在重新加载前于同一事务中执行，用于维护关联数据
+func updateWithHistory(db *gorm.DB, entityType string, id uint, entity, updates interface{}, after func(tx *gorm.DB) error) error {
+	before := audit.Snapshot(entity)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(entity).Updates(updates).Error; err != nil {
+			return err
+		}
+		if after != nil {
+			if err := after(tx); err != nil {
+				return err
+			}
+		}
+		if err := tx.First(entity, id).Error; err != nil {
+			return err
+		}
+		return audit.RecordUpdate(tx, entityType, id, before, audit.Snapshot(entity))
+	})
+}
+
+// deleteWithHistory 在同一事务中删除实体并记录删除前的数据
+// after 不为nil时在删除后于同一事务中执行，用于维护关联数据
+func deleteWithHistory(db *gorm.DB, entityType string, id uint, entity interface{}, after func(tx *gorm.DB) error) error {
+	before := audit.Snapshot(entity)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(entity, id).Error; err != nil {
+			return err
+		}
+		if after != nil {
+			if err := after(tx); err != nil {
+				return err
+			}
+		}
+		return audit.RecordDelete(tx, entityType, id, before)
+	})
+}
+
+// GetAssetHistory 获取资产、建筑、楼层或房间的变更记录，实体不在数据权限范围内时视为不存在
+func (s *AssetService) GetAssetHistory(entityType string, id uint, page, pageSize int) ([]*model.ChangeLog, int64, error) {
+	if err := s.checkEntity(entityType, id); err != nil {
+		return nil, 0, err
+	}
+	return audit.History(s.db, entityType, id, page, pageSize)
+}
+
+// checkEntity 确认资产、建筑、楼层或房间存在且在数据权限范围内，否则返回 gorm.ErrRecordNotFound
+func (s *AssetService) checkEntity(entityType string, id uint) error {
+	var entity interface{}
+	switch entityType {
+	case model.EntityAsset:
+		entity = &model.Asset{}
+	case model.EntityBuilding:
+		entity = &model.Building{}
+	case model.EntityFloor:
+		entity = &model.Floor{}
+	case model.EntityRoom:
+		entity = &model.Room{}
+	default:
+		return gorm.ErrRecordNotFound
+	}
+	return s.db.Select("id").First(entity, id).Error
+}
+
+// GetUserHistory 获取用户的变更记录，用户不在数据权限范围内时视为不存在
+func (s *UserService) GetUserHistory(id uint, page, pageSize int) ([]*model.ChangeLog, int64, error) {
+	if err := s.db.Select("id").First(&model.User{}, id).Error; err != nil {
+		return nil, 0, err
+	}
+	return audit.History(s.db, model.EntityUser, id, page, pageSize)
+}
+
+// userSnapshot 生成用户快照，角色以角色编码列表记录
+func userSnapshot(user *model.User) map[string]interface{} {
+	snapshot := audit.Snapshot(user)
+	if snapshot == nil {
+		snapshot = make(map[string]interface{})
+	}
+	codes := make([]interface{}, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		codes = append(codes, role.Code)
+	}
+	snapshot["roles"] = codes
+	return snapshot
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize 导出时每批读取的行数
+const exportBatchSize = 500
+
+// ErrExportTooLarge 导出行数超过配置的上限
+var ErrExportTooLarge = errors.New("导出数据过多")
+
+// exportLimitError 导出行数超限错误，匹配 ErrExportTooLarge 并携带行数
+type exportLimitError struct {
+	total, max int64
+}
+
+func (e exportLimitError) Error() string {
+	return fmt.Sprintf("符合条件的数据共%d行，超过单次导出上限%d行，请缩小筛选范围", e.total, e.max)
+}
+
+func (e exportLimitError) Is(target error) bool {
+	return target == ErrExportTooLarge
+}
+
+// exportRows 统计查询结果并按主键分批读取，每批读取并加载preloads关联后调用fn
+// maxRows大于0且结果超过该行数时不读取数据，返回 ErrExportTooLarge
+func exportRows[T any](query *gorm.DB, maxRows int, fn func([]*T) error, preloads ...string) error {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return err
+	}
+	if maxRows > 0 && total > int64(maxRows) {
+		return exportLimitError{total: total, max: int64(maxRows)}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	var batch []*T
+	return query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
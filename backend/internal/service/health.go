@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"building-asset-backend/migrations"
+	"building-asset-backend/pkg/cache"
+	"building-asset-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// healthCheckTimeout 单项检查的超时时间，需小于编排系统探针的超时
+const healthCheckTimeout = 2 * time.Second
+
+// 健康状态
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport 就绪检查结果，任一检查失败时整体为 unavailable
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// OK 是否全部检查通过
+func (r *HealthReport) OK() bool {
+	return r.Status == HealthStatusOK
+}
+
+type HealthService struct {
+	db *gorm.DB
+}
+
+func NewHealthService() *HealthService {
+	return &HealthService{
+		db: database.GetDB(),
+	}
+}
+
+// Ready 并发检查数据库、Redis连接和未执行的迁移
+func (s *HealthService) Ready(ctx context.Context) *HealthReport {
+	checks := map[string]func(ctx context.Context) error{
+		"database":   s.pingDatabase,
+		"redis":      pingRedis,
+		"migrations": s.checkMigrations,
+	}
+
+	report := &HealthReport{Status: HealthStatusOK, Checks: make(map[string]HealthCheck, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			result := runHealthCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != HealthStatusOK {
+				report.Status = HealthStatusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// runHealthCheck 在超时内执行一项检查并记录耗时
+func runHealthCheck(ctx context.Context, check func(ctx context.Context) error) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := HealthCheck{
+		Status:    HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (s *HealthService) pingDatabase(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func pingRedis(ctx context.Context) error {
+	return cache.GetClient().Ping(ctx).Err()
+}
+
+// checkMigrations 存在未执行或被修改的迁移时失败，避免新版本在结构变更前接收流量
+func (s *HealthService) checkMigrations(ctx context.Context) error {
+	migrator, err := migrations.New(s.db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migration(s), first %s_%s", len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
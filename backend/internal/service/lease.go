@@ -0,0 +1,442 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLeaseRoomConflict 房间在合同期内已被其他合同占用
+	ErrLeaseRoomConflict = errors.New("所选房间在租期内已有生效的租赁合同")
+	// ErrLeaseRoomNotFound 房间不存在或不在数据权限范围内
+	ErrLeaseRoomNotFound = errors.New("房间不存在")
+	// ErrInvalidLease 合同内容校验失败
+	ErrInvalidLease = errors.New("合同信息无效")
+	// ErrTenantInUse 租户存在租赁合同
+	ErrTenantInUse = errors.New("该租户存在租赁合同，无法删除")
+)
+
+// leaseError 合同校验错误，匹配 ErrInvalidLease 并携带具体原因
+type leaseError string
+
+func (e leaseError) Error() string {
+	return string(e)
+}
+
+func (e leaseError) Is(target error) bool {
+	return target == ErrInvalidLease
+}
+
+// dateLayout 合同日期格式
+const dateLayout = "2006-01-02"
+
+type LeaseService struct {
+	db *gorm.DB
+}
+
+func NewLeaseService() *LeaseService {
+	return &LeaseService{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务，查询时自动应用数据权限
+func (s *LeaseService) WithContext(ctx context.Context) *LeaseService {
+	return &LeaseService{db: s.db.WithContext(ctx)}
+}
+
+// WithTx 返回在指定事务中执行的服务，用于将多个操作放在同一事务中
+func (s *LeaseService) WithTx(tx *gorm.DB) *LeaseService {
+	return &LeaseService{db: tx}
+}
+
+// Tenant operations
+
+func (s *LeaseService) GetTenants(page, pageSize int, name, status string) ([]*model.Tenant, int64, error) {
+	var tenants []*model.Tenant
+	var total int64
+
+	query := s.db.Model(&model.Tenant{})
+	if name != "" {
+		query = query.Where("name LIKE ?", "%"+name+"%")
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&tenants).Error; err != nil {
+		return nil, 0, err
+	}
+	return tenants, total, nil
+}
+
+func (s *LeaseService) GetTenantByID(id uint) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := s.db.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (s *LeaseService) CreateTenant(tenant *model.Tenant) (*model.Tenant, error) {
+	if err := s.db.Create(tenant).Error; err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (s *LeaseService) UpdateTenant(id uint, updates *model.Tenant) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := s.db.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&tenant).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (s *LeaseService) DeleteTenant(id uint) error {
+	if err := s.db.Select("id").First(&model.Tenant{}, id).Error; err != nil {
+		return err
+	}
+
+	// 检查是否有关联的合同
+	var count int64
+	datascope.Skip(s.db).Model(&model.Lease{}).Where("tenant_id = ?", id).Count(&count)
+	if count > 0 {
+		return ErrTenantInUse
+	}
+
+	return s.db.Delete(&model.Tenant{}, id).Error
+}
+
+// Lease operations
+
+func (s *LeaseService) GetLeases(page, pageSize int, leaseNo string, tenantID, roomID uint, status string) ([]*model.Lease, int64, error) {
+	var leases []*model.Lease
+	var total int64
+
+	query := s.db.Model(&model.Lease{})
+	if leaseNo != "" {
+		query = query.Where("lease_no LIKE ?", "%"+leaseNo+"%")
+	}
+	if tenantID > 0 {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	if roomID > 0 {
+		query = query.Where("id IN (SELECT lease_id FROM t_lease_rooms WHERE room_id = ?)", roomID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Tenant").Preload("Rooms").Order("start_date DESC, id DESC").
+		Offset(offset).Limit(pageSize).Find(&leases).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return leases, total, nil
+}
+
+func (s *LeaseService) GetLeaseByID(id uint) (*model.Lease, error) {
+	var lease model.Lease
+	if err := s.db.Preload("Tenant").Preload("Rooms.Floor.Building").First(&lease, id).Error; err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// CreateLease 创建合同并关联房间，同时刷新房间出租状态
+func (s *LeaseService) CreateLease(lease *model.Lease, roomIDs []uint) (*model.Lease, error) {
+	if lease.PaymentCycle == "" {
+		lease.PaymentCycle = "monthly"
+	}
+	lease.Status = model.LeaseStatusActive
+
+	// 检查合同编号是否重复
+	var count int64
+	datascope.Skip(s.db).Model(&model.Lease{}).Where("lease_no = ?", lease.LeaseNo).Count(&count)
+	if count > 0 {
+		return nil, leaseError("合同编号已存在")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		rooms, err := validateLease(tx, lease, roomIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Tenant", "Rooms").Create(lease).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(lease).Association("Rooms").Replace(rooms); err != nil {
+			return err
+		}
+		return syncRoomStatus(tx, roomIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLeaseByID(lease.ID)
+}
+
+// UpdateLease 修改合同，roomIDs为nil时保持原有房间不变
+func (s *LeaseService) UpdateLease(id uint, updates *model.Lease, roomIDs []uint) (*model.Lease, error) {
+	var lease model.Lease
+	if err := s.db.Preload("Rooms").First(&lease, id).Error; err != nil {
+		return nil, err
+	}
+
+	// 检查合同编号是否重复
+	if updates.LeaseNo != "" && updates.LeaseNo != lease.LeaseNo {
+		var count int64
+		datascope.Skip(s.db).Model(&model.Lease{}).Where("lease_no = ? AND id != ?", updates.LeaseNo, id).Count(&count)
+		if count > 0 {
+			return nil, leaseError("合同编号已存在")
+		}
+	}
+
+	// 以修改后的合同内容校验
+	merged := lease
+	if updates.TenantID > 0 {
+		merged.TenantID = updates.TenantID
+	}
+	if !updates.StartDate.IsZero() {
+		merged.StartDate = updates.StartDate
+	}
+	if !updates.EndDate.IsZero() {
+		merged.EndDate = updates.EndDate
+	}
+	if updates.PaymentCycle != "" {
+		merged.PaymentCycle = updates.PaymentCycle
+	}
+	if updates.Status != "" {
+		merged.Status = updates.Status
+	}
+
+	oldRoomIDs := make([]uint, 0, len(lease.Rooms))
+	for _, room := range lease.Rooms {
+		oldRoomIDs = append(oldRoomIDs, room.ID)
+	}
+	if roomIDs == nil {
+		roomIDs = oldRoomIDs
+	}
+
+	if updates.Status == model.LeaseStatusTerminated && lease.Status != model.LeaseStatusTerminated {
+		now := time.Now()
+		updates.TerminatedAt = &now
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		rooms, err := validateLease(tx, &merged, roomIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&lease).Omit("Tenant", "Rooms").Updates(updates).Error; err != nil {
+			return err
+		}
+		// 恢复已终止的合同时清除终止时间
+		if merged.Status == model.LeaseStatusActive && lease.TerminatedAt != nil {
+			if err := tx.Model(&lease).Update("terminated_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&lease).Association("Rooms").Replace(rooms); err != nil {
+			return err
+		}
+		return syncRoomStatus(tx, append(oldRoomIDs, roomIDs...))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLeaseByID(id)
+}
+
+// DeleteLease 删除合同并释放其房间
+func (s *LeaseService) DeleteLease(id uint) error {
+	var lease model.Lease
+	if err := s.db.Preload("Rooms").First(&lease, id).Error; err != nil {
+		return err
+	}
+
+	roomIDs := make([]uint, 0, len(lease.Rooms))
+	for _, room := range lease.Rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.Lease{}, id).Error; err != nil {
+			return err
+		}
+		return syncRoomStatus(tx, roomIDs)
+	})
+}
+
+// validateLease 在事务中校验合同内容，返回合同关联的房间
+// 关联的房间按ID顺序加行锁，并发为同一房间签订合同时后到的事务等待前者提交后再检查租期冲突
+func validateLease(tx *gorm.DB, lease *model.Lease, roomIDs []uint) ([]model.Room, error) {
+	if lease.StartDate.IsZero() || lease.EndDate.IsZero() {
+		return nil, leaseError("请填写租期开始和结束日期")
+	}
+	if lease.EndDate.Before(lease.StartDate) {
+		return nil, leaseError("租期结束日期不能早于开始日期")
+	}
+	if !model.IsValidPaymentCycle(lease.PaymentCycle) {
+		return nil, leaseError("无效的付款周期")
+	}
+	if lease.Status != model.LeaseStatusActive && lease.Status != model.LeaseStatusTerminated {
+		return nil, leaseError("无效的合同状态")
+	}
+	if err := tx.Select("id").First(&model.Tenant{}, lease.TenantID).Error; err != nil {
+		return nil, leaseError("租户不存在")
+	}
+
+	roomIDs = uniqueIDs(roomIDs)
+	if len(roomIDs) == 0 {
+		return nil, leaseError("请选择租赁房间")
+	}
+
+	// 房间必须存在且在数据权限范围内
+	var rooms []model.Room
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", roomIDs).Order("id").Find(&rooms).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) != len(roomIDs) {
+		return nil, ErrLeaseRoomNotFound
+	}
+
+	// 已终止的合同不占用房间
+	if lease.Status == model.LeaseStatusTerminated {
+		return rooms, nil
+	}
+
+	var count int64
+	err = datascope.Skip(tx).Table("t_lease_rooms lr").
+		Joins("JOIN t_lease l ON l.id = lr.lease_id").
+		Where("l.deleted_at IS NULL AND l.status = ? AND l.id <> ?", model.LeaseStatusActive, lease.ID).
+		Where("l.start_date <= ? AND l.end_date >= ?", lease.EndDate.Format(dateLayout), lease.StartDate.Format(dateLayout)).
+		Where("lr.room_id IN ?", roomIDs).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrLeaseRoomConflict
+	}
+
+	return rooms, nil
+}
+
+// uniqueIDs 去除重复和无效的ID
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// Room status
+
+// activeLeaseRooms 指定日期处于生效合同中的房间ID子查询，该日之后才终止的合同在当日仍然生效
+func activeLeaseRooms(db *gorm.DB, day time.Time) *gorm.DB {
+	date := day.Format(dateLayout)
+	nextDay := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	return db.Session(&gorm.Session{NewDB: true}).Table("t_lease_rooms lr").
+		Select("lr.room_id").
+		Joins("JOIN t_lease l ON l.id = lr.lease_id").
+		Where("l.deleted_at IS NULL AND l.start_date <= ? AND l.end_date >= ?", date, date).
+		Where("(l.status = ? OR l.terminated_at >= ?)", model.LeaseStatusActive, nextDay)
+}
+
+// syncRoomStatus 按生效合同刷新房间状态：有生效合同为已租，否则为可租，维护中的房间不处理
+// roomIDs为nil时刷新全部房间；状态变化的房间所在楼层同时重算汇总字段
+func syncRoomStatus(tx *gorm.DB, roomIDs []uint) error {
+	if roomIDs != nil && len(roomIDs) == 0 {
+		return nil
+	}
+	today := time.Now()
+
+	var toRent, toRelease []model.Room
+	rent := datascope.Skip(tx).Select("id", "floor_id").
+		Where("status = ? AND id IN (?)", model.RoomStatusAvailable, activeLeaseRooms(tx, today))
+	release := datascope.Skip(tx).Select("id", "floor_id").
+		Where("status = ? AND id NOT IN (?)", model.RoomStatusRented, activeLeaseRooms(tx, today))
+	if roomIDs != nil {
+		rent = rent.Where("id IN ?", roomIDs)
+		release = release.Where("id IN ?", roomIDs)
+	}
+	if err := rent.Find(&toRent).Error; err != nil {
+		return err
+	}
+	if err := release.Find(&toRelease).Error; err != nil {
+		return err
+	}
+
+	var floorIDs []uint
+	for status, rooms := range map[string][]model.Room{
+		model.RoomStatusRented:    toRent,
+		model.RoomStatusAvailable: toRelease,
+	} {
+		if len(rooms) == 0 {
+			continue
+		}
+		ids := make([]uint, 0, len(rooms))
+		for _, room := range rooms {
+			ids = append(ids, room.ID)
+			floorIDs = append(floorIDs, room.FloorID)
+		}
+		if err := datascope.Skip(tx).Model(&model.Room{}).Where("id IN ?", ids).Update("status", status).Error; err != nil {
+			return err
+		}
+	}
+
+	return refreshRollups(tx, floorIDs, nil, nil)
+}
+
+// roomLeaseStatus 返回房间当前按合同应有的状态
+func roomLeaseStatus(db *gorm.DB, roomID uint) (string, error) {
+	var count int64
+	err := datascope.Skip(db).Table("(?) AS active", activeLeaseRooms(db, time.Now())).
+		Where("room_id = ?", roomID).Count(&count).Error
+	if err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return model.RoomStatusRented, nil
+	}
+	return model.RoomStatusAvailable, nil
+}
+
+// SyncAllRoomStatus 刷新全部房间的出租状态，供定时任务在合同起止日变化时调用
+func (s *LeaseService) SyncAllRoomStatus() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return syncRoomStatus(tx, nil)
+	})
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/pkg/logger"
+)
+
+// RoomStatusWorker 定时按合同起止日刷新房间出租状态
+// 合同到达开始日或结束日时没有写操作触发刷新，由该任务补齐
+type RoomStatusWorker struct {
+	service  *LeaseService
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewRoomStatusWorker 创建房间状态刷新任务
+func NewRoomStatusWorker(service *LeaseService, interval time.Duration) *RoomStatusWorker {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &RoomStatusWorker{
+		service:  service,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start 启动任务，启动时立即刷新一次
+func (w *RoomStatusWorker) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := w.service.SyncAllRoomStatus(); err != nil {
+				logger.Errorf("刷新房间出租状态失败: %v", err)
+			}
+
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止任务并等待当前刷新完成
+func (w *RoomStatusWorker) Stop() {
+	close(w.stop)
+	w.wg.Wait()
+}
+
+var roomStatusWorker *RoomStatusWorker
+
+// StartRoomStatusWorker 启动全局房间状态刷新任务
+func StartRoomStatusWorker(cfg config.LeaseConfig) {
+	if roomStatusWorker != nil {
+		return
+	}
+	interval := time.Duration(cfg.RoomStatusSyncInterval) * time.Second
+	roomStatusWorker = NewRoomStatusWorker(NewLeaseService(), interval)
+	roomStatusWorker.Start()
+}
+
+// StopRoomStatusWorker 停止全局房间状态刷新任务
+func StopRoomStatusWorker() {
+	if roomStatusWorker != nil {
+		roomStatusWorker.Stop()
+		roomStatusWorker = nil
+	}
+}
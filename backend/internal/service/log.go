@@ -19,10 +19,8 @@ func NewLogService() *LogService {
 	}
 }
 
-func (s *LogService) GetOperationLogs(page, pageSize int, username, module string, startTime, endTime *time.Time) ([]*model.OperationLog, int64, error) {
-	var logs []*model.OperationLog
-	var total int64
-
+// operationLogQuery 操作日志列表查询条件
+func (s *LogService) operationLogQuery(username, module string, startTime, endTime *time.Time) *gorm.DB {
 	query := s.db.Model(&model.OperationLog{})
 
 	if username != "" {
@@ -32,13 +30,21 @@ func (s *LogService) GetOperationLogs(page, pageSize int, username, module strin
 		query = query.Where("module = ?", module)
 	}
 	if startTime != nil {
-		query = query.Where("created_at >= ?", startTime)
+		query = query.Where("operation_time >= ?", startTime)
 	}
 	if endTime != nil {
 		// 添加一天，包含结束日期当天的数据
 		endDate := endTime.Add(24 * time.Hour)
-		query = query.Where("created_at < ?", endDate)
+		query = query.Where("operation_time < ?", endDate)
 	}
+	return query
+}
+
+func (s *LogService) GetOperationLogs(page, pageSize int, username, module string, startTime, endTime *time.Time) ([]*model.OperationLog, int64, error) {
+	var logs []*model.OperationLog
+	var total int64
+
+	query := s.operationLogQuery(username, module, startTime, endTime)
 
 	err := query.Count(&total).Error
 	if err != nil {
@@ -46,7 +52,7 @@ func (s *LogService) GetOperationLogs(page, pageSize int, username, module strin
 	}
 
 	offset := (page - 1) * pageSize
-	err = query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	err = query.Order("operation_time DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -54,23 +60,34 @@ func (s *LogService) GetOperationLogs(page, pageSize int, username, module strin
 	return logs, total, nil
 }
 
-func (s *LogService) GetLoginLogs(page, pageSize int, username string, startTime, endTime *time.Time) ([]*model.LoginLog, int64, error) {
-	var logs []*model.LoginLog
-	var total int64
+// ExportOperationLogs 按列表筛选条件分批读取全部操作日志
+func (s *LogService) ExportOperationLogs(username, module string, startTime, endTime *time.Time, maxRows int, fn func([]*model.OperationLog) error) error {
+	return exportRows(s.operationLogQuery(username, module, startTime, endTime), maxRows, fn)
+}
 
+// loginLogQuery 登录日志列表查询条件
+func (s *LogService) loginLogQuery(username string, startTime, endTime *time.Time) *gorm.DB {
 	query := s.db.Model(&model.LoginLog{})
 
 	if username != "" {
 		query = query.Where("username LIKE ?", "%"+username+"%")
 	}
 	if startTime != nil {
-		query = query.Where("created_at >= ?", startTime)
+		query = query.Where("login_time >= ?", startTime)
 	}
 	if endTime != nil {
 		// 添加一天，包含结束日期当天的数据
 		endDate := endTime.Add(24 * time.Hour)
-		query = query.Where("created_at < ?", endDate)
+		query = query.Where("login_time < ?", endDate)
 	}
+	return query
+}
+
+func (s *LogService) GetLoginLogs(page, pageSize int, username string, startTime, endTime *time.Time) ([]*model.LoginLog, int64, error) {
+	var logs []*model.LoginLog
+	var total int64
+
+	query := s.loginLogQuery(username, startTime, endTime)
 
 	err := query.Count(&total).Error
 	if err != nil {
@@ -78,7 +95,7 @@ func (s *LogService) GetLoginLogs(page, pageSize int, username string, startTime
 	}
 
 	offset := (page - 1) * pageSize
-	err = query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	err = query.Order("login_time DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -86,6 +103,11 @@ func (s *LogService) GetLoginLogs(page, pageSize int, username string, startTime
 	return logs, total, nil
 }
 
+// ExportLoginLogs 按列表筛选条件分批读取全部登录日志
+func (s *LogService) ExportLoginLogs(username string, startTime, endTime *time.Time, maxRows int, fn func([]*model.LoginLog) error) error {
+	return exportRows(s.loginLogQuery(username, startTime, endTime), maxRows, fn)
+}
+
 func (s *LogService) CreateOperationLog(log *model.OperationLog) error {
 	return s.db.Create(log).Error
 }
@@ -115,6 +137,7 @@ func (s *LogService) LogOperation(userID uint, username, module, action, method,
 func (s *LogService) LogLogin(username, ip, userAgent, status, message string) {
 	log := &model.LoginLog{
 		Username:  username,
+		LoginType: "login",
 		ClientIP:  ip,
 		UserAgent: userAgent,
 		Status:    status,
@@ -124,18 +147,48 @@ func (s *LogService) LogLogin(username, ip, userAgent, status, message string) {
 	s.CreateLoginLog(log)
 }
 
+// LogTwoFactor 记录两步验证日志
+func (s *LogService) LogTwoFactor(userID uint, username, ip, userAgent, status, message string) {
+	log := &model.LoginLog{
+		UserID:    userID,
+		Username:  username,
+		LoginType: "2fa",
+		ClientIP:  ip,
+		UserAgent: userAgent,
+		Status:    status,
+		Message:   message,
+		LoginTime: time.Now(),
+	}
+	s.CreateLoginLog(log)
+}
+
+// LogLogout 记录登出日志
+func (s *LogService) LogLogout(userID uint, username, ip, userAgent string) {
+	log := &model.LoginLog{
+		UserID:    userID,
+		Username:  username,
+		LoginType: "logout",
+		ClientIP:  ip,
+		UserAgent: userAgent,
+		Status:    "success",
+		Message:   "登出成功",
+		LoginTime: time.Now(),
+	}
+	s.CreateLoginLog(log)
+}
+
 // CleanOldLogs 清理旧日志
 func (s *LogService) CleanOldLogs(days int) error {
 	// 计算截止时间
 	deadline := time.Now().AddDate(0, 0, -days)
 
 	// 删除操作日志
-	if err := s.db.Where("created_at < ?", deadline).Delete(&model.OperationLog{}).Error; err != nil {
+	if err := s.db.Where("operation_time < ?", deadline).Delete(&model.OperationLog{}).Error; err != nil {
 		return err
 	}
 
 	// 删除登录日志
-	if err := s.db.Where("created_at < ?", deadline).Delete(&model.LoginLog{}).Error; err != nil {
+	if err := s.db.Where("login_time < ?", deadline).Delete(&model.LoginLog{}).Error; err != nil {
 		return err
 	}
 
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// OperationLogWriter 操作日志异步批量写入器
+//
+// 日志先进入缓冲通道，由后台协程按批次或时间间隔写入数据库；
+// 缓冲区已满时直接丢弃，保证记录日志不会阻塞或影响请求。
+type OperationLogWriter struct {
+	db            *gorm.DB
+	entries       chan *model.OperationLog
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Int64
+	mu            sync.RWMutex
+	closed        bool
+	done          chan struct{}
+}
+
+var operationLogWriter *OperationLogWriter
+
+// NewOperationLogWriter 创建并启动操作日志写入器
+func NewOperationLogWriter(db *gorm.DB, cfg config.OperationLogConfig) *OperationLogWriter {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	flushInterval := time.Duration(cfg.FlushInterval) * time.Second
+	if flushInterval <= 0 {
+		flushInterval = 2 * time.Second
+	}
+
+	w := &OperationLogWriter{
+		db:            db,
+		entries:       make(chan *model.OperationLog, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write 提交一条日志，不阻塞；缓冲区已满或写入器已关闭时丢弃并返回false
+func (w *OperationLogWriter) Write(log *model.OperationLog) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+
+	select {
+	case w.entries <- log:
+		return true
+	default:
+		if n := w.dropped.Add(1); n == 1 || n%100 == 0 {
+			logger.Warnf("操作日志缓冲区已满，已丢弃 %d 条日志", n)
+		}
+		return false
+	}
+}
+
+// Close 停止接收新日志，写入缓冲区中的剩余日志后返回
+func (w *OperationLogWriter) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+// run 后台批量写入
+func (w *OperationLogWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*model.OperationLog, 0, w.batchSize)
+	for {
+		select {
+		case log, ok := <-w.entries:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, log)
+			if len(batch) >= w.batchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush 写入一批日志，失败只记录错误
+func (w *OperationLogWriter) flush(batch []*model.OperationLog) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := w.db.CreateInBatches(batch, w.batchSize).Error; err != nil {
+		logger.Errorf("写入操作日志失败(%d条): %v", len(batch), err)
+	}
+}
+
+// StartOperationLogWriter 启动全局操作日志写入器
+func StartOperationLogWriter(cfg config.OperationLogConfig) {
+	if !cfg.Enabled || operationLogWriter != nil {
+		return
+	}
+	operationLogWriter = NewOperationLogWriter(database.GetDB(), cfg)
+}
+
+// WriteOperationLog 异步写入操作日志，写入器未启动时忽略
+func WriteOperationLog(log *model.OperationLog) {
+	if operationLogWriter != nil {
+		operationLogWriter.Write(log)
+	}
+}
+
+// StopOperationLogWriter 停止全局写入器并写入剩余日志
+func StopOperationLogWriter() {
+	if operationLogWriter != nil {
+		operationLogWriter.Close()
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/cache"
+	"building-asset-backend/pkg/logger"
+)
+
+var (
+	// ErrAccountTemporarilyLocked 账户因连续登录失败被临时锁定
+	ErrAccountTemporarilyLocked = errors.New("登录失败次数过多，账户已临时锁定，请稍后再试")
+	// ErrIPBlocked IP因登录失败次数过多被临时封禁
+	ErrIPBlocked = errors.New("该IP登录失败次数过多，请稍后再试")
+	// ErrLoginGuardUnavailable 无法读取登录锁定状态，拒绝登录以免失败次数限制失效
+	ErrLoginGuardUnavailable = errors.New("认证服务暂不可用，请稍后再试")
+)
+
+// 登录日志中的锁定事件状态
+const (
+	LoginStatusLocked        = "locked"         // 账户临时锁定
+	LoginStatusLockedByAdmin = "locked_account" // 账户锁定，需管理员解锁
+	LoginStatusIPBlocked     = "ip_blocked"     // IP临时封禁
+)
+
+// Authenticate 校验登录凭据，并执行按用户名和IP的失败计数与锁定
+func (s *AuthService) Authenticate(username, password, ip, userAgent string) (*model.User, error) {
+	if err := checkLoginLock(username, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.ValidateCredentials(username, password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.recordLoginFailure(username, ip, userAgent)
+		}
+		return nil, err
+	}
+
+	// 不需要两步验证时登录成功，清除该用户名的失败计数；需要两步验证时在验证通过后清除，
+	// 否则知道密码的人可以通过重新登录清零验证码的错误次数
+	if !user.TOTPEnabled && !requiresTwoFactor(user) {
+		clearLoginFailures(username)
+	}
+
+	return user, nil
+}
+
+// checkLoginLock 检查IP封禁和账户临时锁定，读取失败时拒绝登录
+func checkLoginLock(username, ip string) error {
+	blocked, err := cache.Exists(loginLockKey("ip", ip))
+	if err != nil {
+		logger.Errorf("读取IP登录封禁状态失败: %v", err)
+		return ErrLoginGuardUnavailable
+	}
+	if blocked {
+		return ErrIPBlocked
+	}
+
+	locked, err := cache.Exists(loginLockKey("user", username))
+	if err != nil {
+		logger.Errorf("读取账户登录锁定状态失败: %v", err)
+		return ErrLoginGuardUnavailable
+	}
+	if locked {
+		return ErrAccountTemporarilyLocked
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次登录失败，达到阈值时锁定账户或IP
+func (s *AuthService) recordLoginFailure(username, ip, userAgent string) {
+	cfg := config.Get().Security.Login
+	window := time.Duration(cfg.FailureWindow) * time.Second
+	lockDuration := time.Duration(cfg.LockDuration) * time.Second
+
+	if cfg.MaxFailures > 0 {
+		failures, err := cache.IncrWithExpire(loginFailureKey("user", username), window)
+		if err != nil {
+			logger.Warnf("记录登录失败次数失败: %v", err)
+		} else if failures >= int64(cfg.MaxFailures) {
+			s.lockAccount(username, ip, userAgent, lockDuration)
+		}
+	}
+
+	if cfg.IPMaxFailures > 0 && ip != "" {
+		failures, err := cache.IncrWithExpire(loginFailureKey("ip", ip), window)
+		if err != nil {
+			logger.Warnf("记录IP登录失败次数失败: %v", err)
+		} else if failures >= int64(cfg.IPMaxFailures) {
+			if err := cache.Set(loginLockKey("ip", ip), time.Now().Unix(), lockDuration); err != nil {
+				logger.Errorf("封禁IP失败: %v", err)
+				return
+			}
+			if err := cache.Delete(loginFailureKey("ip", ip)); err != nil {
+				logger.Warnf("清除IP登录失败计数失败: %v", err)
+			}
+			s.logService.LogLogin(username, ip, userAgent, LoginStatusIPBlocked,
+				fmt.Sprintf("IP登录失败%d次，封禁%d秒", failures, cfg.LockDuration))
+		}
+	}
+}
+
+// lockAccount 临时锁定账户，短期内多次锁定则将账户状态置为locked
+func (s *AuthService) lockAccount(username, ip, userAgent string, lockDuration time.Duration) {
+	cfg := config.Get().Security.Login
+
+	if err := cache.Set(loginLockKey("user", username), time.Now().Unix(), lockDuration); err != nil {
+		// 未能写入临时锁定时保留失败计数，下次失败会再次尝试锁定
+		logger.Errorf("临时锁定账户失败: %v", err)
+		return
+	}
+	if err := cache.Delete(loginFailureKey("user", username)); err != nil {
+		logger.Warnf("清除登录失败计数失败: %v", err)
+	}
+	s.logService.LogLogin(username, ip, userAgent, LoginStatusLocked,
+		fmt.Sprintf("连续登录失败%d次，账户临时锁定%d秒", cfg.MaxFailures, cfg.LockDuration))
+
+	if cfg.MaxLockouts <= 0 {
+		return
+	}
+
+	lockouts, err := cache.IncrWithExpire(loginLockoutsKey(username), time.Duration(cfg.LockoutWindow)*time.Second)
+	if err != nil {
+		logger.Warnf("记录账户锁定次数失败: %v", err)
+		return
+	}
+	if lockouts < int64(cfg.MaxLockouts) {
+		return
+	}
+
+	result := s.db.Model(&model.User{}).Where("username = ? AND status = ?", username, "active").Update("status", "locked")
+	if result.Error != nil {
+		logger.Errorf("锁定账户失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		s.logService.LogLogin(username, ip, userAgent, LoginStatusLockedByAdmin,
+			fmt.Sprintf("账户%d次被临时锁定，已锁定账户，需管理员解锁", lockouts))
+	}
+}
+
+// clearLoginFailures 登录成功后清除用户名的失败计数
+func clearLoginFailures(username string) {
+	if err := cache.Delete(loginFailureKey("user", username)); err != nil {
+		logger.Warnf("清除登录失败计数失败: %v", err)
+	}
+}
+
+// clearLoginLock 清除用户名的登录失败计数与锁定记录
+func clearLoginLock(username string) {
+	keys := []string{
+		loginFailureKey("user", username),
+		loginLockKey("user", username),
+		loginLockoutsKey(username),
+	}
+	if err := cache.Delete(keys...); err != nil {
+		logger.Warnf("清除登录锁定记录失败: %v", err)
+	}
+}
+
+// loginFailureKey 登录失败计数缓存键
+func loginFailureKey(kind, value string) string {
+	return fmt.Sprintf("login:fail:%s:%s", kind, value)
+}
+
+// loginLockKey 登录锁定缓存键
+func loginLockKey(kind, value string) string {
+	return fmt.Sprintf("login:lock:%s:%s", kind, value)
+}
+
+// loginLockoutsKey 账户临时锁定次数缓存键
+func loginLockoutsKey(username string) string {
+	return fmt.Sprintf("login:lockouts:%s", username)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrPasswordPolicy 密码不符合安全策略
+var ErrPasswordPolicy = errors.New("密码不符合安全策略")
+
+// 需要修改密码的原因
+const (
+	PasswordChangeReasonDefault = "default" // 默认密码或不符合当前策略的弱密码
+	PasswordChangeReasonExpired = "expired" // 密码已过期
+	PasswordChangeReasonReset   = "reset"   // 管理员重置后首次登录
+)
+
+// ValidatePasswordPolicy 校验密码是否符合配置的密码策略
+func ValidatePasswordPolicy(password, username string) error {
+	policy := config.Get().Security.Password
+
+	if password == "" {
+		return fmt.Errorf("%w: 密码不能为空", ErrPasswordPolicy)
+	}
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("%w: 密码长度不能少于%d位", ErrPasswordPolicy, policy.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		return fmt.Errorf("%w: 密码必须包含大写字母", ErrPasswordPolicy)
+	}
+	if policy.RequireLower && !hasLower {
+		return fmt.Errorf("%w: 密码必须包含小写字母", ErrPasswordPolicy)
+	}
+	if policy.RequireDigit && !hasDigit {
+		return fmt.Errorf("%w: 密码必须包含数字", ErrPasswordPolicy)
+	}
+	if policy.RequireSpecial && !hasSpecial {
+		return fmt.Errorf("%w: 密码必须包含特殊字符", ErrPasswordPolicy)
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		return fmt.Errorf("%w: 密码不能与用户名相同", ErrPasswordPolicy)
+	}
+	for _, banned := range policy.BannedPasswords {
+		if strings.EqualFold(password, banned) {
+			return fmt.Errorf("%w: 密码过于简单", ErrPasswordPolicy)
+		}
+	}
+
+	return nil
+}
+
+// PasswordChangeReason 判断用户登录后是否必须修改密码，返回原因，无需修改时返回空字符串
+func (s *UserService) PasswordChangeReason(user *model.User, password string) string {
+	if user.MustChangePassword {
+		return PasswordChangeReasonReset
+	}
+
+	if ValidatePasswordPolicy(password, user.Username) != nil {
+		return PasswordChangeReasonDefault
+	}
+
+	maxAge := config.Get().Security.Password.MaxAgeDays
+	if maxAge > 0 {
+		changedAt := user.CreatedAt
+		if user.PasswordChangedAt != nil {
+			changedAt = *user.PasswordChangedAt
+		}
+		if time.Since(changedAt) > time.Duration(maxAge)*24*time.Hour {
+			return PasswordChangeReasonExpired
+		}
+	}
+
+	return ""
+}
+
+// ChangePassword 用户自助修改密码，成功后吊销该用户的全部会话
+func (s *UserService) ChangePassword(id uint, oldPassword, newPassword string) error {
+	var user model.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return err
+	}
+
+	if !user.CheckPassword(oldPassword) {
+		return errors.New("原密码错误")
+	}
+	if oldPassword == newPassword {
+		return fmt.Errorf("%w: 新密码不能与原密码相同", ErrPasswordPolicy)
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.setPassword(tx, &user, newPassword, false)
+	}); err != nil {
+		return err
+	}
+
+	return s.RevokeUserSessions(id)
+}
+
+// setPassword 校验密码策略和历史记录后更新密码
+func (s *UserService) setPassword(tx *gorm.DB, user *model.User, password string, mustChange bool) error {
+	if err := ValidatePasswordPolicy(password, user.Username); err != nil {
+		return err
+	}
+
+	if user.ID > 0 {
+		if err := s.checkPasswordHistory(tx, user, password); err != nil {
+			return err
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Password = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = mustChange
+
+	if user.ID == 0 {
+		return nil
+	}
+
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"password":             user.Password,
+		"password_changed_at":  user.PasswordChangedAt,
+		"must_change_password": user.MustChangePassword,
+	}).Error; err != nil {
+		return err
+	}
+
+	return s.recordPasswordHistory(tx, user.ID, user.Password)
+}
+
+// checkPasswordHistory 检查新密码是否与当前密码或最近使用过的密码相同
+func (s *UserService) checkPasswordHistory(tx *gorm.DB, user *model.User, password string) error {
+	count := config.Get().Security.Password.HistoryCount
+	if count <= 0 {
+		return nil
+	}
+
+	hashes := []string{user.Password}
+	var histories []model.PasswordHistory
+	if err := tx.Where("user_id = ?", user.ID).Order("id DESC").Limit(count).Find(&histories).Error; err != nil {
+		return err
+	}
+	for _, h := range histories {
+		hashes = append(hashes, h.PasswordHash)
+	}
+
+	for _, hash := range hashes {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return fmt.Errorf("%w: 不能使用最近%d次使用过的密码", ErrPasswordPolicy, count)
+		}
+	}
+	return nil
+}
+
+// recordPasswordHistory 记录密码历史并清理超出保留数量的记录
+func (s *UserService) recordPasswordHistory(tx *gorm.DB, userID uint, hash string) error {
+	if err := tx.Create(&model.PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+		return err
+	}
+
+	keep := config.Get().Security.Password.HistoryCount
+	if keep <= 0 {
+		keep = 1
+	}
+
+	var ids []uint
+	if err := tx.Model(&model.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) > keep {
+		return tx.Delete(&model.PasswordHistory{}, ids[keep:]).Error
+	}
+	return nil
+}
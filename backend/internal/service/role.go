@@ -2,13 +2,27 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"building-asset-backend/internal/datascope"
 	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/cache"
 	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/logger"
 
 	"gorm.io/gorm"
 )
 
+// userPermissionCacheTTL 用户权限缓存有效期
+const userPermissionCacheTTL = 30 * time.Minute
+
+// orgTreeGenerationKey 组织树代数缓存键
+const orgTreeGenerationKey = "org:tree_generation"
+
+// ErrInvalidDataScope 数据权限范围不合法
+var ErrInvalidDataScope = errors.New("无效的数据权限范围")
+
 type RoleService struct {
 	db *gorm.DB
 }
@@ -21,6 +35,15 @@ func NewRoleService() *RoleService {
 
 // Role operations
 
+// GetRoleByCode 按代码获取角色
+func (s *RoleService) GetRoleByCode(code string) (*model.Role, error) {
+	var role model.Role
+	if err := s.db.Where("code = ?", code).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
 func (s *RoleService) GetRoles(page, pageSize int, name, code string) ([]*model.Role, int64, error) {
 	var roles []*model.Role
 	var total int64
@@ -69,6 +92,12 @@ func (s *RoleService) CreateRole(role *model.Role) (*model.Role, error) {
 	if role.Status == "" {
 		role.Status = "active"
 	}
+	if role.DataScope == "" {
+		role.DataScope = model.DataScopeAll
+	}
+	if !model.IsValidDataScope(role.DataScope) {
+		return nil, ErrInvalidDataScope
+	}
 
 	if err := s.db.Create(role).Error; err != nil {
 		return nil, err
@@ -78,6 +107,10 @@ func (s *RoleService) CreateRole(role *model.Role) (*model.Role, error) {
 }
 
 func (s *RoleService) UpdateRole(id uint, updates *model.Role) (*model.Role, error) {
+	if updates.DataScope != "" && !model.IsValidDataScope(updates.DataScope) {
+		return nil, ErrInvalidDataScope
+	}
+
 	var role model.Role
 	if err := s.db.First(&role, id).Error; err != nil {
 		return nil, err
@@ -96,9 +129,28 @@ func (s *RoleService) UpdateRole(id uint, updates *model.Role) (*model.Role, err
 		return nil, err
 	}
 
+	// 角色状态可能变化，刷新相关用户的权限缓存
+	s.invalidateRoleUsers(role.ID)
+
 	return &role, nil
 }
 
+// SetRoleTwoFactor 设置角色是否要求成员启用两步验证
+func (s *RoleService) SetRoleTwoFactor(id uint, required bool) error {
+	result := s.db.Model(&model.Role{}).Where("id = ?", id).Update("require_two_factor", required)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		s.db.Model(&model.Role{}).Where("id = ?", id).Count(&count)
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
 func (s *RoleService) DeleteRole(id uint) error {
 	// 检查是否有用户使用该角色
 	var count int64
@@ -111,6 +163,7 @@ func (s *RoleService) DeleteRole(id uint) error {
 	var role model.Role
 	s.db.First(&role, id)
 	s.db.Model(&role).Association("Permissions").Clear()
+	s.invalidateRoleUsers(id)
 
 	return s.db.Delete(&model.Role{}, id).Error
 }
@@ -128,7 +181,239 @@ func (s *RoleService) UpdateRolePermissions(roleID uint, permissionIDs []uint) e
 	}
 
 	// 更新角色权限关联
-	return s.db.Model(&role).Association("Permissions").Replace(permissions)
+	if err := s.db.Model(&role).Association("Permissions").Replace(permissions); err != nil {
+		return err
+	}
+
+	s.invalidateRoleUsers(role.ID)
+	return nil
+}
+
+// GetUserPermissionCodes 获取用户的有效权限代码，优先读取缓存
+func (s *RoleService) GetUserPermissionCodes(userID uint) ([]string, error) {
+	key := userPermissionCacheKey(userID)
+
+	var codes []string
+	err := cache.Get(key, &codes)
+	if err == nil {
+		return codes, nil
+	}
+	if !cache.IsNil(err) {
+		logger.Warnf("读取用户权限缓存失败: %v", err)
+	}
+
+	var user model.User
+	if err := s.db.Preload("Roles", "status = ?", "active").Preload("Roles.Permissions").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	codes = make([]string, 0)
+	if user.IsActive() {
+		seen := make(map[string]bool)
+		for _, role := range user.Roles {
+			for _, perm := range role.Permissions {
+				if !seen[perm.Code] {
+					seen[perm.Code] = true
+					codes = append(codes, perm.Code)
+				}
+			}
+		}
+	}
+
+	if err := cache.Set(key, codes, userPermissionCacheTTL); err != nil {
+		logger.Warnf("写入用户权限缓存失败: %v", err)
+	}
+
+	return codes, nil
+}
+
+// cachedDataScope 缓存的用户数据权限，记录计算时的组织树代数
+type cachedDataScope struct {
+	OrgTreeGeneration int64
+	Scope             *datascope.Scope
+}
+
+// GetUserDataScope 获取用户的数据权限，优先读取缓存
+// 组织增删改后组织树代数加1，代数不一致的缓存需重新计算，避免逐个清除本级及下级用户的缓存
+func (s *RoleService) GetUserDataScope(userID uint) (*datascope.Scope, error) {
+	generation, err := orgTreeGeneration()
+	if err != nil {
+		logger.Warnf("读取组织树代数失败: %v", err)
+		return s.loadUserDataScope(userID)
+	}
+
+	key := userDataScopeCacheKey(userID)
+	var cached cachedDataScope
+	err = cache.Get(key, &cached)
+	if err == nil && cached.Scope != nil && cached.OrgTreeGeneration == generation {
+		return cached.Scope, nil
+	}
+	if err != nil && !cache.IsNil(err) {
+		logger.Warnf("读取用户数据权限缓存失败: %v", err)
+	}
+
+	scope, err := s.loadUserDataScope(userID)
+	if err != nil {
+		return nil, err
+	}
+	cached = cachedDataScope{OrgTreeGeneration: generation, Scope: scope}
+	if err := cache.Set(key, cached, userPermissionCacheTTL); err != nil {
+		logger.Warnf("写入用户数据权限缓存失败: %v", err)
+	}
+	return scope, nil
+}
+
+// loadUserDataScope 计算用户的数据权限，多个角色取范围最大的一个
+func (s *RoleService) loadUserDataScope(userID uint) (*datascope.Scope, error) {
+	var user model.User
+	if err := s.db.Preload("Roles", "status = ?", "active").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	// 没有有效角色时只能访问本人数据
+	level := model.DataScopeSelf
+	rank := map[string]int{
+		model.DataScopeSelf:           0,
+		model.DataScopeOrg:            1,
+		model.DataScopeOrgAndChildren: 2,
+		model.DataScopeAll:            3,
+	}
+	for _, role := range user.Roles {
+		roleScope := role.DataScope
+		if roleScope == "" {
+			roleScope = model.DataScopeAll
+		}
+		if rank[roleScope] > rank[level] {
+			level = roleScope
+		}
+	}
+
+	scope := &datascope.Scope{UserID: user.ID, OrgIDs: []uint{}}
+	switch level {
+	case model.DataScopeAll:
+		scope.All = true
+	case model.DataScopeOrgAndChildren:
+		if user.OrgID > 0 {
+			ids, err := s.descendantOrgIDs(user.OrgID)
+			if err != nil {
+				return nil, err
+			}
+			scope.OrgIDs = ids
+		}
+	case model.DataScopeOrg:
+		if user.OrgID > 0 {
+			scope.OrgIDs = []uint{user.OrgID}
+		}
+	default:
+		scope.SelfOnly = true
+		if user.OrgID > 0 {
+			scope.OrgIDs = []uint{user.OrgID}
+		}
+	}
+
+	return scope, nil
+}
+
+// descendantOrgIDs 获取组织及其全部下级组织ID
+func (s *RoleService) descendantOrgIDs(orgID uint) ([]uint, error) {
+	var orgs []model.Organization
+	if err := s.db.Select("id", "parent_id").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, org := range orgs {
+		if org.ParentID != nil {
+			children[*org.ParentID] = append(children[*org.ParentID], org.ID)
+		}
+	}
+
+	ids := []uint{orgID}
+	seen := map[uint]bool{orgID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// HasPermission 检查用户是否拥有指定权限
+func (s *RoleService) HasPermission(userID uint, permission string) (bool, error) {
+	codes, err := s.GetUserPermissionCodes(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, code := range codes {
+		if code == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// invalidateRoleUsers 清除拥有该角色的所有用户的权限和数据权限缓存
+func (s *RoleService) invalidateRoleUsers(roleID uint) {
+	role := model.Role{BaseModel: model.BaseModel{ID: roleID}}
+	var users []model.User
+	if err := s.db.Model(&role).Association("Users").Find(&users); err != nil {
+		logger.Warnf("查询角色用户失败: %v", err)
+		return
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+	InvalidateUserPermissions(userIDs...)
+}
+
+// InvalidateUserPermissions 清除用户权限和数据权限缓存
+func InvalidateUserPermissions(userIDs ...uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, 2*len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, userPermissionCacheKey(id), userDataScopeCacheKey(id))
+	}
+	if err := cache.Delete(keys...); err != nil {
+		logger.Warnf("清除用户权限缓存失败: %v", err)
+	}
+}
+
+// userPermissionCacheKey 用户权限缓存键
+func userPermissionCacheKey(userID uint) string {
+	return fmt.Sprintf("user:permissions:%d", userID)
+}
+
+// userDataScopeCacheKey 用户数据权限缓存键
+func userDataScopeCacheKey(userID uint) string {
+	return fmt.Sprintf("user:datascope:%d", userID)
+}
+
+// InvalidateOrgTree 组织树变化后使全部用户的数据权限缓存失效
+func InvalidateOrgTree() {
+	if _, err := cache.Incr(orgTreeGenerationKey); err != nil {
+		logger.Warnf("更新组织树代数失败: %v", err)
+	}
+}
+
+// orgTreeGeneration 当前的组织树代数，从未变化过时为0
+func orgTreeGeneration() (int64, error) {
+	var generation int64
+	if err := cache.Get(orgTreeGenerationKey, &generation); err != nil {
+		if cache.IsNil(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return generation, nil
 }
 
 // Permission operations
@@ -146,47 +431,130 @@ func (s *RoleService) GetPermissionTree() ([]*model.Permission, error) {
 
 // Initialize default roles and permissions
 
-func (s *RoleService) InitializeDefaultData() error {
-	// 创建默认权限
-	var permCount int64
-	s.db.Model(&model.Permission{}).Count(&permCount)
-	if permCount == 0 {
-		permissions := []model.Permission{
-			// 资产管理权限
-			{Name: "资产管理", Code: "asset", Module: "asset", Description: "资产管理模块权限"},
-			{Name: "资产列表", Code: "asset:list", Module: "asset", Description: "查看资产列表"},
-			{Name: "查看资产", Code: "asset:view", Module: "asset", Description: "查看资产详情"},
-			{Name: "创建资产", Code: "asset:create", Module: "asset", Description: "创建新资产"},
-			{Name: "编辑资产", Code: "asset:update", Module: "asset", Description: "编辑资产信息"},
-			{Name: "删除资产", Code: "asset:delete", Module: "asset", Description: "删除资产"},
-
-			{Name: "建筑列表", Code: "building:list", Module: "asset", Description: "查看建筑列表"},
-			{Name: "查看建筑", Code: "building:view", Module: "asset", Description: "查看建筑详情"},
-			{Name: "创建建筑", Code: "building:create", Module: "asset", Description: "创建新建筑"},
-			{Name: "编辑建筑", Code: "building:update", Module: "asset", Description: "编辑建筑信息"},
-			{Name: "删除建筑", Code: "building:delete", Module: "asset", Description: "删除建筑"},
-
-			// 系统管理权限
-			{Name: "系统管理", Code: "system", Module: "system", Description: "系统管理模块权限"},
-			{Name: "用户管理", Code: "user:list", Module: "system", Description: "用户管理权限"},
-			{Name: "查看用户", Code: "user:view", Module: "system", Description: "查看用户详情"},
-			{Name: "创建用户", Code: "user:create", Module: "system", Description: "创建新用户"},
-			{Name: "编辑用户", Code: "user:update", Module: "system", Description: "编辑用户信息"},
-			{Name: "删除用户", Code: "user:delete", Module: "system", Description: "删除用户"},
-
-			{Name: "角色管理", Code: "role:list", Module: "system", Description: "角色管理权限"},
-			{Name: "查看角色", Code: "role:view", Module: "system", Description: "查看角色详情"},
-			{Name: "创建角色", Code: "role:create", Module: "system", Description: "创建新角色"},
-			{Name: "编辑角色", Code: "role:update", Module: "system", Description: "编辑角色信息"},
-			{Name: "删除角色", Code: "role:delete", Module: "system", Description: "删除角色"},
-
-			{Name: "组织管理", Code: "org:list", Module: "system", Description: "组织管理权限"},
-			{Name: "操作日志", Code: "log:list", Module: "system", Description: "查看操作日志"},
-		}
-
-		for _, perm := range permissions {
-			s.db.Create(&perm)
+// defaultPermissions 内置权限定义
+func defaultPermissions() []model.Permission {
+	return []model.Permission{
+		// 资产管理权限
+		{Name: "资产管理", Code: "asset", Module: "asset", Description: "资产管理模块权限"},
+		{Name: "资产列表", Code: "asset:list", Module: "asset", Description: "查看资产列表"},
+		{Name: "查看资产", Code: "asset:view", Module: "asset", Description: "查看资产详情"},
+		{Name: "创建资产", Code: "asset:create", Module: "asset", Description: "创建新资产"},
+		{Name: "编辑资产", Code: "asset:update", Module: "asset", Description: "编辑资产信息"},
+		{Name: "删除资产", Code: "asset:delete", Module: "asset", Description: "删除资产"},
+		{Name: "批量导入", Code: "asset:import", Module: "asset", Description: "通过CSV或XLSX批量导入资产、建筑、楼层和房间"},
+
+		{Name: "建筑列表", Code: "building:list", Module: "asset", Description: "查看建筑列表"},
+		{Name: "查看建筑", Code: "building:view", Module: "asset", Description: "查看建筑详情"},
+		{Name: "创建建筑", Code: "building:create", Module: "asset", Description: "创建新建筑"},
+		{Name: "编辑建筑", Code: "building:update", Module: "asset", Description: "编辑建筑信息"},
+		{Name: "删除建筑", Code: "building:delete", Module: "asset", Description: "删除建筑"},
+
+		{Name: "楼层列表", Code: "floor:list", Module: "asset", Description: "查看楼层列表"},
+		{Name: "创建楼层", Code: "floor:create", Module: "asset", Description: "创建新楼层"},
+		{Name: "编辑楼层", Code: "floor:update", Module: "asset", Description: "编辑楼层信息"},
+		{Name: "删除楼层", Code: "floor:delete", Module: "asset", Description: "删除楼层"},
+
+		{Name: "房间列表", Code: "room:list", Module: "asset", Description: "查看房间列表"},
+		{Name: "创建房间", Code: "room:create", Module: "asset", Description: "创建新房间"},
+		{Name: "编辑房间", Code: "room:update", Module: "asset", Description: "编辑房间信息"},
+		{Name: "删除房间", Code: "room:delete", Module: "asset", Description: "删除房间"},
+
+		{Name: "数据统计", Code: "statistics:view", Module: "asset", Description: "查看统计数据"},
+		{Name: "重算汇总数据", Code: "rollup:recompute", Module: "asset", Description: "按房间重算楼层、建筑、资产的面积和出租率"},
+
+		// 租赁管理权限
+		{Name: "租户列表", Code: "tenant:list", Module: "lease", Description: "查看租户列表"},
+		{Name: "查看租户", Code: "tenant:view", Module: "lease", Description: "查看租户详情"},
+		{Name: "创建租户", Code: "tenant:create", Module: "lease", Description: "创建新租户"},
+		{Name: "编辑租户", Code: "tenant:update", Module: "lease", Description: "编辑租户信息"},
+		{Name: "删除租户", Code: "tenant:delete", Module: "lease", Description: "删除租户"},
+
+		{Name: "合同列表", Code: "lease:list", Module: "lease", Description: "查看租赁合同列表"},
+		{Name: "查看合同", Code: "lease:view", Module: "lease", Description: "查看租赁合同详情"},
+		{Name: "创建合同", Code: "lease:create", Module: "lease", Description: "创建租赁合同"},
+		{Name: "编辑合同", Code: "lease:update", Module: "lease", Description: "编辑租赁合同"},
+		{Name: "删除合同", Code: "lease:delete", Module: "lease", Description: "删除租赁合同"},
+
+		// 维修工单权限
+		{Name: "工单列表", Code: "work_order:list", Module: "work_order", Description: "查看维修工单列表"},
+		{Name: "查看工单", Code: "work_order:view", Module: "work_order", Description: "查看维修工单详情"},
+		{Name: "创建工单", Code: "work_order:create", Module: "work_order", Description: "创建维修工单"},
+		{Name: "处理工单", Code: "work_order:update", Module: "work_order", Description: "编辑工单、变更状态和评论"},
+		{Name: "派单", Code: "work_order:assign", Module: "work_order", Description: "指派或改派工单处理人"},
+		{Name: "删除工单", Code: "work_order:delete", Module: "work_order", Description: "删除已关闭的工单"},
+
+		// 系统管理权限
+		{Name: "系统管理", Code: "system", Module: "system", Description: "系统管理模块权限"},
+		{Name: "用户管理", Code: "user:list", Module: "system", Description: "用户管理权限"},
+		{Name: "查看用户", Code: "user:view", Module: "system", Description: "查看用户详情"},
+		{Name: "创建用户", Code: "user:create", Module: "system", Description: "创建新用户"},
+		{Name: "编辑用户", Code: "user:update", Module: "system", Description: "编辑用户信息"},
+		{Name: "删除用户", Code: "user:delete", Module: "system", Description: "删除用户"},
+
+		{Name: "角色管理", Code: "role:list", Module: "system", Description: "角色管理权限"},
+		{Name: "查看角色", Code: "role:view", Module: "system", Description: "查看角色详情"},
+		{Name: "创建角色", Code: "role:create", Module: "system", Description: "创建新角色"},
+		{Name: "编辑角色", Code: "role:update", Module: "system", Description: "编辑角色信息"},
+		{Name: "删除角色", Code: "role:delete", Module: "system", Description: "删除角色"},
+
+		{Name: "组织管理", Code: "org:list", Module: "system", Description: "组织管理权限"},
+		{Name: "查看组织", Code: "org:view", Module: "system", Description: "查看组织详情"},
+		{Name: "创建组织", Code: "org:create", Module: "system", Description: "创建新组织"},
+		{Name: "编辑组织", Code: "org:update", Module: "system", Description: "编辑组织信息"},
+		{Name: "删除组织", Code: "org:delete", Module: "system", Description: "删除组织"},
+
+		{Name: "菜单管理", Code: "menu:list", Module: "system", Description: "查看菜单配置"},
+		{Name: "操作日志", Code: "log:list", Module: "system", Description: "查看操作日志"},
+	}
+}
+
+// SyncPermissions 按内置定义补齐权限（已有权限保持不变）并将全部权限授予管理员角色，返回新增的权限数
+func (s *RoleService) SyncPermissions() (int, error) {
+	created, err := s.ensurePermissions()
+	if err != nil {
+		return created, err
+	}
+	return created, s.grantAdminAllPermissions()
+}
+
+// ensurePermissions 按代码补齐内置权限，返回新增的权限数
+func (s *RoleService) ensurePermissions() (int, error) {
+	created := 0
+	for _, perm := range defaultPermissions() {
+		result := s.db.Where("code = ?", perm.Code).FirstOrCreate(&perm)
+		if result.Error != nil {
+			return created, result.Error
 		}
+		created += int(result.RowsAffected)
+	}
+	return created, nil
+}
+
+// grantAdminAllPermissions 确保管理员角色拥有全部权限（包括后续新增的权限）
+func (s *RoleService) grantAdminAllPermissions() error {
+	var adminRole model.Role
+	if err := s.db.Where("code = ?", "admin").First(&adminRole).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var allPermissions []model.Permission
+	if err := s.db.Find(&allPermissions).Error; err != nil {
+		return err
+	}
+	if err := s.db.Model(&adminRole).Association("Permissions").Replace(allPermissions); err != nil {
+		return err
+	}
+	s.invalidateRoleUsers(adminRole.ID)
+	return nil
+}
+
+func (s *RoleService) InitializeDefaultData() error {
+	// 创建默认权限（按代码补齐，已有权限保持不变）
+	if _, err := s.ensurePermissions(); err != nil {
+		return err
 	}
 
 	// 创建默认角色
@@ -218,7 +586,10 @@ func (s *RoleService) InitializeDefaultData() error {
 
 		// 分配查看权限给普通用户
 		var viewPermissions []model.Permission
-		s.db.Where("code IN ?", []string{"asset", "asset:list", "asset:view", "building:list", "building:view"}).Find(&viewPermissions)
+		s.db.Where("code IN ?", []string{
+			"asset", "asset:list", "asset:view", "building:list", "building:view",
+			"floor:list", "room:list", "statistics:view",
+		}).Find(&viewPermissions)
 		s.db.Model(userRole).Association("Permissions").Replace(viewPermissions)
 
 		// 给默认管理员分配角色
@@ -228,5 +599,5 @@ func (s *RoleService) InitializeDefaultData() error {
 		}
 	}
 
-	return nil
+	return s.grantAdminAllPermissions()
 }
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"math"
+	"sort"
+
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// rollupTolerance 汇总值比较容差，小于该值的差异视为一致
+const rollupTolerance = 0.01
+
+// RollupMismatch 存储值与按下级记录计算值不一致的字段
+type RollupMismatch struct {
+	EntityType string  `json:"entity_type"`
+	EntityID   uint    `json:"entity_id"`
+	Field      string  `json:"field"`
+	Stored     float64 `json:"stored"`
+	Computed   float64 `json:"computed"`
+}
+
+// RollupReport 汇总数据重算结果
+type RollupReport struct {
+	Floors     int              `json:"floors"`     // 检查的楼层数
+	Buildings  int              `json:"buildings"`  // 检查的建筑数
+	Assets     int              `json:"assets"`     // 检查的资产数
+	Mismatches []RollupMismatch `json:"mismatches"` // 已修正的不一致字段
+}
+
+// rollupFields 参与重算的汇总字段
+type rollupFields map[string]float64
+
+// refreshRollups 按房间重新计算楼层、建筑、资产的汇总字段
+// 传入受影响的楼层、建筑、资产ID，上级记录随下级自动重算；需在写操作的同一事务中调用
+func refreshRollups(tx *gorm.DB, floorIDs, buildingIDs, assetIDs []uint) error {
+	_, err := recomputeRollups(tx, uniqueIDs(floorIDs), uniqueIDs(buildingIDs), uniqueIDs(assetIDs), false)
+	return err
+}
+
+// RecomputeAllRollups 重算全部楼层、建筑、资产的汇总字段，返回存储值与计算值不一致的记录
+func (s *AssetService) RecomputeAllRollups() (*RollupReport, error) {
+	var report *RollupReport
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		report, err = recomputeRollups(tx, nil, nil, nil, true)
+		return err
+	})
+	return report, err
+}
+
+// recomputeRollups 重算汇总字段，all为true时忽略传入ID重算全部记录
+func recomputeRollups(tx *gorm.DB, floorIDs, buildingIDs, assetIDs []uint, all bool) (*RollupReport, error) {
+	db := datascope.Skip(tx)
+	report := &RollupReport{Mismatches: []RollupMismatch{}}
+
+	// 楼层：按房间汇总面积、已租面积和租金
+	if all || len(floorIDs) > 0 {
+		var floors []model.Floor
+		query := db.Select("id", "building_id", "rentable_area", "rented_area", "occupancy_rate", "avg_rent_price")
+		if !all {
+			query = query.Where("id IN ?", floorIDs)
+		}
+		if err := query.Find(&floors).Error; err != nil {
+			return nil, err
+		}
+
+		var sums []struct {
+			FloorID      uint
+			TotalArea    float64
+			RentedArea   float64
+			RentedIncome float64
+		}
+		query = db.Model(&model.Room{}).
+			Select("floor_id, SUM(room_area) AS total_area,"+
+				" SUM(CASE WHEN status = ? THEN room_area ELSE 0 END) AS rented_area,"+
+				" SUM(CASE WHEN status = ? THEN rent_price ELSE 0 END) AS rented_income",
+				model.RoomStatusRented, model.RoomStatusRented).
+			Group("floor_id")
+		if !all {
+			query = query.Where("floor_id IN ?", floorIDs)
+		}
+		if err := query.Scan(&sums).Error; err != nil {
+			return nil, err
+		}
+		byFloor := make(map[uint]int, len(sums))
+		for i, sum := range sums {
+			byFloor[sum.FloorID] = i
+		}
+
+		for _, floor := range floors {
+			var computed rollupFields
+			if i, ok := byFloor[floor.ID]; ok {
+				sum := sums[i]
+				computed = rollupFields{
+					"rentable_area":  round2(sum.TotalArea),
+					"rented_area":    round2(sum.RentedArea),
+					"occupancy_rate": ratio(sum.RentedArea*100, sum.TotalArea),
+					"avg_rent_price": ratio(sum.RentedIncome, sum.RentedArea),
+				}
+			} else {
+				computed = rollupFields{"rentable_area": 0, "rented_area": 0, "occupancy_rate": 0, "avg_rent_price": 0}
+			}
+			stored := rollupFields{
+				"rentable_area":  floor.RentableArea,
+				"rented_area":    floor.RentedArea,
+				"occupancy_rate": floor.OccupancyRate,
+				"avg_rent_price": floor.AvgRentPrice,
+			}
+			if err := applyRollup(db, &model.Floor{}, model.EntityFloor, floor.ID, stored, computed, report); err != nil {
+				return nil, err
+			}
+			buildingIDs = append(buildingIDs, floor.BuildingID)
+		}
+		report.Floors = len(floors)
+	}
+
+	// 建筑：可租面积为各楼层可租面积之和
+	buildingIDs = uniqueIDs(buildingIDs)
+	if all || len(buildingIDs) > 0 {
+		var buildings []model.Building
+		query := db.Select("id", "asset_id", "rentable_area")
+		if !all {
+			query = query.Where("id IN ?", buildingIDs)
+		}
+		if err := query.Find(&buildings).Error; err != nil {
+			return nil, err
+		}
+
+		areas, err := sumBy(db, &model.Floor{}, "building_id", "rentable_area", buildingIDs, all)
+		if err != nil {
+			return nil, err
+		}
+		for _, building := range buildings {
+			stored := rollupFields{"rentable_area": building.RentableArea}
+			computed := rollupFields{"rentable_area": round2(areas[building.ID])}
+			if err := applyRollup(db, &model.Building{}, model.EntityBuilding, building.ID, stored, computed, report); err != nil {
+				return nil, err
+			}
+			assetIDs = append(assetIDs, building.AssetID)
+		}
+		report.Buildings = len(buildings)
+	}
+
+	// 资产：可租面积为各建筑可租面积之和
+	assetIDs = uniqueIDs(assetIDs)
+	if all || len(assetIDs) > 0 {
+		var assets []model.Asset
+		query := db.Select("id", "rentable_area")
+		if !all {
+			query = query.Where("id IN ?", assetIDs)
+		}
+		if err := query.Find(&assets).Error; err != nil {
+			return nil, err
+		}
+
+		areas, err := sumBy(db, &model.Building{}, "asset_id", "rentable_area", assetIDs, all)
+		if err != nil {
+			return nil, err
+		}
+		for _, asset := range assets {
+			stored := rollupFields{"rentable_area": asset.RentableArea}
+			computed := rollupFields{"rentable_area": round2(areas[asset.ID])}
+			if err := applyRollup(db, &model.Asset{}, model.EntityAsset, asset.ID, stored, computed, report); err != nil {
+				return nil, err
+			}
+		}
+		report.Assets = len(assets)
+	}
+
+	return report, nil
+}
+
+// sumBy 按上级ID分组汇总下级记录的字段
+func sumBy(db *gorm.DB, child interface{}, parentColumn, column string, parentIDs []uint, all bool) (map[uint]float64, error) {
+	var rows []struct {
+		ParentID uint
+		Total    float64
+	}
+	query := db.Model(child).Select(parentColumn + " AS parent_id, SUM(" + column + ") AS total").Group(parentColumn)
+	if !all {
+		query = query.Where(parentColumn+" IN ?", parentIDs)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		result[row.ParentID] = row.Total
+	}
+	return result, nil
+}
+
+// applyRollup 比较存储值与计算值，不一致时写入计算值并记录差异
+// 汇总字段由系统维护，使用UpdateColumns不更新修改时间和修改人
+func applyRollup(db *gorm.DB, entity interface{}, entityType string, id uint, stored, computed rollupFields, report *RollupReport) error {
+	fields := make([]string, 0, len(computed))
+	for field := range computed {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	updates := make(map[string]interface{})
+	for _, field := range fields {
+		value := computed[field]
+		if math.Abs(stored[field]-value) < rollupTolerance {
+			continue
+		}
+		updates[field] = value
+		report.Mismatches = append(report.Mismatches, RollupMismatch{
+			EntityType: entityType,
+			EntityID:   id,
+			Field:      field,
+			Stored:     stored[field],
+			Computed:   value,
+		})
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return db.Model(entity).Where("id = ?", id).UpdateColumns(updates).Error
+}
+
+// ratio 计算比值并保留两位小数，分母为0时返回0
+func ratio(numerator, denominator float64) float64 {
+	if denominator <= 0 {
+		return 0
+	}
+	return round2(numerator / denominator)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
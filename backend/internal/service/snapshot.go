@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 趋势统计粒度
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// maxTrendDays 趋势查询的最大日期跨度
+const maxTrendDays = 3 * 366
+
+// ErrInvalidTrendQuery 趋势查询参数无效
+var ErrInvalidTrendQuery = errors.New("趋势查询参数无效")
+
+type SnapshotService struct {
+	db *gorm.DB
+}
+
+func NewSnapshotService() *SnapshotService {
+	return &SnapshotService{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务，查询时自动应用数据权限
+func (s *SnapshotService) WithContext(ctx context.Context) *SnapshotService {
+	return &SnapshotService{db: s.db.WithContext(ctx)}
+}
+
+// occupancy 出租情况累计值
+type occupancy struct {
+	RoomCount        int64
+	RentedRooms      int64
+	MaintenanceRooms int64
+	RentableArea     float64
+	RentedArea       float64
+	RentedIncome     float64
+}
+
+func (o *occupancy) add(other occupancy) {
+	o.RoomCount += other.RoomCount
+	o.RentedRooms += other.RentedRooms
+	o.MaintenanceRooms += other.MaintenanceRooms
+	o.RentableArea += other.RentableArea
+	o.RentedArea += other.RentedArea
+	o.RentedIncome += other.RentedIncome
+}
+
+func (o occupancy) snapshot(day time.Time, entityType string, entityID uint) model.OccupancySnapshot {
+	return model.OccupancySnapshot{
+		SnapshotDate:     day,
+		EntityType:       entityType,
+		EntityID:         entityID,
+		RoomCount:        o.RoomCount,
+		AvailableRooms:   o.RoomCount - o.RentedRooms - o.MaintenanceRooms,
+		RentedRooms:      o.RentedRooms,
+		MaintenanceRooms: o.MaintenanceRooms,
+		RentableArea:     round2(o.RentableArea),
+		RentedArea:       round2(o.RentedArea),
+		RentedIncome:     round2(o.RentedIncome),
+		AvgRent:          ratio(o.RentedIncome, o.RentedArea),
+	}
+}
+
+// TakeSnapshot 生成指定日期的资产、建筑、楼层快照，已有的同日快照会被替换
+// 出租情况按该日生效的合同计算，只统计该日及之前登记的记录
+func (s *SnapshotService) TakeSnapshot(day time.Time) (int, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	next := day.AddDate(0, 0, 1)
+
+	var floors []struct {
+		ID, BuildingID, AssetID, StreetID uint
+	}
+	err := s.db.Table("t_floor f").
+		Select("f.id, f.building_id, b.asset_id, a.street_id").
+		Joins("JOIN t_building b ON b.id = f.building_id AND b.deleted_at IS NULL").
+		Joins("JOIN t_asset a ON a.id = b.asset_id AND a.deleted_at IS NULL").
+		Where("f.deleted_at IS NULL AND f.created_at < ?", next).
+		Scan(&floors).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var buildings []struct {
+		ID, AssetID, StreetID uint
+	}
+	err = s.db.Table("t_building b").
+		Select("b.id, b.asset_id, a.street_id").
+		Joins("JOIN t_asset a ON a.id = b.asset_id AND a.deleted_at IS NULL").
+		Where("b.deleted_at IS NULL AND b.created_at < ?", next).
+		Scan(&buildings).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var assets []struct {
+		ID, StreetID uint
+	}
+	err = s.db.Table("t_asset").Select("id, street_id").
+		Where("deleted_at IS NULL AND created_at < ?", next).
+		Scan(&assets).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// 按楼层汇总房间
+	rented := clause.Expr{SQL: "r.id IN (?)", Vars: []interface{}{activeLeaseRooms(s.db, day)}}
+	var rows []struct {
+		FloorID          uint
+		RoomCount        int64
+		RentedRooms      int64
+		MaintenanceRooms int64
+		RentableArea     float64
+		RentedArea       float64
+		RentedIncome     float64
+	}
+	err = s.db.Table("t_room r").
+		Select("r.floor_id, COUNT(*) AS room_count,"+
+			" SUM(CASE WHEN ? THEN 1 ELSE 0 END) AS rented_rooms,"+
+			" SUM(CASE WHEN NOT (?) AND r.status = ? THEN 1 ELSE 0 END) AS maintenance_rooms,"+
+			" COALESCE(SUM(r.room_area), 0) AS rentable_area,"+
+			" COALESCE(SUM(CASE WHEN ? THEN r.room_area ELSE 0 END), 0) AS rented_area,"+
+			" COALESCE(SUM(CASE WHEN ? THEN r.rent_price ELSE 0 END), 0) AS rented_income",
+			rented, rented, model.RoomStatusMaintenance, rented, rented).
+		Where("r.deleted_at IS NULL AND r.created_at < ?", next).
+		Group("r.floor_id").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+	byFloor := make(map[uint]occupancy, len(rows))
+	for _, row := range rows {
+		byFloor[row.FloorID] = occupancy{
+			RoomCount:        row.RoomCount,
+			RentedRooms:      row.RentedRooms,
+			MaintenanceRooms: row.MaintenanceRooms,
+			RentableArea:     row.RentableArea,
+			RentedArea:       row.RentedArea,
+			RentedIncome:     row.RentedIncome,
+		}
+	}
+
+	snapshots := make([]model.OccupancySnapshot, 0, len(floors)+len(buildings)+len(assets))
+	byBuilding := make(map[uint]*occupancy, len(buildings))
+	byAsset := make(map[uint]*occupancy, len(assets))
+	for _, b := range buildings {
+		byBuilding[b.ID] = &occupancy{}
+	}
+	for _, a := range assets {
+		byAsset[a.ID] = &occupancy{}
+	}
+
+	for _, f := range floors {
+		o := byFloor[f.ID]
+		snapshot := o.snapshot(day, model.EntityFloor, f.ID)
+		snapshot.StreetID, snapshot.AssetID, snapshot.BuildingID = f.StreetID, f.AssetID, f.BuildingID
+		snapshots = append(snapshots, snapshot)
+		if total, ok := byBuilding[f.BuildingID]; ok {
+			total.add(o)
+		}
+	}
+	for _, b := range buildings {
+		o := byBuilding[b.ID]
+		snapshot := o.snapshot(day, model.EntityBuilding, b.ID)
+		snapshot.StreetID, snapshot.AssetID, snapshot.BuildingID = b.StreetID, b.AssetID, b.ID
+		snapshots = append(snapshots, snapshot)
+		if total, ok := byAsset[b.AssetID]; ok {
+			total.add(*o)
+		}
+	}
+	for _, a := range assets {
+		snapshot := byAsset[a.ID].snapshot(day, model.EntityAsset, a.ID)
+		snapshot.StreetID, snapshot.AssetID = a.StreetID, a.ID
+		snapshots = append(snapshots, snapshot)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("snapshot_date = ?", day.Format(dateLayout)).Delete(&model.OccupancySnapshot{}).Error; err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(snapshots, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(snapshots), nil
+}
+
+// HasSnapshot 指定日期是否已生成快照
+func (s *SnapshotService) HasSnapshot(day time.Time) (bool, error) {
+	var count int64
+	err := s.db.Model(&model.OccupancySnapshot{}).
+		Where("snapshot_date = ?", day.Format(dateLayout)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Backfill 补生成日期区间内（含首尾）每一天的快照，返回生成的快照条数
+func (s *SnapshotService) Backfill(from, to time.Time, progress func(day time.Time, rows int)) (int, error) {
+	if to.Before(from) {
+		return 0, fmt.Errorf("结束日期不能早于开始日期")
+	}
+
+	total := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		rows, err := s.TakeSnapshot(day)
+		if err != nil {
+			return total, fmt.Errorf("生成%s快照失败: %w", day.Format(dateLayout), err)
+		}
+		total += rows
+		if progress != nil {
+			progress(day, rows)
+		}
+	}
+	return total, nil
+}
+
+// TrendFilter 趋势查询条件
+type TrendFilter struct {
+	EntityType  string    // 实体类型：asset、building、floor，默认asset
+	EntityID    uint      // 实体ID，为空时汇总筛选范围内的全部实体
+	StreetID    uint      // 街道ID
+	AssetID     uint      // 资产ID
+	StartDate   time.Time // 开始日期
+	EndDate     time.Time // 结束日期（含）
+	Granularity string    // 粒度：day、week、month，默认day
+}
+
+// TrendPoint 趋势数据点，周、月粒度取周期内每日快照的平均值
+type TrendPoint struct {
+	Period           string  `json:"period"`            // 周期：2026-01-02、2026-W01、2026-01
+	StartDate        string  `json:"start_date"`        // 周期开始日期
+	Days             int     `json:"days"`              // 周期内有快照的天数
+	RoomCount        float64 `json:"room_count"`        // 房间数
+	AvailableRooms   float64 `json:"available_rooms"`   // 可租房间数
+	RentedRooms      float64 `json:"rented_rooms"`      // 已租房间数
+	MaintenanceRooms float64 `json:"maintenance_rooms"` // 维护中房间数
+	RentableArea     float64 `json:"rentable_area"`     // 可租面积(平方米)
+	RentedArea       float64 `json:"rented_area"`       // 已租面积(平方米)
+	OccupancyRate    float64 `json:"occupancy_rate"`    // 出租率(%)
+	AvgRent          float64 `json:"avg_rent"`          // 平均租金(元/平方米/月)
+}
+
+// GetTrends 按日、周、月汇总快照，返回出租率和租金趋势
+func (s *SnapshotService) GetTrends(filter TrendFilter) ([]TrendPoint, error) {
+	if filter.EntityType == "" {
+		filter.EntityType = model.EntityAsset
+	}
+	if filter.Granularity == "" {
+		filter.Granularity = GranularityDay
+	}
+	switch filter.EntityType {
+	case model.EntityAsset, model.EntityBuilding, model.EntityFloor:
+	default:
+		return nil, ErrInvalidTrendQuery
+	}
+	switch filter.Granularity {
+	case GranularityDay, GranularityWeek, GranularityMonth:
+	default:
+		return nil, ErrInvalidTrendQuery
+	}
+	if filter.EndDate.Before(filter.StartDate) || filter.EndDate.Sub(filter.StartDate) > maxTrendDays*24*time.Hour {
+		return nil, ErrInvalidTrendQuery
+	}
+
+	query := s.db.Model(&model.OccupancySnapshot{}).
+		Select("snapshot_date, SUM(room_count) AS room_count, SUM(available_rooms) AS available_rooms,"+
+			" SUM(rented_rooms) AS rented_rooms, SUM(maintenance_rooms) AS maintenance_rooms,"+
+			" SUM(rentable_area) AS rentable_area, SUM(rented_area) AS rented_area, SUM(rented_income) AS rented_income").
+		Where("entity_type = ?", filter.EntityType).
+		Where("snapshot_date BETWEEN ? AND ?", filter.StartDate.Format(dateLayout), filter.EndDate.Format(dateLayout))
+	if filter.EntityID > 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.StreetID > 0 {
+		query = query.Where("street_id = ?", filter.StreetID)
+	}
+	if filter.AssetID > 0 {
+		query = query.Where("asset_id = ?", filter.AssetID)
+	}
+
+	var days []struct {
+		SnapshotDate     time.Time
+		RoomCount        int64
+		AvailableRooms   int64
+		RentedRooms      int64
+		MaintenanceRooms int64
+		RentableArea     float64
+		RentedArea       float64
+		RentedIncome     float64
+	}
+	if err := query.Group("snapshot_date").Order("snapshot_date").Scan(&days).Error; err != nil {
+		return nil, err
+	}
+
+	points := make([]TrendPoint, 0)
+	var income float64
+	flush := func() {
+		if len(points) == 0 {
+			return
+		}
+		p := &points[len(points)-1]
+		n := float64(p.Days)
+		p.OccupancyRate = ratio(p.RentedArea*100, p.RentableArea)
+		p.AvgRent = ratio(income, p.RentedArea)
+		p.RoomCount = round2(p.RoomCount / n)
+		p.AvailableRooms = round2(p.AvailableRooms / n)
+		p.RentedRooms = round2(p.RentedRooms / n)
+		p.MaintenanceRooms = round2(p.MaintenanceRooms / n)
+		p.RentableArea = round2(p.RentableArea / n)
+		p.RentedArea = round2(p.RentedArea / n)
+	}
+
+	for _, day := range days {
+		period, start := trendPeriod(day.SnapshotDate, filter.Granularity)
+		if len(points) == 0 || points[len(points)-1].Period != period {
+			flush()
+			points = append(points, TrendPoint{Period: period, StartDate: start.Format(dateLayout)})
+			income = 0
+		}
+		p := &points[len(points)-1]
+		p.Days++
+		p.RoomCount += float64(day.RoomCount)
+		p.AvailableRooms += float64(day.AvailableRooms)
+		p.RentedRooms += float64(day.RentedRooms)
+		p.MaintenanceRooms += float64(day.MaintenanceRooms)
+		p.RentableArea += day.RentableArea
+		p.RentedArea += day.RentedArea
+		income += day.RentedIncome
+	}
+	flush()
+
+	return points, nil
+}
+
+// trendPeriod 返回日期所属周期的名称和开始日期，周按ISO周计算
+func trendPeriod(day time.Time, granularity string) (string, time.Time) {
+	switch granularity {
+	case GranularityWeek:
+		year, week := day.ISOWeek()
+		offset := (int(day.Weekday()) + 6) % 7
+		return fmt.Sprintf("%d-W%02d", year, week), day.AddDate(0, 0, -offset)
+	case GranularityMonth:
+		return day.Format("2006-01"), time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+	return day.Format(dateLayout), day
+}
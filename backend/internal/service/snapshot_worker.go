@@ -0,0 +1,141 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/pkg/cache"
+	"building-asset-backend/pkg/logger"
+)
+
+const (
+	// snapshotLockPrefix 每日快照分布式锁，多实例部署时只有取得锁的实例生成快照
+	snapshotLockPrefix = "lock:occupancy_snapshot:"
+	snapshotLockTTL    = 30 * time.Minute
+)
+
+// SnapshotWorker 定时生成每日出租情况快照
+// 每次检查时若已到生成时刻且当日快照不存在，取得锁后生成
+type SnapshotWorker struct {
+	service  *SnapshotService
+	hour     int
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSnapshotWorker 创建快照任务
+func NewSnapshotWorker(service *SnapshotService, hour int, interval time.Duration) *SnapshotWorker {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &SnapshotWorker{
+		service:  service,
+		hour:     hour,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start 启动任务
+func (w *SnapshotWorker) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.runOnce(time.Now())
+
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runOnce 检查并生成当日快照
+func (w *SnapshotWorker) runOnce(now time.Time) {
+	if now.Hour() < w.hour {
+		return
+	}
+
+	exists, err := w.service.HasSnapshot(now)
+	if err != nil {
+		logger.Errorf("检查出租快照失败: %v", err)
+		return
+	}
+	if exists {
+		return
+	}
+
+	key := snapshotLockPrefix + now.Format(dateLayout)
+	token := lockToken()
+	locked, err := cache.Lock(key, token, snapshotLockTTL)
+	if err != nil {
+		logger.Errorf("获取出租快照锁失败: %v", err)
+		return
+	}
+	if !locked {
+		return
+	}
+	defer func() {
+		if err := cache.Unlock(key, token); err != nil {
+			logger.Warnf("释放出租快照锁失败: %v", err)
+		}
+	}()
+
+	// 取得锁后再次确认，避免其他实例刚生成完毕后重复生成
+	if exists, err := w.service.HasSnapshot(now); err != nil || exists {
+		return
+	}
+
+	rows, err := w.service.TakeSnapshot(now)
+	if err != nil {
+		logger.Errorf("生成出租快照失败: %v", err)
+		return
+	}
+	logger.Infof("已生成%s出租快照%d条", now.Format(dateLayout), rows)
+}
+
+// Stop 停止任务并等待当前快照完成
+func (w *SnapshotWorker) Stop() {
+	close(w.stop)
+	w.wg.Wait()
+}
+
+// lockToken 生成分布式锁持有者标识
+func lockToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
+
+var snapshotWorker *SnapshotWorker
+
+// StartSnapshotWorker 启动全局快照任务
+func StartSnapshotWorker(cfg config.StatisticsConfig) {
+	if !cfg.SnapshotEnabled || snapshotWorker != nil {
+		return
+	}
+	interval := time.Duration(cfg.SnapshotCheckInterval) * time.Second
+	snapshotWorker = NewSnapshotWorker(NewSnapshotService(), cfg.SnapshotHour, interval)
+	snapshotWorker.Start()
+}
+
+// StopSnapshotWorker 停止全局快照任务
+func StopSnapshotWorker() {
+	if snapshotWorker != nil {
+		snapshotWorker.Stop()
+		snapshotWorker = nil
+	}
+}
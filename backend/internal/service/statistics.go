@@ -0,0 +1,224 @@
+package service
+
+import (
+	"time"
+
+	"building-asset-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AssetStatisticsFilter 资产统计筛选条件
+type AssetStatisticsFilter struct {
+	StreetID uint       // 街道ID
+	AssetID  uint       // 资产ID
+	AssetIDs []uint     // 资产ID列表，用于统计空间查询命中的资产
+	Date     *time.Time // 统计日期：只统计该日及之前登记的记录，出租情况按该日生效的合同计算；为空时按房间当前状态统计
+}
+
+// StatisticsTotals 统计汇总
+type StatisticsTotals struct {
+	AssetCount    int64   `json:"asset_count"`    // 资产数
+	BuildingCount int64   `json:"building_count"` // 建筑数
+	FloorCount    int64   `json:"floor_count"`    // 楼层数
+	TotalArea     float64 `json:"total_area"`     // 资产总面积(平方米)
+	StatisticsItem
+}
+
+// StatisticsItem 按房间汇总的出租指标
+type StatisticsItem struct {
+	RoomCount     int64   `json:"room_count"`     // 房间数
+	RentedRooms   int64   `json:"rented_rooms"`   // 已租房间数
+	RentableArea  float64 `json:"rentable_area"`  // 可租面积(平方米)
+	RentedArea    float64 `json:"rented_area"`    // 已租面积(平方米)
+	VacantArea    float64 `json:"vacant_area"`    // 空置面积(平方米)
+	VacancyRate   float64 `json:"vacancy_rate"`   // 空置率(%)，按面积计算
+	OccupancyRate float64 `json:"occupancy_rate"` // 出租率(%)，按面积计算
+	AvgRent       float64 `json:"avg_rent"`       // 平均租金(元/平方米/月)，按已租房间计算
+}
+
+// StatisticsBreakdown 分组统计
+type StatisticsBreakdown struct {
+	Key  string `json:"key"`            // 分组值
+	Name string `json:"name,omitempty"` // 分组名称（街道名称）
+	StatisticsItem
+}
+
+// AssetStatistics 资产统计结果
+type AssetStatistics struct {
+	Date           string                `json:"date,omitempty"`   // 统计日期
+	Totals         StatisticsTotals      `json:"totals"`           // 汇总
+	ByStreet       []StatisticsBreakdown `json:"by_street"`        // 按街道
+	ByBuildingType []StatisticsBreakdown `json:"by_building_type"` // 按建筑类型
+	ByRoomType     []StatisticsBreakdown `json:"by_room_type"`     // 按房间类型
+	ByDecoration   []StatisticsBreakdown `json:"by_decoration"`    // 按装修情况
+	ByStatus       []StatisticsBreakdown `json:"by_status"`        // 按房间状态
+}
+
+// roomAggregate 房间聚合查询结果
+type roomAggregate struct {
+	GroupKey     string
+	GroupName    string
+	RoomCount    int64
+	RentedRooms  int64
+	RentableArea float64
+	RentedArea   float64
+	RentedIncome float64
+}
+
+// add 累加另一组聚合结果
+func (r *roomAggregate) add(other roomAggregate) {
+	r.RoomCount += other.RoomCount
+	r.RentedRooms += other.RentedRooms
+	r.RentableArea += other.RentableArea
+	r.RentedArea += other.RentedArea
+	r.RentedIncome += other.RentedIncome
+}
+
+func (r roomAggregate) item() StatisticsItem {
+	item := StatisticsItem{
+		RoomCount:     r.RoomCount,
+		RentedRooms:   r.RentedRooms,
+		RentableArea:  round2(r.RentableArea),
+		RentedArea:    round2(r.RentedArea),
+		VacantArea:    round2(r.RentableArea - r.RentedArea),
+		OccupancyRate: ratio(r.RentedArea*100, r.RentableArea),
+		AvgRent:       ratio(r.RentedIncome, r.RentedArea),
+	}
+	if r.RentableArea > 0 {
+		item.VacancyRate = round2(100 - item.OccupancyRate)
+	}
+	return item
+}
+
+// GetAssetStatistics 按房间统计面积、出租率和租金，并按街道、建筑类型、房间类型、装修情况和状态分组
+func (s *AssetService) GetAssetStatistics(filter AssetStatisticsFilter) (*AssetStatistics, error) {
+	stats := &AssetStatistics{}
+	var createdBefore time.Time
+	if filter.Date != nil {
+		day := *filter.Date
+		stats.Date = day.Format(dateLayout)
+		createdBefore = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	}
+
+	// 资产、建筑、楼层数量及资产总面积
+	assets := s.db.Model(&model.Asset{})
+	if filter.StreetID > 0 {
+		assets = assets.Where("street_id = ?", filter.StreetID)
+	}
+	if filter.AssetID > 0 {
+		assets = assets.Where("id = ?", filter.AssetID)
+	}
+	if filter.Date != nil {
+		assets = assets.Where("created_at < ?", createdBefore)
+	}
+
+	var assetTotals struct {
+		AssetCount int64
+		TotalArea  float64
+	}
+	if err := assets.Select("COUNT(*) AS asset_count, COALESCE(SUM(total_area), 0) AS total_area").Scan(&assetTotals).Error; err != nil {
+		return nil, err
+	}
+	stats.Totals.AssetCount = assetTotals.AssetCount
+	stats.Totals.TotalArea = round2(assetTotals.TotalArea)
+
+	buildings := s.filtered(s.db.Model(&model.Building{}).
+		Joins("JOIN t_asset a ON a.id = t_building.asset_id AND a.deleted_at IS NULL"), filter, "t_building", createdBefore)
+	if err := buildings.Count(&stats.Totals.BuildingCount).Error; err != nil {
+		return nil, err
+	}
+
+	floors := s.filtered(s.db.Model(&model.Floor{}).
+		Joins("JOIN t_building b ON b.id = t_floor.building_id AND b.deleted_at IS NULL").
+		Joins("JOIN t_asset a ON a.id = b.asset_id AND a.deleted_at IS NULL"), filter, "t_floor", createdBefore)
+	if err := floors.Count(&stats.Totals.FloorCount).Error; err != nil {
+		return nil, err
+	}
+
+	// 房间出租指标
+	rented := clause.Expr{SQL: "t_room.status = ?", Vars: []interface{}{model.RoomStatusRented}}
+	if filter.Date != nil {
+		rented = clause.Expr{SQL: "t_room.id IN (?)", Vars: []interface{}{activeLeaseRooms(s.db, *filter.Date)}}
+	}
+
+	var totals []roomAggregate
+	none := clause.Expr{SQL: "''"}
+	if err := s.roomAggregates(filter, createdBefore, rented, none, none, &totals); err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		stats.Totals.StatisticsItem = totals[0].item()
+	}
+
+	status := clause.Expr{
+		SQL:  "CASE WHEN ? THEN ? WHEN t_room.status = ? THEN ? ELSE ? END",
+		Vars: []interface{}{rented, model.RoomStatusRented, model.RoomStatusMaintenance, model.RoomStatusMaintenance, model.RoomStatusAvailable},
+	}
+	breakdowns := []struct {
+		target *[]StatisticsBreakdown
+		key    clause.Expr
+		name   clause.Expr
+	}{
+		{&stats.ByStreet, clause.Expr{SQL: "a.street_id"}, clause.Expr{SQL: "COALESCE(o.name, '')"}},
+		{&stats.ByBuildingType, clause.Expr{SQL: "COALESCE(b.building_type, '')"}, none},
+		{&stats.ByRoomType, clause.Expr{SQL: "COALESCE(t_room.room_type, '')"}, none},
+		{&stats.ByDecoration, clause.Expr{SQL: "COALESCE(t_room.decoration, '')"}, none},
+		{&stats.ByStatus, status, none},
+	}
+	for _, breakdown := range breakdowns {
+		var rows []roomAggregate
+		if err := s.roomAggregates(filter, createdBefore, rented, breakdown.key, breakdown.name, &rows); err != nil {
+			return nil, err
+		}
+		*breakdown.target = make([]StatisticsBreakdown, 0, len(rows))
+		for _, row := range rows {
+			*breakdown.target = append(*breakdown.target, StatisticsBreakdown{
+				Key:            row.GroupKey,
+				Name:           row.GroupName,
+				StatisticsItem: row.item(),
+			})
+		}
+	}
+
+	return stats, nil
+}
+
+// roomAggregates 按分组表达式聚合房间，key为空字符串常量时汇总全部房间
+func (s *AssetService) roomAggregates(filter AssetStatisticsFilter, createdBefore time.Time, rented, key, name clause.Expr, dest *[]roomAggregate) error {
+	query := s.db.Model(&model.Room{}).
+		Select("? AS group_key, ? AS group_name, COUNT(*) AS room_count,"+
+			" COALESCE(SUM(CASE WHEN ? THEN 1 ELSE 0 END), 0) AS rented_rooms,"+
+			" COALESCE(SUM(t_room.room_area), 0) AS rentable_area,"+
+			" COALESCE(SUM(CASE WHEN ? THEN t_room.room_area ELSE 0 END), 0) AS rented_area,"+
+			" COALESCE(SUM(CASE WHEN ? THEN t_room.rent_price ELSE 0 END), 0) AS rented_income",
+			key, name, rented, rented, rented).
+		Joins("JOIN t_floor f ON f.id = t_room.floor_id AND f.deleted_at IS NULL").
+		Joins("JOIN t_building b ON b.id = f.building_id AND b.deleted_at IS NULL").
+		Joins("JOIN t_asset a ON a.id = b.asset_id AND a.deleted_at IS NULL").
+		Joins("LEFT JOIN t_organization o ON o.id = a.street_id")
+	query = s.filtered(query, filter, "t_room", createdBefore)
+
+	if key.SQL != "''" {
+		query = query.Group("group_key").Group("group_name").Order("rentable_area DESC")
+	}
+	return query.Scan(dest).Error
+}
+
+// filtered 追加街道、资产和统计日期条件，查询需已关联别名为a的资产表
+func (s *AssetService) filtered(query *gorm.DB, filter AssetStatisticsFilter, table string, createdBefore time.Time) *gorm.DB {
+	if filter.StreetID > 0 {
+		query = query.Where("a.street_id = ?", filter.StreetID)
+	}
+	if filter.AssetID > 0 {
+		query = query.Where("a.id = ?", filter.AssetID)
+	}
+	if len(filter.AssetIDs) > 0 {
+		query = query.Where("a.id IN ?", filter.AssetIDs)
+	}
+	if filter.Date != nil {
+		query = query.Where(table+".created_at < ?", createdBefore)
+	}
+	return query
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/auth"
+	"building-asset-backend/pkg/cache"
+	"building-asset-backend/pkg/logger"
+	"building-asset-backend/pkg/totp"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// mfaTokenTTL 两步验证临时凭证有效期
+	mfaTokenTTL = 5 * time.Minute
+	// mfaMaxAttempts 单个临时凭证允许的验证码错误次数
+	mfaMaxAttempts = 5
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// totpSkew 允许的时间步偏差
+	totpSkew = 1
+)
+
+var (
+	// ErrInvalidTwoFactorCode 两步验证码错误
+	ErrInvalidTwoFactorCode = errors.New("验证码错误")
+	// ErrMFATokenInvalid 两步验证临时凭证无效
+	ErrMFATokenInvalid = errors.New("两步验证凭证无效或已过期，请重新登录")
+)
+
+// LoginResult 密码校验通过后的登录结果
+type LoginResult struct {
+	Tokens           *TokenPair `json:"tokens,omitempty"`
+	MFARequired      bool       `json:"mfa_required"`       // 需要输入两步验证码
+	MFASetupRequired bool       `json:"mfa_setup_required"` // 角色要求两步验证但尚未启用
+	MFAToken         string     `json:"mfa_token,omitempty"`
+}
+
+// TOTPEnrollment 两步验证绑定信息
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// CompleteLogin 密码校验通过后完成登录，需要两步验证时只返回临时凭证
+func (s *AuthService) CompleteLogin(user *model.User) (*LoginResult, error) {
+	switch {
+	case user.TOTPEnabled:
+		token, err := auth.GenerateChallengeToken(user.ID, auth.TokenTypeMFA, mfaTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFARequired: true, MFAToken: token}, nil
+
+	case requiresTwoFactor(user):
+		token, err := auth.GenerateChallengeToken(user.ID, auth.TokenTypeMFASetup, mfaTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFASetupRequired: true, MFAToken: token}, nil
+	}
+
+	tokens, err := s.IssueTokens(user)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// VerifyTwoFactor 使用验证码或恢复码完成两步验证并签发正式token
+// 验证码错误时仍返回用户信息，便于记录登录日志
+func (s *AuthService) VerifyTwoFactor(mfaToken, code, recoveryCode, ip, userAgent string) (*TokenPair, *model.User, error) {
+	claims, err := s.parseMFAToken(mfaToken, auth.TokenTypeMFA)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.loadLoginUser(claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, nil, ErrMFATokenInvalid
+	}
+	if err := checkLoginLock(user.Username, ip); err != nil {
+		return nil, user, err
+	}
+
+	if recoveryCode != "" {
+		err = s.useRecoveryCode(user.ID, recoveryCode)
+	} else {
+		err = s.verifyTOTP(user, code)
+	}
+	if err != nil {
+		s.recordMFAFailure(claims, user.Username, ip, userAgent)
+		return nil, user, err
+	}
+
+	// 临时凭证只能使用一次
+	auth.RevokeToken(claims)
+	clearLoginFailures(user.Username)
+
+	tokens, err := s.IssueTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, user, nil
+}
+
+// BeginTOTPEnrollment 生成两步验证密钥，确认前不生效
+func (s *AuthService) BeginTOTPEnrollment(userID uint) (*TOTPEnrollment, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("已启用两步验证")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(config.Get().App.Name, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment 校验验证码后启用两步验证，返回恢复码（仅展示一次）
+func (s *AuthService) ConfirmTOTPEnrollment(userID uint, code string) ([]string, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("已启用两步验证")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("请先获取两步验证密钥")
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// BeginRequiredTOTPEnrollment 角色要求两步验证的用户在登录过程中获取密钥
+func (s *AuthService) BeginRequiredTOTPEnrollment(mfaToken string) (*TOTPEnrollment, error) {
+	claims, err := s.parseMFAToken(mfaToken, auth.TokenTypeMFASetup)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginTOTPEnrollment(claims.UserID)
+}
+
+// ConfirmRequiredTOTPEnrollment 登录过程中确认启用两步验证，成功后签发正式token
+func (s *AuthService) ConfirmRequiredTOTPEnrollment(mfaToken, code, ip, userAgent string) (*TokenPair, *model.User, []string, error) {
+	claims, err := s.parseMFAToken(mfaToken, auth.TokenTypeMFASetup)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	user, err := s.loadLoginUser(claims.UserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := checkLoginLock(user.Username, ip); err != nil {
+		return nil, nil, nil, err
+	}
+
+	codes, err := s.ConfirmTOTPEnrollment(claims.UserID, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordMFAFailure(claims, user.Username, ip, userAgent)
+		}
+		return nil, nil, nil, err
+	}
+	auth.RevokeToken(claims)
+	clearLoginFailures(user.Username)
+
+	tokens, err := s.IssueTokens(user)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tokens, user, codes, nil
+}
+
+// DisableTOTP 用户关闭两步验证，需要密码和当前验证码
+func (s *AuthService) DisableTOTP(userID uint, password, code string) error {
+	user, err := s.loadLoginUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("未启用两步验证")
+	}
+	if requiresTwoFactor(user) {
+		return errors.New("所属角色要求启用两步验证，无法关闭")
+	}
+	if !user.CheckPassword(password) {
+		return errors.New("密码错误")
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return err
+	}
+
+	return s.ResetTOTP(userID)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, errors.New("未启用两步验证")
+	}
+	if err := s.verifyTOTP(&user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// ResetTOTP 清除用户的两步验证配置（管理员可用于处理设备丢失）
+func (s *AuthService) ResetTOTP(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// verifyTOTP 校验验证码并拒绝重放已使用过的时间步
+func (s *AuthService) verifyTOTP(user *model.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	result := s.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// useRecoveryCode 使用一次性恢复码
+func (s *AuthService) useRecoveryCode(userID uint, code string) error {
+	result := s.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes 生成新的恢复码并替换旧恢复码
+func (s *AuthService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]model.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// parseMFAToken 解析两步验证临时凭证
+func (s *AuthService) parseMFAToken(token, tokenType string) (*auth.Claims, error) {
+	claims, err := auth.ParseChallengeToken(token, tokenType)
+	if err != nil {
+		return nil, ErrMFATokenInvalid
+	}
+	return claims, nil
+}
+
+// recordMFAFailure 记录验证码错误，与密码错误一起计入用户名和IP的登录失败次数，达到上限后锁定账户；
+// 单个临时凭证的错误次数超过上限后该凭证作废
+func (s *AuthService) recordMFAFailure(claims *auth.Claims, username, ip, userAgent string) {
+	s.recordLoginFailure(username, ip, userAgent)
+
+	failures, err := cache.IncrWithExpire(fmt.Sprintf("mfa:fail:%s", claims.ID), mfaTokenTTL)
+	if err != nil {
+		logger.Warnf("记录两步验证失败次数失败: %v", err)
+		return
+	}
+	if failures >= mfaMaxAttempts {
+		auth.RevokeToken(claims)
+		cache.Delete(fmt.Sprintf("mfa:fail:%s", claims.ID))
+	}
+}
+
+// loadLoginUser 加载登录所需的用户信息
+func (s *AuthService) loadLoginUser(userID uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.Preload("Roles").Preload("Organization").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if !user.IsActive() {
+		return nil, errors.New("用户已被禁用")
+	}
+	return &user, nil
+}
+
+// requiresTwoFactor 用户所属角色是否要求两步验证
+func requiresTwoFactor(user *model.User) bool {
+	for _, role := range user.Roles {
+		if role.RequireTwoFactor && role.Status == "active" {
+			return true
+		}
+	}
+	return false
+}
+
+// hashRecoveryCode 计算恢复码哈希（忽略大小写和分隔符）
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,15 +1,35 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"sync"
 
+	"building-asset-backend/internal/audit"
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/datascope"
 	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/auth"
 	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/logger"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrUserLocked 账户已被锁定
+	ErrUserLocked = errors.New("账户已被锁定，请联系管理员解锁")
+)
+
+// dummyPasswordHash 用户不存在时用于比较的密码哈希，使响应时间与用户存在时一致
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
 type UserService struct {
 	db *gorm.DB
 }
@@ -20,12 +40,28 @@ func NewUserService() *UserService {
 	}
 }
 
-// User operations
+// WithContext 返回绑定请求上下文的服务，查询时自动应用数据权限
+func (s *UserService) WithContext(ctx context.Context) *UserService {
+	return &UserService{db: s.db.WithContext(ctx)}
+}
 
-func (s *UserService) GetUsers(page, pageSize int, username, realName, status string, orgID uint) ([]*model.User, int64, error) {
-	var users []*model.User
-	var total int64
+// WithTx 返回在指定事务中执行的服务，用于将多个操作放在同一事务中
+func (s *UserService) WithTx(tx *gorm.DB) *UserService {
+	return &UserService{db: tx}
+}
+
+// checkOrgScope 校验当前用户是否可以管理指定组织的用户
+func (s *UserService) checkOrgScope(orgID uint) error {
+	if scope, ok := datascope.FromContext(s.db.Statement.Context); ok && !scope.AllowsOrg(orgID) {
+		return ErrOutOfDataScope
+	}
+	return nil
+}
 
+// User operations
+
+// userQuery 用户列表查询条件
+func (s *UserService) userQuery(username, realName, status string, orgID uint) *gorm.DB {
 	query := s.db.Model(&model.User{})
 
 	if username != "" {
@@ -40,6 +76,14 @@ func (s *UserService) GetUsers(page, pageSize int, username, realName, status st
 	if orgID > 0 {
 		query = query.Where("org_id = ?", orgID)
 	}
+	return query
+}
+
+func (s *UserService) GetUsers(page, pageSize int, username, realName, status string, orgID uint) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+
+	query := s.userQuery(username, realName, status, orgID)
 
 	err := query.Count(&total).Error
 	if err != nil {
@@ -60,6 +104,11 @@ func (s *UserService) GetUsers(page, pageSize int, username, realName, status st
 	return users, total, nil
 }
 
+// ExportUsers 按列表筛选条件分批读取全部用户
+func (s *UserService) ExportUsers(username, realName, status string, orgID uint, maxRows int, fn func([]*model.User) error) error {
+	return exportRows(s.userQuery(username, realName, status, orgID), maxRows, fn, "Roles", "Organization")
+}
+
 func (s *UserService) GetUserByID(id uint) (*model.User, error) {
 	var user model.User
 	err := s.db.Preload("Roles").Preload("Organization").First(&user, id).Error
@@ -80,19 +129,21 @@ func (s *UserService) GetUserByUsername(username string) (*model.User, error) {
 }
 
 func (s *UserService) CreateUser(user *model.User) (*model.User, error) {
+	if err := s.checkOrgScope(user.OrgID); err != nil {
+		return nil, err
+	}
+
 	// 检查用户名是否已存在
 	var count int64
-	s.db.Model(&model.User{}).Where("username = ?", user.Username).Count(&count)
+	datascope.Skip(s.db).Model(&model.User{}).Where("username = ?", user.Username).Count(&count)
 	if count > 0 {
 		return nil, errors.New("用户名已存在")
 	}
 
-	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	if err != nil {
+	// 校验密码策略并加密密码
+	if err := s.setPassword(s.db, user, user.Password, false); err != nil {
 		return nil, err
 	}
-	user.Password = string(hashedPassword)
 
 	// 设置默认值
 	if user.Status == "" {
@@ -102,6 +153,9 @@ func (s *UserService) CreateUser(user *model.User) (*model.User, error) {
 	if err := s.db.Create(user).Error; err != nil {
 		return nil, err
 	}
+	if err := s.recordPasswordHistory(s.db, user.ID, user.Password); err != nil {
+		return nil, err
+	}
 
 	// 关联角色
 	if len(user.Roles) > 0 {
@@ -120,34 +174,72 @@ func (s *UserService) CreateUser(user *model.User) (*model.User, error) {
 
 func (s *UserService) UpdateUser(id uint, updates *model.User) (*model.User, error) {
 	var user model.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.db.Preload("Roles").First(&user, id).Error; err != nil {
 		return nil, err
 	}
 
+	if updates.OrgID > 0 && updates.OrgID != user.OrgID {
+		if err := s.checkOrgScope(updates.OrgID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查用户名是否重复
 	if updates.Username != "" && updates.Username != user.Username {
 		var count int64
-		s.db.Model(&model.User{}).Where("username = ? AND id != ?", updates.Username, id).Count(&count)
+		datascope.Skip(s.db).Model(&model.User{}).Where("username = ? AND id != ?", updates.Username, id).Count(&count)
 		if count > 0 {
 			return nil, errors.New("用户名已存在")
 		}
 	}
 
 	// 更新用户信息（不包括密码）
+	oldStatus := user.Status
+	oldOrgID := user.OrgID
+	before := userSnapshot(&user)
 	updates.Password = user.Password
-	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 角色关联单独维护，避免随用户信息一并保存
+		if err := tx.Model(&user).Omit("Roles").Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// 更新角色关联
+		if updates.Roles != nil {
+			var roleIDs []uint
+			for _, role := range updates.Roles {
+				roleIDs = append(roleIDs, role.ID)
+			}
+			var roles []model.Role
+			if len(roleIDs) > 0 {
+				if err := tx.Find(&roles, roleIDs).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&user).Association("Roles").Replace(roles); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Preload("Roles").First(&user, id).Error; err != nil {
+			return err
+		}
+		return audit.RecordUpdate(tx, model.EntityUser, id, before, userSnapshot(&user))
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// 更新角色关联
-	if updates.Roles != nil {
-		var roleIDs []uint
-		for _, role := range updates.Roles {
-			roleIDs = append(roleIDs, role.ID)
+	// 角色、状态或所属组织变化后刷新权限缓存
+	if updates.Roles != nil || (updates.Status != "" && updates.Status != oldStatus) || (updates.OrgID > 0 && updates.OrgID != oldOrgID) {
+		InvalidateUserPermissions(id)
+	}
+
+	// 禁用用户时吊销其全部会话
+	if updates.Status != "" && updates.Status != "active" && oldStatus == "active" {
+		if err := s.RevokeUserSessions(id); err != nil {
+			logger.Warnf("吊销用户会话失败: %v", err)
 		}
-		var roles []model.Role
-		s.db.Find(&roles, roleIDs)
-		s.db.Model(&user).Association("Roles").Replace(roles)
 	}
 
 	// 重新加载用户信息
@@ -159,7 +251,7 @@ func (s *UserService) UpdateUser(id uint, updates *model.User) (*model.User, err
 func (s *UserService) DeleteUser(id uint) error {
 	// 检查是否为管理员
 	var user model.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.db.Preload("Roles").First(&user, id).Error; err != nil {
 		return err
 	}
 
@@ -167,34 +259,95 @@ func (s *UserService) DeleteUser(id uint) error {
 		return errors.New("不能删除管理员账户")
 	}
 
-	// 删除用户角色关联
-	s.db.Model(&user).Association("Roles").Clear()
+	before := userSnapshot(&user)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 删除用户角色关联
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.User{}, id).Error; err != nil {
+			return err
+		}
+		return audit.RecordDelete(tx, model.EntityUser, id, before)
+	})
+	if err != nil {
+		return err
+	}
+	InvalidateUserPermissions(id)
 
-	return s.db.Delete(&model.User{}, id).Error
+	if err := s.RevokeUserSessions(id); err != nil {
+		logger.Warnf("吊销用户会话失败: %v", err)
+	}
+	return nil
 }
 
+// ResetPassword 管理员重置密码，用户下次登录时必须修改
 func (s *UserService) ResetPassword(id uint, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	var user model.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.setPassword(tx, &user, password, true)
+	}); err != nil {
 		return err
 	}
 
-	return s.db.Model(&model.User{}).Where("id = ?", id).Update("password", string(hashedPassword)).Error
+	// 密码重置后旧会话全部失效
+	return s.RevokeUserSessions(id)
 }
 
+// UnlockUser 解锁用户：恢复锁定状态并清除登录失败记录
+func (s *UserService) UnlockUser(id uint) error {
+	var user model.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return err
+	}
+
+	if user.Status == "locked" {
+		if err := updateWithHistory(s.db, model.EntityUser, id, &user, map[string]interface{}{"status": "active"}, nil); err != nil {
+			return err
+		}
+		InvalidateUserPermissions(id)
+	}
+
+	clearLoginLock(user.Username)
+	return nil
+}
+
+// RevokeUserSessions 吊销用户的全部会话
+func (s *UserService) RevokeUserSessions(id uint) error {
+	var count int64
+	if err := s.db.Unscoped().Model(&model.User{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return auth.RevokeUserTokens(id)
+}
+
+// ValidateCredentials 校验用户名和密码，密码正确后才返回账户锁定、禁用等状态，
+// 避免不知道密码的人据此判断用户名是否存在及账户状态
 func (s *UserService) ValidateCredentials(username, password string) (*model.User, error) {
 	user, err := s.GetUserByUsername(username)
 	if err != nil {
-		return nil, errors.New("用户名或密码错误")
-	}
-
-	if user.Status != "active" {
-		return nil, errors.New("用户已被禁用")
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("用户名或密码错误")
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Status == "locked" {
+		return nil, ErrUserLocked
+	}
+	if user.Status != "active" {
+		return nil, errors.New("用户已被禁用")
 	}
 
 	user.Password = ""
@@ -263,6 +416,7 @@ func (s *UserService) CreateOrganization(org *model.Organization) (*model.Organi
 	if err := s.db.Create(org).Error; err != nil {
 		return nil, err
 	}
+	InvalidateOrgTree()
 
 	return org, nil
 }
@@ -295,6 +449,7 @@ func (s *UserService) UpdateOrganization(id uint, updates *model.Organization) (
 	if err := s.db.Model(&org).Updates(updates).Error; err != nil {
 		return nil, err
 	}
+	InvalidateOrgTree()
 
 	return &org, nil
 }
@@ -313,7 +468,11 @@ func (s *UserService) DeleteOrganization(id uint) error {
 		return errors.New("该组织下存在用户，无法删除")
 	}
 
-	return s.db.Delete(&model.Organization{}, id).Error
+	if err := s.db.Delete(&model.Organization{}, id).Error; err != nil {
+		return err
+	}
+	InvalidateOrgTree()
+	return nil
 }
 
 // Initialize default data
@@ -334,10 +493,12 @@ func (s *UserService) InitializeDefaultData() error {
 			return err
 		}
 
-		// 创建默认管理员
+		// 创建默认管理员，生产环境不使用默认密码，需通过 user create 命令创建
 		var userCount int64
 		s.db.Model(&model.User{}).Count(&userCount)
-		if userCount == 0 {
+		if userCount == 0 && config.IsProduction() {
+			logger.Warn("未创建默认管理员，请使用 user create -role admin 创建管理员账户")
+		} else if userCount == 0 {
 			hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
 			admin := &model.User{
 				Username: "admin",
@@ -347,6 +508,8 @@ func (s *UserService) InitializeDefaultData() error {
 				Phone:    "13800138000",
 				Status:   "active",
 				OrgID:    defaultOrg.ID,
+				// 默认密码，首次登录必须修改
+				MustChangePassword: true,
 			}
 			if err := s.db.Create(admin).Error; err != nil {
 				return err
@@ -0,0 +1,548 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/model"
+	"building-asset-backend/pkg/auth"
+	"building-asset-backend/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidWorkOrder 工单内容校验失败
+	ErrInvalidWorkOrder = errors.New("工单信息无效")
+	// ErrWorkOrderTransition 工单当前状态不允许该操作
+	ErrWorkOrderTransition = errors.New("工单当前状态不允许该操作")
+)
+
+// workOrderError 工单校验错误，匹配 ErrInvalidWorkOrder 并携带具体原因
+type workOrderError string
+
+func (e workOrderError) Error() string {
+	return string(e)
+}
+
+func (e workOrderError) Is(target error) bool {
+	return target == ErrInvalidWorkOrder
+}
+
+// defaultSLAHours 未配置时限的优先级使用的时限(小时)
+const defaultSLAHours = 72
+
+// maxOrderNoAttempts 工单编号冲突时最多尝试的次数
+const maxOrderNoAttempts = 5
+
+// roomMaintenanceTx 涉及房间维护的事务使用读已提交隔离级别，锁定房间后统计的其他工单状态为最新提交的状态，
+// 而不是事务开始时的快照
+var roomMaintenanceTx = &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+
+// workOrderTransitions 允许的状态变更，派单通过 AssignWorkOrder 完成
+var workOrderTransitions = map[string][]string{
+	model.WorkOrderStatusOpen:       {model.WorkOrderStatusClosed},
+	model.WorkOrderStatusAssigned:   {model.WorkOrderStatusInProgress, model.WorkOrderStatusClosed},
+	model.WorkOrderStatusInProgress: {model.WorkOrderStatusResolved, model.WorkOrderStatusClosed},
+	model.WorkOrderStatusResolved:   {model.WorkOrderStatusInProgress, model.WorkOrderStatusClosed},
+}
+
+// WorkOrderFilter 工单列表筛选条件
+type WorkOrderFilter struct {
+	Keyword    string // 工单编号或标题
+	Status     string
+	Priority   string
+	Category   string
+	BuildingID uint
+	FloorID    uint
+	RoomID     uint
+	AssigneeID uint
+	ReporterID uint
+	Overdue    *bool // 是否超出SLA
+}
+
+type WorkOrderService struct {
+	db *gorm.DB
+}
+
+func NewWorkOrderService() *WorkOrderService {
+	return &WorkOrderService{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回绑定请求上下文的服务，查询时自动应用数据权限
+func (s *WorkOrderService) WithContext(ctx context.Context) *WorkOrderService {
+	return &WorkOrderService{db: s.db.WithContext(ctx)}
+}
+
+// WithTx 返回在指定事务中执行的服务，用于将多个操作放在同一事务中
+func (s *WorkOrderService) WithTx(tx *gorm.DB) *WorkOrderService {
+	return &WorkOrderService{db: tx}
+}
+
+func (s *WorkOrderService) GetWorkOrders(page, pageSize int, filter WorkOrderFilter) ([]*model.WorkOrder, int64, error) {
+	var orders []*model.WorkOrder
+	var total int64
+
+	query := s.db.Model(&model.WorkOrder{})
+	if filter.Keyword != "" {
+		query = query.Where("order_no LIKE ? OR title LIKE ?", "%"+filter.Keyword+"%", "%"+filter.Keyword+"%")
+	}
+	for column, value := range map[string]string{
+		"status": filter.Status, "priority": filter.Priority, "category": filter.Category,
+	} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for column, value := range map[string]uint{
+		"building_id": filter.BuildingID, "floor_id": filter.FloorID, "room_id": filter.RoomID,
+		"assignee_id": filter.AssigneeID, "reporter_id": filter.ReporterID,
+	} {
+		if value > 0 {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if filter.Overdue != nil {
+		// 与 model.WorkOrder.IsOverdue 一致：按解决时间、关闭时间或当前时间与截止时间比较
+		overdue := "COALESCE(resolved_at, closed_at, ?) > due_at"
+		if *filter.Overdue {
+			query = query.Where(overdue, time.Now())
+		} else {
+			query = query.Where("NOT ("+overdue+")", time.Now())
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Building").Preload("Floor").Preload("Room").Preload("Assignee").
+		Order("id DESC").Offset(offset).Limit(pageSize).Find(&orders).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	for _, order := range orders {
+		order.Overdue = order.IsOverdue(now)
+	}
+	return orders, total, nil
+}
+
+func (s *WorkOrderService) GetWorkOrderByID(id uint) (*model.WorkOrder, error) {
+	var order model.WorkOrder
+	err := s.db.Preload("Building").Preload("Floor").Preload("Room").Preload("Assignee").
+		Preload("Comments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	order.Overdue = order.IsOverdue(time.Now())
+	return &order, nil
+}
+
+// CreateWorkOrder 创建工单，位置按最细一级（房间、楼层、建筑）补全上级
+// 指定处理人时直接进入已派单状态；room_maintenance为true时将房间设为维护中
+func (s *WorkOrderService) CreateWorkOrder(order *model.WorkOrder) (*model.WorkOrder, error) {
+	if order.Title == "" {
+		return nil, workOrderError("工单标题不能为空")
+	}
+	if order.Category == "" {
+		order.Category = "other"
+	}
+	if !model.IsValidWorkOrderCategory(order.Category) {
+		return nil, workOrderError("无效的工单分类")
+	}
+	if order.Priority == "" {
+		order.Priority = model.WorkOrderPriorityMedium
+	}
+	if !isValidWorkOrderPriority(order.Priority) {
+		return nil, workOrderError("无效的工单优先级")
+	}
+	if err := s.resolveLocation(order); err != nil {
+		return nil, err
+	}
+	if order.RoomMaintenance && order.RoomID == nil {
+		return nil, workOrderError("只有房间工单可以将房间设为维护中")
+	}
+
+	now := time.Now()
+	if user, ok := auth.CurrentUserFromContext(s.db.Statement.Context); ok && order.ReporterID == 0 {
+		order.ReporterID = user.ID
+		if order.ReporterName == "" {
+			order.ReporterName = user.Name
+		}
+	}
+	order.Status = model.WorkOrderStatusOpen
+	order.DueAt = now.Add(slaDuration(order.Priority))
+	order.AssignedAt, order.StartedAt, order.ResolvedAt, order.ClosedAt = nil, nil, nil, nil
+	if order.AssigneeID != nil {
+		if err := s.checkAssignee(*order.AssigneeID); err != nil {
+			return nil, err
+		}
+		order.Status = model.WorkOrderStatusAssigned
+		order.AssignedAt = &now
+	}
+
+	// 工单编号由时间和随机数组成，与已有编号冲突时换一个编号重新创建
+	var err error
+	for attempt := 1; ; attempt++ {
+		order.OrderNo = fmt.Sprintf("WO%s%04d", now.Format("20060102150405"), rand.IntN(10000))
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if order.RoomMaintenance {
+				previous, err := enterRoomMaintenance(tx, *order.RoomID)
+				if err != nil {
+					return err
+				}
+				order.PreviousRoomStatus = previous
+			}
+			if err := tx.Omit("Building", "Floor", "Room", "Assignee", "Comments").Create(order).Error; err != nil {
+				return err
+			}
+			return addWorkOrderComment(tx, order.ID, "创建工单", order.Status)
+		}, roomMaintenanceTx)
+		if attempt == maxOrderNoAttempts || !database.IsDuplicateKey(err, "order_no") {
+			break
+		}
+		order.ID = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetWorkOrderByID(order.ID)
+}
+
+// UpdateWorkOrder 修改工单标题、描述、分类、优先级和报修人信息，修改优先级时按创建时间重新计算截止时间
+// 位置、状态和处理人不通过该方法修改
+func (s *WorkOrderService) UpdateWorkOrder(id uint, updates *model.WorkOrder) (*model.WorkOrder, error) {
+	var order model.WorkOrder
+	if err := s.db.First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	if order.Status == model.WorkOrderStatusClosed {
+		return nil, ErrWorkOrderTransition
+	}
+
+	changes := map[string]interface{}{}
+	if updates.Title != "" {
+		changes["title"] = updates.Title
+	}
+	if updates.Description != "" {
+		changes["description"] = updates.Description
+	}
+	if updates.Category != "" {
+		if !model.IsValidWorkOrderCategory(updates.Category) {
+			return nil, workOrderError("无效的工单分类")
+		}
+		changes["category"] = updates.Category
+	}
+	if updates.Priority != "" && updates.Priority != order.Priority {
+		if !isValidWorkOrderPriority(updates.Priority) {
+			return nil, workOrderError("无效的工单优先级")
+		}
+		changes["priority"] = updates.Priority
+		changes["due_at"] = order.CreatedAt.Add(slaDuration(updates.Priority))
+	}
+	if updates.ReporterName != "" {
+		changes["reporter_name"] = updates.ReporterName
+	}
+	if updates.ReporterPhone != "" {
+		changes["reporter_phone"] = updates.ReporterPhone
+	}
+
+	if len(changes) > 0 {
+		if err := s.db.Model(&order).Updates(changes).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.GetWorkOrderByID(id)
+}
+
+// AssignWorkOrder 派单或改派，待处理的工单进入已派单状态，处理中的工单保持状态
+func (s *WorkOrderService) AssignWorkOrder(id, assigneeID uint, comment string) (*model.WorkOrder, error) {
+	if err := s.checkAssignee(assigneeID); err != nil {
+		return nil, err
+	}
+	if comment == "" {
+		comment = "派单"
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockWorkOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		switch order.Status {
+		case model.WorkOrderStatusOpen, model.WorkOrderStatusAssigned, model.WorkOrderStatusInProgress:
+		default:
+			return ErrWorkOrderTransition
+		}
+
+		changes := map[string]interface{}{"assignee_id": assigneeID, "assigned_at": time.Now()}
+		if order.Status == model.WorkOrderStatusOpen {
+			changes["status"] = model.WorkOrderStatusAssigned
+		}
+		if err := updateWorkOrderStatus(tx, order, changes); err != nil {
+			return err
+		}
+		return addWorkOrderComment(tx, id, comment, order.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetWorkOrderByID(id)
+}
+
+// ChangeWorkOrderStatus 变更工单状态并记录说明，解决时说明作为处理结果
+// 关闭设置了房间维护的工单时恢复房间状态
+func (s *WorkOrderService) ChangeWorkOrderStatus(id uint, status, comment string) (*model.WorkOrder, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockWorkOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		allowed := false
+		for _, next := range workOrderTransitions[order.Status] {
+			if next == status {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrWorkOrderTransition
+		}
+
+		now := time.Now()
+		changes := map[string]interface{}{"status": status}
+		switch status {
+		case model.WorkOrderStatusInProgress:
+			if order.StartedAt == nil {
+				changes["started_at"] = now
+			}
+			// 重新处理时清除上次的解决时间
+			changes["resolved_at"] = nil
+		case model.WorkOrderStatusResolved:
+			changes["resolved_at"] = now
+			changes["resolution"] = comment
+		case model.WorkOrderStatusClosed:
+			changes["closed_at"] = now
+		}
+
+		if err := updateWorkOrderStatus(tx, order, changes); err != nil {
+			return err
+		}
+		if status == model.WorkOrderStatusClosed {
+			if err := leaveRoomMaintenance(tx, order); err != nil {
+				return err
+			}
+		}
+		return addWorkOrderComment(tx, id, comment, status)
+	}, roomMaintenanceTx)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetWorkOrderByID(id)
+}
+
+// AddWorkOrderComment 添加工单评论
+func (s *WorkOrderService) AddWorkOrderComment(id uint, content string) (*model.WorkOrderComment, error) {
+	if content == "" {
+		return nil, workOrderError("评论内容不能为空")
+	}
+	if err := s.db.Select("id").First(&model.WorkOrder{}, id).Error; err != nil {
+		return nil, err
+	}
+
+	comment := newWorkOrderComment(s.db, id, content, "")
+	if err := s.db.Create(comment).Error; err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteWorkOrder 删除工单，只能删除已关闭的工单
+func (s *WorkOrderService) DeleteWorkOrder(id uint) error {
+	var order model.WorkOrder
+	if err := s.db.First(&order, id).Error; err != nil {
+		return err
+	}
+	if order.Status != model.WorkOrderStatusClosed {
+		return workOrderError("只能删除已关闭的工单")
+	}
+	return s.db.Delete(&order).Error
+}
+
+// resolveLocation 按房间、楼层、建筑补全工单位置，位置须存在且在数据权限范围内
+func (s *WorkOrderService) resolveLocation(order *model.WorkOrder) error {
+	if order.RoomID != nil {
+		var room model.Room
+		if err := s.db.Select("id", "floor_id").First(&room, *order.RoomID).Error; err != nil {
+			return workOrderError("房间不存在")
+		}
+		order.FloorID = &room.FloorID
+	}
+	if order.FloorID != nil {
+		var floor model.Floor
+		if err := s.db.Select("id", "building_id").First(&floor, *order.FloorID).Error; err != nil {
+			return workOrderError("楼层不存在")
+		}
+		order.BuildingID = floor.BuildingID
+	}
+	if order.BuildingID == 0 {
+		return workOrderError("请选择工单位置")
+	}
+	if err := s.db.Select("id").First(&model.Building{}, order.BuildingID).Error; err != nil {
+		return workOrderError("建筑不存在")
+	}
+	return nil
+}
+
+// checkAssignee 处理人须为正常状态的用户，可以属于其他组织
+func (s *WorkOrderService) checkAssignee(userID uint) error {
+	var user model.User
+	if err := datascope.Skip(s.db).Select("id", "status").First(&user, userID).Error; err != nil {
+		return workOrderError("处理人不存在")
+	}
+	if user.Status != "active" {
+		return workOrderError("处理人账户不可用")
+	}
+	return nil
+}
+
+// lockWorkOrder 在事务中读取并锁定工单，并发的状态变更按顺序执行
+func lockWorkOrder(tx *gorm.DB, id uint) (*model.WorkOrder, error) {
+	var order model.WorkOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// updateWorkOrderStatus 按读取时的状态更新工单，状态已被修改时返回 ErrWorkOrderTransition
+func updateWorkOrderStatus(tx *gorm.DB, order *model.WorkOrder, changes map[string]interface{}) error {
+	result := tx.Model(order).Where("status = ?", order.Status).Updates(changes)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWorkOrderTransition
+	}
+	return nil
+}
+
+// lockRoom 在事务中读取并锁定房间，同一房间的维护开始和结束按顺序执行
+func lockRoom(tx *gorm.DB, roomID uint) (*model.Room, error) {
+	var room model.Room
+	err := datascope.Skip(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "floor_id", "status").First(&room, roomID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// enterRoomMaintenance 将房间设为维护中，返回维护前的房间状态
+// 房间已因其他未关闭的工单维护中时沿用该工单记录的状态，以便最后一个工单关闭时正确恢复
+func enterRoomMaintenance(tx *gorm.DB, roomID uint) (string, error) {
+	room, err := lockRoom(tx, roomID)
+	if err != nil {
+		return "", err
+	}
+	if room.Status == model.RoomStatusMaintenance {
+		var other model.WorkOrder
+		err := datascope.Skip(tx).Select("previous_room_status").
+			Where("room_id = ? AND room_maintenance = ? AND status <> ?", roomID, true, model.WorkOrderStatusClosed).
+			Order("id").First(&other).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.RoomStatusMaintenance, nil
+		}
+		return other.PreviousRoomStatus, err
+	}
+
+	err = datascope.Skip(tx).Model(&model.Room{}).Where("id = ?", roomID).
+		Update("status", model.RoomStatusMaintenance).Error
+	if err != nil {
+		return "", err
+	}
+	return room.Status, refreshRollups(tx, []uint{room.FloorID}, nil, nil)
+}
+
+// leaveRoomMaintenance 关闭工单后恢复房间状态
+// 仍有其他未关闭的工单维护该房间、维护前已处于维护中或房间已被手工解除维护时不处理；
+// 维护期间合同可能生效或到期，已租、可租状态按当前合同重新确定
+// 先锁定房间再统计其他工单，在 roomMaintenanceTx 事务中同时关闭同一房间的多个工单时，后执行的事务能看到先关闭的工单
+func leaveRoomMaintenance(tx *gorm.DB, order *model.WorkOrder) error {
+	if !order.RoomMaintenance || order.RoomID == nil || order.PreviousRoomStatus == model.RoomStatusMaintenance {
+		return nil
+	}
+
+	room, err := lockRoom(tx, *order.RoomID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var count int64
+	err = datascope.Skip(tx).Model(&model.WorkOrder{}).
+		Where("room_id = ? AND room_maintenance = ? AND status <> ? AND id <> ?",
+			*order.RoomID, true, model.WorkOrderStatusClosed, order.ID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	if room.Status != model.RoomStatusMaintenance {
+		return nil
+	}
+
+	status, err := roomLeaseStatus(tx, room.ID)
+	if err != nil {
+		return err
+	}
+	if err := datascope.Skip(tx).Model(&model.Room{}).Where("id = ?", room.ID).Update("status", status).Error; err != nil {
+		return err
+	}
+	return refreshRollups(tx, []uint{room.FloorID}, nil, nil)
+}
+
+// addWorkOrderComment 记录工单状态变更
+func addWorkOrderComment(tx *gorm.DB, orderID uint, content, status string) error {
+	return tx.Create(newWorkOrderComment(tx, orderID, content, status)).Error
+}
+
+func newWorkOrderComment(db *gorm.DB, orderID uint, content, status string) *model.WorkOrderComment {
+	comment := &model.WorkOrderComment{WorkOrderID: orderID, Content: content, Status: status}
+	if user, ok := auth.CurrentUserFromContext(db.Statement.Context); ok {
+		comment.UserID = user.ID
+		comment.Username = user.Username
+	}
+	return comment
+}
+
+// slaDuration 按优先级返回解决时限
+func slaDuration(priority string) time.Duration {
+	hours := config.Get().WorkOrder.SLAHours[priority]
+	if hours <= 0 {
+		hours = defaultSLAHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func isValidWorkOrderPriority(priority string) bool {
+	switch priority {
+	case model.WorkOrderPriorityLow, model.WorkOrderPriorityMedium, model.WorkOrderPriorityHigh, model.WorkOrderPriorityUrgent:
+		return true
+	}
+	return false
+}
@@ -1,20 +1,36 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"building-asset-backend/internal/audit"
 	"building-asset-backend/internal/config"
-	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/datascope"
+	"building-asset-backend/internal/metrics"
 	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/cache"
 	"building-asset-backend/pkg/database"
 	"building-asset-backend/pkg/logger"
+	"building-asset-backend/pkg/storage"
 	"building-asset-backend/router"
 )
 
 func main() {
+	configFile := flag.String("config", "config/config.yaml", "path to the configuration file")
+	flag.Usage = usage
+	flag.Parse()
+
 	// Initialize configuration
-	if err := config.Load("config/config.yaml"); err != nil {
+	if err := config.Load(*configFile); err != nil {
 		log.Fatalf("Failed to initialize configuration: %v", err)
 	}
 
@@ -24,59 +40,185 @@ func main() {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 
-	// Initialize database
-	if err := database.Init(&cfg.Database.MySQL); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Run the server unless a command is given
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"serve"}
 	}
+	err := runCommand(args)
+	logger.Sync()
+	if err != nil {
+		log.Fatalf("%s failed: %v", args[0], err)
+	}
+}
 
-	// Auto migrate database
-	if err := migrateDatabase(); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+// serve runs the HTTP server and background workers until SIGINT or SIGTERM,
+// then drains in-flight requests, stops the workers and closes connections in that order
+func serve() error {
+	cfg := config.Get()
+	if err := setup(); err != nil {
+		return err
 	}
+	defer closeConnections()
 
-	// Initialize default data
-	if err := initializeDefaultData(); err != nil {
-		log.Fatalf("Failed to initialize default data: %v", err)
+	// Initialize attachment file storage
+	if err := storage.Init(&cfg.Upload); err != nil {
+		return fmt.Errorf("failed to initialize file storage: %w", err)
+	}
+
+	// Start asynchronous operation log writer, flushed after the workers stop
+	service.StartOperationLogWriter(cfg.OperationLog)
+	defer service.StopOperationLogWriter()
+
+	// Start room status sync worker for lease start/end dates
+	service.StartRoomStatusWorker(cfg.Lease)
+	defer service.StopRoomStatusWorker()
+
+	// Start daily occupancy snapshot worker
+	service.StartSnapshotWorker(cfg.Statistics)
+	defer service.StopSnapshotWorker()
+
+	// Initialize metrics, served on their own address when configured
+	var metricsSrv *http.Server
+	if cfg.Metrics.Enabled {
+		var err error
+		if metricsSrv, err = initMetrics(&cfg.Metrics); err != nil {
+			return fmt.Errorf("failed to initialize metrics: %w", err)
+		}
 	}
 
 	// Initialize router
-	r := router.InitRouter()
+	r, err := router.InitRouter()
+	if err != nil {
+		return err
+	}
 
 	// Start server
 	port := fmt.Sprintf("%d", cfg.App.Port)
 	if port == "0" {
 		port = "8080"
 	}
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Duration(cfg.App.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(cfg.App.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(cfg.App.IdleTimeout) * time.Second,
+	}
 
-	logger.Info(fmt.Sprintf("Server starting on port %s", port))
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("Server starting on port %s", port))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal terminates immediately
+	stop()
+
+	logger.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.App.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warnf("Server forced to shutdown, in-flight requests aborted: %v", err)
+		srv.Close()
 	}
+	// Metrics stay available while requests drain
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			metricsSrv.Close()
+		}
+	}
+
+	logger.Info("Server stopped")
+	return nil
 }
 
-// migrateDatabase auto migrates all database models
-func migrateDatabase() error {
-	db := database.GetDB()
-
-	// Migrate all models
-	return db.AutoMigrate(
-		// User management models
-		&model.User{},
-		&model.Organization{},
-		&model.Role{},
-		&model.Permission{},
-		&model.Menu{},
-
-		// Asset management models
-		&model.Asset{},
-		&model.Building{},
-		&model.Floor{},
-		&model.Room{},
-
-		// Log models
-		&model.OperationLog{},
-		&model.LoginLog{},
-	)
+// initMetrics registers the GORM plugin and pool collectors and, when a listen address is
+// configured, starts the metrics server on it. Without one, the router serves /metrics and a token is required.
+func initMetrics(cfg *config.MetricsConfig) (*http.Server, error) {
+	if cfg.Listen == "" && cfg.Token == "" {
+		return nil, errors.New("metrics.token or metrics.listen is required")
+	}
+	if err := database.GetDB().Use(metrics.Plugin{}); err != nil {
+		return nil, err
+	}
+	if err := metrics.Register(database.GetDB(), cache.GetClient()); err != nil {
+		return nil, err
+	}
+	if cfg.Listen == "" {
+		return nil, nil
+	}
+
+	// Listen before serving so a busy address fails startup
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(cfg.Token))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		logger.Info(fmt.Sprintf("Metrics server starting on %s", cfg.Listen))
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Metrics server stopped: %v", err)
+		}
+	}()
+	return srv, nil
+}
+
+// setup connects to the database and redis, applies migrations and creates default data
+func setup() error {
+	cfg := config.Get()
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	// Initialize database
+	if err := database.Init(&cfg.Database.MySQL); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Register row-level data scope plugin
+	if err := database.GetDB().Use(datascope.Plugin{}); err != nil {
+		return fmt.Errorf("failed to register data scope plugin: %w", err)
+	}
+
+	// Register audit field plugin
+	if err := database.GetDB().Use(audit.Plugin{}); err != nil {
+		return fmt.Errorf("failed to register audit plugin: %w", err)
+	}
+
+	// Initialize redis
+	if err := cache.Init(&cfg.Redis); err != nil {
+		return fmt.Errorf("failed to initialize redis: %w", err)
+	}
+
+	// Apply schema migrations (production only checks that none are pending)
+	if err := migrateDatabase(); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	// Initialize default data
+	return initializeDefaultData()
+}
+
+// closeConnections closes MySQL and then Redis
+func closeConnections() {
+	if err := database.Close(); err != nil {
+		logger.Warnf("Failed to close database: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		logger.Warnf("Failed to close redis: %v", err)
+	}
 }
 
 // initializeDefaultData creates default data
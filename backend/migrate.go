@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/migrations"
+	"building-asset-backend/pkg/database"
+	"building-asset-backend/pkg/logger"
+	"building-asset-backend/pkg/migrate"
+)
+
+// migrateDatabase applies pending schema migrations on startup.
+// Production refuses to start with pending migrations; run `migrate up` as a release step instead.
+func migrateDatabase() error {
+	migrator, err := migrations.New(database.GetDB())
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if config.IsProduction() {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s) starting at %s_%s, run `migrate up` first",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+
+	applied, err := migrator.Up(ctx, 0)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		logger.Info(fmt.Sprintf("Applied %d migration(s)", len(applied)))
+	}
+	return nil
+}
+
+// runMigrate runs a schema migration command
+//
+//	migrate up [-steps N]
+//	migrate down [-steps N]
+//	migrate status
+//	migrate create [-dir migrations] <name>
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down|status|create")
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := fs.Int("steps", 0, "number of migrations to apply or roll back (up: all, down: 1)")
+	dir := fs.String("dir", "migrations", "directory for new migration files")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	// Creating migration files does not need a database connection
+	if args[0] == "create" {
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: migrate create [-dir migrations] <name>")
+		}
+		paths, err := migrate.Create(*dir, fs.Arg(0), time.Now())
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return nil
+	}
+
+	if err := database.Init(&config.Get().Database.MySQL); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	migrator, err := migrations.New(database.GetDB())
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx, *steps)
+		fmt.Printf("Applied %d migration(s)\n", len(applied))
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		fmt.Printf("Rolled back %d migration(s)\n", len(reverted))
+		return err
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	}
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
+
+// printMigrationStatus prints every migration with its state
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		if status.Missing {
+			state = "missing"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,15 @@
+-- 删除基线创建的全部表，会丢失所有数据
+
+DROP TABLE IF EXISTS `t_login_log`;
+DROP TABLE IF EXISTS `t_operation_log`;
+DROP TABLE IF EXISTS `t_room`;
+DROP TABLE IF EXISTS `t_floor`;
+DROP TABLE IF EXISTS `t_building`;
+DROP TABLE IF EXISTS `t_asset`;
+DROP TABLE IF EXISTS `t_menu`;
+DROP TABLE IF EXISTS `t_role_permissions`;
+DROP TABLE IF EXISTS `t_permission`;
+DROP TABLE IF EXISTS `t_user_roles`;
+DROP TABLE IF EXISTS `t_role`;
+DROP TABLE IF EXISTS `t_user`;
+DROP TABLE IF EXISTS `t_organization`;
@@ -0,0 +1,248 @@
+-- 基线表结构，与迁移系统引入前最初版本 AutoMigrate 生成的结构一致。
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库执行后结构不变，只记录版本；之后新增的列和表由后续迁移添加。
+
+CREATE TABLE IF NOT EXISTS `t_organization` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `name` varchar(100) NOT NULL,
+    `code` varchar(50),
+    `type` varchar(20),
+    `parent_id` bigint unsigned,
+    `sort` bigint DEFAULT 0,
+    `status` varchar(20) DEFAULT 'active',
+    `street_id` bigint unsigned,
+    `district_id` bigint unsigned,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_organization_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_organization_code` (`code`),
+    INDEX `idx_t_organization_parent_id` (`parent_id`),
+    INDEX `idx_t_organization_street_id` (`street_id`),
+    INDEX `idx_t_organization_district_id` (`district_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_user` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `username` varchar(50) NOT NULL,
+    `password` varchar(100) NOT NULL,
+    `name` varchar(50) NOT NULL,
+    `phone` varchar(20),
+    `email` varchar(100),
+    `org_id` bigint unsigned,
+    `status` varchar(20) DEFAULT 'active',
+    `last_login_time` datetime(3) NULL,
+    `last_login_ip` varchar(50),
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_user_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_user_username` (`username`),
+    INDEX `idx_t_user_org_id` (`org_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_role` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `code` varchar(50) NOT NULL,
+    `name` varchar(50) NOT NULL,
+    `description` varchar(200),
+    `status` varchar(20) DEFAULT 'active',
+    `sort` bigint DEFAULT 0,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_role_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_role_code` (`code`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_user_roles` (
+    `role_id` bigint unsigned,
+    `user_id` bigint unsigned,
+    PRIMARY KEY (`role_id`,`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_permission` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `code` varchar(100) NOT NULL,
+    `name` varchar(50) NOT NULL,
+    `module` varchar(50),
+    `description` varchar(200),
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_permission_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_permission_code` (`code`),
+    INDEX `idx_t_permission_module` (`module`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_role_permissions` (
+    `permission_id` bigint unsigned,
+    `role_id` bigint unsigned,
+    PRIMARY KEY (`permission_id`,`role_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_menu` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `name` varchar(50) NOT NULL,
+    `code` varchar(50),
+    `path` varchar(200),
+    `component` varchar(200),
+    `icon` varchar(50),
+    `type` varchar(20),
+    `parent_id` bigint unsigned,
+    `sort` bigint DEFAULT 0,
+    `hidden` boolean DEFAULT false,
+    `status` varchar(20) DEFAULT 'active',
+    `permissions` varchar(500),
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_menu_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_menu_code` (`code`),
+    INDEX `idx_t_menu_parent_id` (`parent_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_asset` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `asset_code` varchar(50) NOT NULL,
+    `asset_name` varchar(100) NOT NULL,
+    `street_id` bigint unsigned NOT NULL,
+    `address` varchar(200),
+    `longitude` double,
+    `latitude` double,
+    `land_nature` varchar(50),
+    `total_area` double,
+    `rentable_area` double,
+    `asset_tags` json,
+    `description` text,
+    `status` varchar(20) DEFAULT 'normal',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_asset_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_asset_asset_code` (`asset_code`),
+    INDEX `idx_t_asset_asset_name` (`asset_name`),
+    INDEX `idx_t_asset_street_id` (`street_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_building` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `building_code` varchar(50) NOT NULL,
+    `building_name` varchar(100) NOT NULL,
+    `asset_id` bigint unsigned NOT NULL,
+    `building_type` varchar(50),
+    `total_floors` bigint,
+    `underground_floors` bigint,
+    `total_area` double,
+    `rentable_area` double,
+    `construction_year` varchar(10),
+    `elevator_count` bigint,
+    `parking_spaces` bigint,
+    `green_rate` double,
+    `property_company` varchar(100),
+    `property_phone` varchar(20),
+    `features` json,
+    `description` text,
+    `status` varchar(20) DEFAULT 'normal',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_building_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_building_building_code` (`building_code`),
+    INDEX `idx_t_building_building_name` (`building_name`),
+    INDEX `idx_t_building_asset_id` (`asset_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_floor` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `building_id` bigint unsigned NOT NULL,
+    `floor_number` bigint,
+    `floor_name` varchar(50),
+    `floor_area` double,
+    `rentable_area` double,
+    `rented_area` double,
+    `avg_rent_price` double,
+    `occupancy_rate` double,
+    `description` text,
+    `status` varchar(20) DEFAULT 'normal',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_floor_deleted_at` (`deleted_at`),
+    INDEX `idx_t_floor_building_id` (`building_id`),
+    INDEX `idx_t_floor_floor_number` (`floor_number`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_room` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `floor_id` bigint unsigned NOT NULL,
+    `room_number` varchar(50),
+    `room_type` varchar(50),
+    `room_area` double,
+    `rent_price` double,
+    `decoration` varchar(50),
+    `orientation` varchar(50),
+    `has_window` boolean,
+    `has_ac` boolean,
+    `description` text,
+    `status` varchar(20) DEFAULT 'available',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_room_deleted_at` (`deleted_at`),
+    INDEX `idx_t_room_floor_id` (`floor_id`),
+    INDEX `idx_t_room_room_number` (`room_number`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_operation_log` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned,
+    `username` varchar(50),
+    `module` varchar(50),
+    `action` varchar(50),
+    `description` varchar(200),
+    `request_url` varchar(200),
+    `request_method` varchar(20),
+    `request_params` text,
+    `response_status` bigint,
+    `response_time` bigint,
+    `client_ip` varchar(50),
+    `user_agent` varchar(500),
+    `operation_time` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_operation_log_user_id` (`user_id`),
+    INDEX `idx_t_operation_log_module` (`module`),
+    INDEX `idx_t_operation_log_operation_time` (`operation_time`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_login_log` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned,
+    `username` varchar(50),
+    `login_type` varchar(20),
+    `status` varchar(20),
+    `message` varchar(200),
+    `client_ip` varchar(50),
+    `user_agent` varchar(500),
+    `login_time` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_login_log_user_id` (`user_id`),
+    INDEX `idx_t_login_log_username` (`username`),
+    INDEX `idx_t_login_log_login_time` (`login_time`)
+);
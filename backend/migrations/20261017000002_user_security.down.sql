@@ -0,0 +1,15 @@
+-- 回滚 user_security
+
+DROP TABLE IF EXISTS `t_recovery_code`;
+DROP TABLE IF EXISTS `t_password_history`;
+
+ALTER TABLE `t_role`
+    DROP COLUMN `data_scope`,
+    DROP COLUMN `require_two_factor`;
+
+ALTER TABLE `t_user`
+    DROP COLUMN `totp_last_step`,
+    DROP COLUMN `totp_enabled`,
+    DROP COLUMN `totp_secret`,
+    DROP COLUMN `must_change_password`,
+    DROP COLUMN `password_changed_at`;
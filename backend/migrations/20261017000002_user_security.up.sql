@@ -0,0 +1,32 @@
+-- user_security
+-- 密码策略、两步验证和角色数据权限新增的列和表，最初版本的 t_user、t_role 没有这些列。
+
+ALTER TABLE `t_user`
+    ADD COLUMN `password_changed_at` datetime(3) NULL,
+    ADD COLUMN `must_change_password` boolean DEFAULT false,
+    ADD COLUMN `totp_secret` varchar(64),
+    ADD COLUMN `totp_enabled` boolean DEFAULT false,
+    ADD COLUMN `totp_last_step` bigint;
+
+ALTER TABLE `t_role`
+    ADD COLUMN `require_two_factor` boolean DEFAULT false,
+    ADD COLUMN `data_scope` varchar(30) DEFAULT 'all';
+
+CREATE TABLE IF NOT EXISTS `t_password_history` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `password_hash` varchar(100) NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_password_history_user_id` (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_recovery_code` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `code_hash` varchar(64) NOT NULL,
+    `used_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_recovery_code_user_id` (`user_id`)
+);
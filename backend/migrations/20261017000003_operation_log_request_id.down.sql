@@ -0,0 +1,5 @@
+-- 回滚 operation_log_request_id
+
+ALTER TABLE `t_operation_log`
+    DROP INDEX `idx_t_operation_log_request_id`,
+    DROP COLUMN `request_id`;
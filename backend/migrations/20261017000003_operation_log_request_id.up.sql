@@ -0,0 +1,6 @@
+-- operation_log_request_id
+-- 操作日志记录请求ID，用于关联数据变更记录
+
+ALTER TABLE `t_operation_log`
+    ADD COLUMN `request_id` varchar(64),
+    ADD INDEX `idx_t_operation_log_request_id` (`request_id`);
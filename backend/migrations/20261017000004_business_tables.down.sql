@@ -0,0 +1,10 @@
+-- 回滚 business_tables，会丢失这些表的全部数据
+
+DROP TABLE IF EXISTS `t_occupancy_snapshot`;
+DROP TABLE IF EXISTS `t_work_order_comment`;
+DROP TABLE IF EXISTS `t_work_order`;
+DROP TABLE IF EXISTS `t_lease_rooms`;
+DROP TABLE IF EXISTS `t_lease`;
+DROP TABLE IF EXISTS `t_tenant`;
+DROP TABLE IF EXISTS `t_attachment`;
+DROP TABLE IF EXISTS `t_change_log`;
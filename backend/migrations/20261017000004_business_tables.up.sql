@@ -0,0 +1,174 @@
+-- business_tables
+-- 数据变更记录、附件、租户与租赁合同、维修工单和出租快照的表
+
+CREATE TABLE IF NOT EXISTS `t_change_log` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `entity_type` varchar(50),
+    `entity_id` bigint unsigned,
+    `action` varchar(20),
+    `changes` json,
+    `user_id` bigint unsigned,
+    `username` varchar(50),
+    `request_id` varchar(64),
+    `changed_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_change_entity` (`entity_type`,`entity_id`),
+    INDEX `idx_t_change_log_user_id` (`user_id`),
+    INDEX `idx_t_change_log_request_id` (`request_id`),
+    INDEX `idx_t_change_log_changed_at` (`changed_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_attachment` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `owner_type` varchar(20) NOT NULL,
+    `owner_id` bigint unsigned NOT NULL,
+    `category` varchar(20) NOT NULL,
+    `file_name` varchar(255) NOT NULL,
+    `content_type` varchar(100),
+    `size` bigint,
+    `sha256` varchar(64) NOT NULL,
+    `description` varchar(500),
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_attachment_deleted_at` (`deleted_at`),
+    INDEX `idx_attachment_owner` (`owner_type`,`owner_id`),
+    INDEX `idx_t_attachment_sha256` (`sha256`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_tenant` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `tenant_type` varchar(20) DEFAULT 'company',
+    `name` varchar(100) NOT NULL,
+    `certificate_no` varchar(50),
+    `contact_person` varchar(50),
+    `contact_phone` varchar(20),
+    `email` varchar(100),
+    `address` varchar(200),
+    `description` text,
+    `status` varchar(20) DEFAULT 'active',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_tenant_deleted_at` (`deleted_at`),
+    INDEX `idx_t_tenant_name` (`name`),
+    INDEX `idx_t_tenant_certificate_no` (`certificate_no`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_lease` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `lease_no` varchar(50) NOT NULL,
+    `tenant_id` bigint unsigned NOT NULL,
+    `lessor` varchar(100),
+    `start_date` date,
+    `end_date` date,
+    `monthly_rent` double,
+    `deposit` double,
+    `payment_cycle` varchar(20) DEFAULT 'monthly',
+    `status` varchar(20) DEFAULT 'active',
+    `terminated_at` datetime(3) NULL,
+    `description` text,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_lease_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_lease_lease_no` (`lease_no`),
+    INDEX `idx_t_lease_tenant_id` (`tenant_id`),
+    INDEX `idx_t_lease_start_date` (`start_date`),
+    INDEX `idx_t_lease_end_date` (`end_date`),
+    INDEX `idx_t_lease_status` (`status`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_lease_rooms` (
+    `lease_id` bigint unsigned,
+    `room_id` bigint unsigned,
+    PRIMARY KEY (`lease_id`,`room_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_work_order` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `created_by` bigint unsigned,
+    `updated_by` bigint unsigned,
+    `order_no` varchar(50) NOT NULL,
+    `title` varchar(200) NOT NULL,
+    `description` text,
+    `category` varchar(20) NOT NULL,
+    `priority` varchar(20) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `building_id` bigint unsigned NOT NULL,
+    `floor_id` bigint unsigned,
+    `room_id` bigint unsigned,
+    `reporter_id` bigint unsigned,
+    `reporter_name` varchar(50),
+    `reporter_phone` varchar(20),
+    `assignee_id` bigint unsigned,
+    `room_maintenance` boolean,
+    `previous_room_status` varchar(20),
+    `due_at` datetime(3) NULL,
+    `assigned_at` datetime(3) NULL,
+    `started_at` datetime(3) NULL,
+    `resolved_at` datetime(3) NULL,
+    `closed_at` datetime(3) NULL,
+    `resolution` text,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_work_order_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_t_work_order_order_no` (`order_no`),
+    INDEX `idx_t_work_order_category` (`category`),
+    INDEX `idx_t_work_order_priority` (`priority`),
+    INDEX `idx_t_work_order_status` (`status`),
+    INDEX `idx_t_work_order_building_id` (`building_id`),
+    INDEX `idx_t_work_order_floor_id` (`floor_id`),
+    INDEX `idx_t_work_order_room_id` (`room_id`),
+    INDEX `idx_t_work_order_reporter_id` (`reporter_id`),
+    INDEX `idx_t_work_order_assignee_id` (`assignee_id`),
+    INDEX `idx_t_work_order_due_at` (`due_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_work_order_comment` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `work_order_id` bigint unsigned NOT NULL,
+    `user_id` bigint unsigned,
+    `username` varchar(50),
+    `content` text,
+    `status` varchar(20),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_work_order_comment_work_order_id` (`work_order_id`),
+    INDEX `idx_t_work_order_comment_user_id` (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `t_occupancy_snapshot` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `snapshot_date` date NOT NULL,
+    `entity_type` varchar(20) NOT NULL,
+    `entity_id` bigint unsigned NOT NULL,
+    `street_id` bigint unsigned,
+    `asset_id` bigint unsigned,
+    `building_id` bigint unsigned,
+    `room_count` bigint,
+    `available_rooms` bigint,
+    `rented_rooms` bigint,
+    `maintenance_rooms` bigint,
+    `rentable_area` double,
+    `rented_area` double,
+    `rented_income` double,
+    `avg_rent` double,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_snapshot_entity` (`snapshot_date`,`entity_type`,`entity_id`),
+    INDEX `idx_t_occupancy_snapshot_street_id` (`street_id`),
+    INDEX `idx_t_occupancy_snapshot_asset_id` (`asset_id`),
+    INDEX `idx_t_occupancy_snapshot_building_id` (`building_id`)
+);
@@ -0,0 +1,44 @@
+-- grant_route_permissions
+-- 路由按权限代码校验后新增了楼层、房间、统计、组织和菜单的权限代码，这些代码原先只在首次启动时创建，
+-- 升级前的角色没有这些权限，升级后原本能访问的接口会返回403。
+-- 补齐权限后按原有权限授予：能查看建筑的角色可查看楼层和房间，能增删改建筑的角色可增删改楼层和房间，
+-- 能查看资产的角色可查看统计，有组织管理权限的角色可查看和维护组织，能管理角色的角色可查看菜单配置。
+-- 管理员角色在启动时获得全部权限，无需处理。不可回滚。
+
+INSERT IGNORE INTO `t_permission` (`created_at`, `updated_at`, `code`, `name`, `module`, `description`) VALUES
+    (NOW(3), NOW(3), 'floor:list', '楼层列表', 'asset', '查看楼层列表'),
+    (NOW(3), NOW(3), 'floor:create', '创建楼层', 'asset', '创建新楼层'),
+    (NOW(3), NOW(3), 'floor:update', '编辑楼层', 'asset', '编辑楼层信息'),
+    (NOW(3), NOW(3), 'floor:delete', '删除楼层', 'asset', '删除楼层'),
+    (NOW(3), NOW(3), 'room:list', '房间列表', 'asset', '查看房间列表'),
+    (NOW(3), NOW(3), 'room:create', '创建房间', 'asset', '创建新房间'),
+    (NOW(3), NOW(3), 'room:update', '编辑房间', 'asset', '编辑房间信息'),
+    (NOW(3), NOW(3), 'room:delete', '删除房间', 'asset', '删除房间'),
+    (NOW(3), NOW(3), 'statistics:view', '数据统计', 'asset', '查看统计数据'),
+    (NOW(3), NOW(3), 'org:view', '查看组织', 'system', '查看组织详情'),
+    (NOW(3), NOW(3), 'org:create', '创建组织', 'system', '创建新组织'),
+    (NOW(3), NOW(3), 'org:update', '编辑组织', 'system', '编辑组织信息'),
+    (NOW(3), NOW(3), 'org:delete', '删除组织', 'system', '删除组织'),
+    (NOW(3), NOW(3), 'menu:list', '菜单管理', 'system', '查看菜单配置');
+
+INSERT IGNORE INTO `t_role_permissions` (`permission_id`, `role_id`)
+SELECT granted.`id`, rp.`role_id`
+FROM (
+    SELECT 'building:list' AS from_code, 'floor:list' AS to_code
+    UNION ALL SELECT 'building:list', 'room:list'
+    UNION ALL SELECT 'building:create', 'floor:create'
+    UNION ALL SELECT 'building:create', 'room:create'
+    UNION ALL SELECT 'building:update', 'floor:update'
+    UNION ALL SELECT 'building:update', 'room:update'
+    UNION ALL SELECT 'building:delete', 'floor:delete'
+    UNION ALL SELECT 'building:delete', 'room:delete'
+    UNION ALL SELECT 'asset:list', 'statistics:view'
+    UNION ALL SELECT 'org:list', 'org:view'
+    UNION ALL SELECT 'org:list', 'org:create'
+    UNION ALL SELECT 'org:list', 'org:update'
+    UNION ALL SELECT 'org:list', 'org:delete'
+    UNION ALL SELECT 'role:list', 'menu:list'
+) AS grants
+JOIN `t_permission` existing ON existing.`code` = grants.from_code AND existing.`deleted_at` IS NULL
+JOIN `t_role_permissions` rp ON rp.`permission_id` = existing.`id`
+JOIN `t_permission` granted ON granted.`code` = grants.to_code AND granted.`deleted_at` IS NULL;
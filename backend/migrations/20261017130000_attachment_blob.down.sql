@@ -0,0 +1,3 @@
+-- 回滚 attachment_blob
+
+DROP TABLE IF EXISTS `t_attachment_blob`;
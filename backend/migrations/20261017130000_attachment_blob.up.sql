@@ -0,0 +1,9 @@
+-- attachment_blob
+-- 附件文件表，每个内容哈希一行。上传和删除附件时在事务中锁定对应的行，
+-- 多个实例之间同一文件的写入与删除按该行串行执行。已有附件的行在首次上传或删除时创建，无需回填。
+
+CREATE TABLE IF NOT EXISTS `t_attachment_blob` (
+    `sha256` varchar(64) NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`sha256`)
+);
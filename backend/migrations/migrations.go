@@ -0,0 +1,57 @@
+// Package migrations 数据库结构迁移
+//
+// SQL迁移放在本目录，命名为 <版本>_<名称>.up.sql / .down.sql，可用 `migrate create <名称>` 生成；
+// 需要判断现有结构或处理数据的迁移用Go函数编写并加入 goMigrations。
+// 已执行的迁移不能再修改，结构变更需要新增迁移。
+package migrations
+
+import (
+	"embed"
+
+	"building-asset-backend/pkg/migrate"
+
+	"gorm.io/gorm"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// goMigrations 以Go函数编写的迁移
+var goMigrations = []*migrate.Migration{
+	{
+		// 资产经纬度的空间列和空间索引，location为 POINT(经度 纬度)，SRID为0，与GeoJSON一致按平面坐标判断包含关系
+		// 早期版本由启动时的结构同步创建，因此先判断是否已存在
+		Version: "20261017000001",
+		Name:    "asset_location",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn("t_asset", "location") {
+				err := tx.Exec("ALTER TABLE t_asset ADD COLUMN location POINT" +
+					" GENERATED ALWAYS AS (POINT(longitude, latitude)) STORED SRID 0 NOT NULL").Error
+				if err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex("t_asset", "idx_t_asset_location") {
+				return tx.Exec("ALTER TABLE t_asset ADD SPATIAL INDEX idx_t_asset_location (location)").Error
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE t_asset DROP INDEX idx_t_asset_location, DROP COLUMN location").Error
+		},
+	},
+}
+
+// All 返回全部迁移
+func All() ([]*migrate.Migration, error) {
+	return migrate.Load(files, goMigrations...)
+}
+
+// New 创建使用全部迁移的迁移执行器
+func New(db *gorm.DB) (*migrate.Migrator, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, all), nil
+}
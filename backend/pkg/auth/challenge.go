@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GenerateChallengeToken 生成短期临时凭证，只能用于完成特定步骤，不能访问业务接口
+func GenerateChallengeToken(userID uint, tokenType string, ttl time.Duration) (string, error) {
+	cfg := config.Get()
+
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+	generation, err := sessionGeneration(userID)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:     userID,
+		TokenType:  tokenType,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    cfg.App.Name,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.JWT.Secret))
+}
+
+// ParseChallengeToken 解析临时凭证并校验类型及吊销状态
+func ParseChallengeToken(tokenString, tokenType string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
+	revoked, err := IsTokenRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token revoked")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "context"
+
+type currentUserKey struct{}
+
+// CurrentUser 请求上下文中的当前登录用户
+type CurrentUser struct {
+	ID       uint
+	Username string
+	Name     string
+}
+
+// WithCurrentUser 将当前用户写入上下文
+func WithCurrentUser(ctx context.Context, user CurrentUser) context.Context {
+	return context.WithValue(ctx, currentUserKey{}, user)
+}
+
+// CurrentUserFromContext 从上下文读取当前用户
+func CurrentUserFromContext(ctx context.Context) (CurrentUser, bool) {
+	if ctx == nil {
+		return CurrentUser{}, false
+	}
+	user, ok := ctx.Value(currentUserKey{}).(CurrentUser)
+	return user, ok && user.ID > 0
+}
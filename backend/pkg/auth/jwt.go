@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -9,26 +11,59 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// token类型
+const (
+	TokenTypeAccess         = "access"
+	TokenTypeRefresh        = "refresh"
+	TokenTypePasswordChange = "password_change" // 强制修改密码临时凭证
+	TokenTypeMFA            = "mfa"             // 两步验证临时凭证
+	TokenTypeMFASetup       = "mfa_setup"       // 强制启用两步验证临时凭证
+)
+
 // Claims JWT claims结构
 type Claims struct {
-	UserID   uint     `json:"user_id"`
-	Username string   `json:"username"`
-	Name     string   `json:"name"`
-	Roles    []string `json:"roles"`
+	UserID     uint     `json:"user_id"`
+	Username   string   `json:"username"`
+	Name       string   `json:"name"`
+	Roles      []string `json:"roles"`
+	SessionID  string   `json:"sid,omitempty"` // 会话ID（对应刷新token家族）
+	TokenType  string   `json:"token_type"`
+	Generation int64    `json:"gen,omitempty"` // 签发时用户的会话代数，用户会话被整体吊销后失效
+	jwt.RegisteredClaims
+}
+
+// RefreshClaims 刷新token claims结构
+type RefreshClaims struct {
+	FamilyID   string `json:"fid"` // 刷新token家族ID
+	TokenType  string `json:"token_type"`
+	Generation int64  `json:"gen,omitempty"` // 签发时用户的会话代数
 	jwt.RegisteredClaims
 }
 
 // GenerateToken 生成JWT token
-func GenerateToken(userID uint, username, name string, roles []string) (string, error) {
+func GenerateToken(userID uint, username, name string, roles []string, sessionID string) (string, error) {
 	cfg := config.Get()
 	
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+	generation, err := sessionGeneration(userID)
+	if err != nil {
+		return "", err
+	}
+	
 	// 创建claims
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Name:     name,
-		Roles:    roles,
+		UserID:     userID,
+		Username:   username,
+		Name:       name,
+		Roles:      roles,
+		SessionID:  sessionID,
+		TokenType:  TokenTypeAccess,
+		Generation: generation,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.Expire) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -43,28 +78,62 @@ func GenerateToken(userID uint, username, name string, roles []string) (string,
 	return token.SignedString([]byte(cfg.JWT.Secret))
 }
 
-// GenerateRefreshToken 生成刷新token
-func GenerateRefreshToken(userID uint) (string, error) {
+// GenerateRefreshToken 生成刷新token，返回token及其唯一标识
+func GenerateRefreshToken(userID uint, familyID string) (string, string, error) {
 	cfg := config.Get()
 	
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", "", err
+	}
+	generation, err := sessionGeneration(userID)
+	if err != nil {
+		return "", "", err
+	}
+	
 	// 创建claims
-	claims := &jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.RefreshExpire) * time.Second)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		NotBefore: jwt.NewNumericDate(time.Now()),
-		Issuer:    cfg.App.Name,
-		Subject:   fmt.Sprintf("%d", userID),
+	claims := &RefreshClaims{
+		FamilyID:   familyID,
+		TokenType:  TokenTypeRefresh,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.RefreshExpire) * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    cfg.App.Name,
+			Subject:   fmt.Sprintf("%d", userID),
+		},
 	}
 	
 	// 创建token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	
 	// 签名
-	return token.SignedString([]byte(cfg.JWT.Secret))
+	signed, err := token.SignedString([]byte(cfg.JWT.Secret))
+	if err != nil {
+		return "", "", err
+	}
+	return signed, tokenID, nil
 }
 
-// ParseToken 解析JWT token
+// ParseToken 解析JWT访问token
 func ParseToken(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 刷新token和临时凭证不能作为访问token使用
+	if claims.TokenType != TokenTypeAccess {
+		return nil, errors.New("invalid token type")
+	}
+	
+	return claims, nil
+}
+
+// parseClaims 解析并校验签名，返回Claims
+func parseClaims(tokenString string) (*Claims, error) {
 	cfg := config.Get()
 	
 	// 解析token
@@ -87,7 +156,7 @@ func ParseToken(tokenString string) (*Claims, error) {
 	
 	// 获取claims
 	claims, ok := token.Claims.(*Claims)
-	if !ok {
+	if !ok || claims.UserID == 0 {
 		return nil, errors.New("invalid token claims")
 	}
 	
@@ -95,11 +164,11 @@ func ParseToken(tokenString string) (*Claims, error) {
 }
 
 // ParseRefreshToken 解析刷新token
-func ParseRefreshToken(tokenString string) (uint, error) {
+func ParseRefreshToken(tokenString string) (uint, *RefreshClaims, error) {
 	cfg := config.Get()
 	
 	// 解析token
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &RefreshClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名算法
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -108,25 +177,27 @@ func ParseRefreshToken(tokenString string) (uint, error) {
 	})
 	
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	
 	// 验证token
 	if !token.Valid {
-		return 0, errors.New("invalid token")
+		return 0, nil, errors.New("invalid token")
 	}
 	
 	// 获取claims
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
-	if !ok {
-		return 0, errors.New("invalid token claims")
+	claims, ok := token.Claims.(*RefreshClaims)
+	if !ok || claims.TokenType != TokenTypeRefresh || claims.FamilyID == "" {
+		return 0, nil, errors.New("invalid token claims")
 	}
 	
 	// 解析用户ID
 	var userID uint
-	fmt.Sscanf(claims.Subject, "%d", &userID)
+	if _, err := fmt.Sscanf(claims.Subject, "%d", &userID); err != nil || userID == 0 {
+		return 0, nil, errors.New("invalid token subject")
+	}
 	
-	return userID, nil
+	return userID, claims, nil
 }
 
 // ValidateToken 验证token
@@ -142,6 +213,15 @@ func IsTokenExpired(err error) bool {
 	return errors.Is(err, jwt.ErrTokenExpired)
 }
 
+// generateTokenID 生成token唯一标识(jti)
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // ExtractToken 从Authorization header中提取token
 func ExtractToken(authHeader string) string {
 	if authHeader == "" {
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/pkg/cache"
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新token无效、过期或所属会话已失效
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused 刷新token被重复使用（整个会话已被吊销）
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// NewRefreshFamily 创建新的刷新token家族，返回家族ID及首个刷新token
+func NewRefreshFamily(userID uint) (string, string, error) {
+	familyID, err := generateTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	token, tokenID, err := GenerateRefreshToken(userID, familyID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := cache.SetString(refreshFamilyKey(familyID), refreshFamilyValue(userID, tokenID), refreshTTL()); err != nil {
+		return "", "", err
+	}
+
+	return familyID, token, nil
+}
+
+// RotateRefreshToken 轮换刷新token
+// 只有家族中最新的刷新token可以换取新token，旧token再次出现时吊销整个家族
+func RotateRefreshToken(tokenString string) (uint, string, string, error) {
+	userID, claims, err := ParseRefreshToken(tokenString)
+	if err != nil {
+		return 0, "", "", ErrRefreshTokenInvalid
+	}
+
+	// 用户会话已被整体吊销
+	revoked, err := isGenerationRevoked(userID, claims.Generation)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if revoked {
+		RevokeRefreshFamily(claims.FamilyID)
+		return 0, "", "", ErrRefreshTokenInvalid
+	}
+
+	key := refreshFamilyKey(claims.FamilyID)
+	current, err := cache.GetString(key)
+	if err != nil {
+		if cache.IsNil(err) {
+			return 0, "", "", ErrRefreshTokenInvalid
+		}
+		return 0, "", "", err
+	}
+
+	expected := refreshFamilyValue(userID, claims.ID)
+	if current != expected {
+		RevokeRefreshFamily(claims.FamilyID)
+		return 0, "", "", ErrRefreshTokenReused
+	}
+
+	newToken, newTokenID, err := GenerateRefreshToken(userID, claims.FamilyID)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	// 并发使用同一刷新token时只有一个请求能够成功轮换
+	swapped, err := cache.CompareAndSwap(key, expected, refreshFamilyValue(userID, newTokenID), refreshTTL())
+	if err != nil {
+		return 0, "", "", err
+	}
+	if !swapped {
+		RevokeRefreshFamily(claims.FamilyID)
+		return 0, "", "", ErrRefreshTokenReused
+	}
+
+	return userID, claims.FamilyID, newToken, nil
+}
+
+// RevokeRefreshFamily 吊销刷新token家族
+func RevokeRefreshFamily(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	return cache.Delete(refreshFamilyKey(familyID))
+}
+
+// refreshTTL 刷新token有效期
+func refreshTTL() time.Duration {
+	return time.Duration(config.Get().JWT.RefreshExpire) * time.Second
+}
+
+// refreshFamilyKey 刷新token家族缓存键
+func refreshFamilyKey(familyID string) string {
+	return fmt.Sprintf("auth:refresh_family:%s", familyID)
+}
+
+// refreshFamilyValue 刷新token家族中记录的当前token
+func refreshFamilyValue(userID uint, tokenID string) string {
+	return fmt.Sprintf("%d:%s", userID, tokenID)
+}
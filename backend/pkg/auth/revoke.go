@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"building-asset-backend/pkg/cache"
+)
+
+// RevokeToken 将token加入吊销列表，有效期为token剩余时长
+func RevokeToken(claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return cache.Set(revokedTokenKey(claims.ID), claims.UserID, ttl)
+}
+
+// RevokeUserTokens 吊销用户此前签发的全部token
+// 每个用户有一个会话代数，签发token时写入当前代数，吊销时代数加1，代数不一致的token均失效；
+// 吊销后立即签发的token使用新代数，不受吊销影响
+func RevokeUserTokens(userID uint) error {
+	_, err := cache.Incr(sessionGenerationKey(userID))
+	return err
+}
+
+// IsTokenRevoked 检查token是否已被吊销
+func IsTokenRevoked(claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := cache.Exists(revokedTokenKey(claims.ID))
+		if err != nil {
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	return isGenerationRevoked(claims.UserID, claims.Generation)
+}
+
+// isGenerationRevoked 检查签发时的会话代数是否已被整体吊销
+func isGenerationRevoked(userID uint, generation int64) (bool, error) {
+	current, err := sessionGeneration(userID)
+	if err != nil {
+		return false, err
+	}
+	return generation != current, nil
+}
+
+// sessionGeneration 用户当前的会话代数，从未吊销过时为0
+// 代数不设过期时间，否则归零后吊销之后签发的token会全部失效
+func sessionGeneration(userID uint) (int64, error) {
+	var generation int64
+	if err := cache.Get(sessionGenerationKey(userID), &generation); err != nil {
+		if cache.IsNil(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return generation, nil
+}
+
+// revokedTokenKey 已吊销token缓存键
+func revokedTokenKey(tokenID string) string {
+	return fmt.Sprintf("auth:revoked:%s", tokenID)
+}
+
+// sessionGenerationKey 用户会话代数缓存键
+func sessionGenerationKey(userID uint) string {
+	return fmt.Sprintf("auth:session_generation:%d", userID)
+}
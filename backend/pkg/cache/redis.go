@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -61,6 +62,11 @@ func Set(key string, value interface{}, expiration time.Duration) error {
 	return rdb.Set(ctx, key, data, expiration).Err()
 }
 
+// SetString 设置字符串缓存（不做JSON编码）
+func SetString(key string, value string, expiration time.Duration) error {
+	return rdb.Set(ctx, key, value, expiration).Err()
+}
+
 // Get 获取缓存
 func Get(key string, dest interface{}) error {
 	data, err := rdb.Get(ctx, key).Result()
@@ -70,6 +76,11 @@ func Get(key string, dest interface{}) error {
 	return json.Unmarshal([]byte(data), dest)
 }
 
+// IsNil 判断是否为缓存未命中
+func IsNil(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
 // GetString 获取字符串缓存
 func GetString(key string) (string, error) {
 	return rdb.Get(ctx, key).Result()
@@ -101,6 +112,18 @@ func Incr(key string) (int64, error) {
 	return rdb.Incr(ctx, key).Result()
 }
 
+// IncrWithExpire 原子地自增并在首次写入时设置过期时间，已存在但没有过期时间的键同时补上过期时间
+func IncrWithExpire(key string, expiration time.Duration) (int64, error) {
+	script := `
+		local n = redis.call("incr", KEYS[1])
+		if n == 1 or redis.call("pttl", KEYS[1]) == -1 then
+			redis.call("pexpire", KEYS[1], ARGV[1])
+		end
+		return n
+	`
+	return rdb.Eval(ctx, script, []string{key}, expiration.Milliseconds()).Int64()
+}
+
 // Decr 自减
 func Decr(key string) (int64, error) {
 	return rdb.Decr(ctx, key).Result()
@@ -193,6 +216,23 @@ func Unlock(key string, value string) error {
 	return rdb.Eval(ctx, script, []string{key}, value).Err()
 }
 
+// CompareAndSwap 当键的当前值等于expected时原子地替换为value
+func CompareAndSwap(key string, expected string, value string, expiration time.Duration) (bool, error) {
+	script := `
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			redis.call("set", KEYS[1], ARGV[2], "PX", ARGV[3])
+			return 1
+		else
+			return 0
+		end
+	`
+	n, err := rdb.Eval(ctx, script, []string{key}, expected, value, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
 // Keys 获取匹配的键
 func Keys(pattern string) ([]string, error) {
 	return rdb.Keys(ctx, pattern).Result()
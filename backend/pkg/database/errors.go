@@ -0,0 +1,20 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// erDupEntry MySQL违反唯一索引的错误码
+const erDupEntry = 1062
+
+// IsDuplicateKey 是否为违反唯一索引的错误，index不为空时只匹配该索引
+func IsDuplicateKey(err error, index string) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != erDupEntry {
+		return false
+	}
+	return index == "" || strings.Contains(mysqlErr.Message, index)
+}
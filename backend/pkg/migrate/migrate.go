@@ -0,0 +1,342 @@
+// Package migrate 实现按版本执行、可回滚的数据库结构迁移
+//
+// 迁移可以是SQL文件（<版本>_<名称>.up.sql / .down.sql）或Go函数，版本为14位时间戳，按版本顺序执行。
+// 已执行的迁移记录在 schema_migrations 表中，同时记录校验和，执行后被修改的迁移会拒绝继续执行。
+// SQL迁移的校验和覆盖文件内容；Go函数无法计算内容校验和，只覆盖版本和名称，执行后不能修改函数，变更需要新增迁移。
+// 执行和回滚期间持有MySQL命名锁（GET_LOCK），多个实例同时启动时依次执行。
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"building-asset-backend/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移内容被修改
+	ErrChecksumMismatch = errors.New("已执行的迁移内容被修改")
+	// ErrIrreversible 迁移没有回滚脚本
+	ErrIrreversible = errors.New("迁移不可回滚")
+	// ErrLocked 其他实例正在执行迁移
+	ErrLocked = errors.New("获取迁移锁超时，其他实例可能正在执行迁移")
+)
+
+// TableName 迁移记录表
+const TableName = "schema_migrations"
+
+// lockTimeout 等待其他实例完成迁移的最长时间
+const lockTimeout = 5 * time.Minute
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version  string // 14位时间戳，如 20261017000000
+	Name     string
+	Up       func(tx *gorm.DB) error
+	Down     func(tx *gorm.DB) error // 为空时不可回滚
+	Checksum string                  // SQL迁移为内容校验和，Go迁移为版本和名称的校验和
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+	Modified  bool       `json:"modified"` // 执行后内容被修改
+	Missing   bool       `json:"missing"`  // 已执行但当前版本中不存在
+}
+
+// record 迁移记录
+type record struct {
+	Version     string
+	Name        string
+	Checksum    string
+	AppliedAt   time.Time
+	ExecutionMs int64
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+}
+
+// New 创建迁移执行器，migrations 按版本排序后使用
+func New(db *gorm.DB, migrations []*Migration) *Migrator {
+	sorted := append([]*Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// Status 返回全部迁移的执行状态，按版本排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if rec, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &rec.AppliedAt
+			status.Modified = modified(rec, migration)
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, rec := range applied {
+		appliedAt := rec.AppliedAt
+		statuses = append(statuses, Status{
+			Version: rec.Version, Name: rec.Name, Applied: true, AppliedAt: &appliedAt, Missing: true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending 返回未执行的迁移，已执行的迁移被修改时返回 ErrChecksumMismatch
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var pending []*Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up 按版本顺序执行未执行的迁移，steps<=0时全部执行，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context, steps int) ([]*Migration, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.fillChecksums(ctx); err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	var done []*Migration
+	for _, migration := range pending {
+		if err := m.run(ctx, migration, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down 按版本倒序回滚最近执行的迁移，steps<=0时回滚一个，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+
+	var done []*Migration
+	for _, version := range versions {
+		migration := m.find(version)
+		if migration == nil {
+			return done, fmt.Errorf("迁移 %s 在当前版本中不存在，无法回滚", version)
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("%s_%s: %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		if err := m.run(ctx, migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// run 在事务中执行或回滚一个迁移并更新迁移记录
+// MySQL的DDL语句会隐式提交，包含DDL的迁移中途失败时可能已部分生效，需要人工确认后再执行
+func (m *Migrator) run(ctx context.Context, migration *Migration, up bool) error {
+	action, fn := "up", migration.Up
+	if !up {
+		action, fn = "down", migration.Down
+	}
+
+	start := time.Now()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if !up {
+			return tx.Table(TableName).Where("version = ?", migration.Version).Delete(&record{}).Error
+		}
+		return tx.Table(TableName).Create(&record{
+			Version:     migration.Version,
+			Name:        migration.Name,
+			Checksum:    migration.Checksum,
+			AppliedAt:   time.Now(),
+			ExecutionMs: time.Since(start).Milliseconds(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("迁移 %s_%s %s 失败: %w", migration.Version, migration.Name, action, err)
+	}
+
+	logger.Infof("Migration %s_%s %s (%s)", migration.Version, migration.Name, action, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// verify 校验已执行的迁移内容未被修改
+func (m *Migrator) verify(applied map[string]record) error {
+	var changed []string
+	for _, migration := range m.migrations {
+		if rec, ok := applied[migration.Version]; ok && modified(rec, migration) {
+			changed = append(changed, migration.Version+"_"+migration.Name)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// modified 已执行的迁移与记录的校验和是否不一致
+// 早期版本执行的Go迁移没有记录校验和，视为未修改，执行 Up 时补写
+func modified(rec record, migration *Migration) bool {
+	return rec.Checksum != "" && rec.Checksum != migration.Checksum
+}
+
+// fillChecksums 为没有记录校验和的已执行迁移补写当前校验和
+func (m *Migrator) fillChecksums(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	db := m.db.WithContext(ctx)
+	for _, migration := range m.migrations {
+		rec, ok := applied[migration.Version]
+		if !ok || rec.Checksum != "" || migration.Checksum == "" {
+			continue
+		}
+		err := db.Table(TableName).Where("version = ? AND checksum = ''", migration.Version).
+			Update("checksum", migration.Checksum).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applied 读取已执行的迁移，迁移记录表不存在时视为没有执行过
+func (m *Migrator) applied(ctx context.Context) (map[string]record, error) {
+	db := m.db.WithContext(ctx)
+	applied := map[string]record{}
+	if !db.Migrator().HasTable(TableName) {
+		return applied, nil
+	}
+
+	var records []record
+	if err := db.Table(TableName).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Exec("CREATE TABLE IF NOT EXISTS " + TableName + " (" +
+		"version varchar(14) NOT NULL," +
+		"name varchar(100) NOT NULL," +
+		"checksum varchar(64) NOT NULL DEFAULT ''," +
+		"applied_at datetime(3) NOT NULL," +
+		"execution_ms bigint NOT NULL DEFAULT 0," +
+		"PRIMARY KEY (version))").Error
+}
+
+func (m *Migrator) find(version string) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// lock 在独立连接上获取当前数据库的迁移锁，返回释放函数
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(CONCAT(DATABASE(), ?), ?)",
+		":"+TableName, int(lockTimeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return nil, ErrLocked
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "DO RELEASE_LOCK(CONCAT(DATABASE(), ?))", ":"+TableName); err != nil {
+			logger.Warnf("释放迁移锁失败: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
@@ -0,0 +1,192 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"empty", "", nil},
+		{"comments only", "-- name\n  -- 说明\n\n", nil},
+		{
+			"multi-line statements",
+			"-- create\nCREATE TABLE t (\n  id bigint -- 主键\n);\n\nINSERT INTO t VALUES (1);\n",
+			[]string{"CREATE TABLE t (\n  id bigint -- 主键\n)", "INSERT INTO t VALUES (1)"},
+		},
+		{"trailing statement without semicolon", "DROP TABLE a;\nDROP TABLE b", []string{"DROP TABLE a", "DROP TABLE b"}},
+		{"semicolon inside a line", "SELECT ';' AS x, 1\nFROM dual;", []string{"SELECT ';' AS x, 1\nFROM dual"}},
+		{"windows line endings", "DROP TABLE a;\r\nDROP TABLE b;\r\n", []string{"DROP TABLE a", "DROP TABLE b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20261017000000_baseline.up.sql":   {Data: []byte("CREATE TABLE a (id int);\n")},
+		"20261017000000_baseline.down.sql": {Data: []byte("DROP TABLE a;\n")},
+		"20261017000002_add_b.up.sql":      {Data: []byte("CREATE TABLE b (id int);\n")},
+		"README.md":                        {Data: []byte("ignored")},
+		"20261017_bad.up.sql":              {Data: []byte("ignored")},
+	}
+	goMigration := &Migration{Version: "20261017000001", Name: "backfill", Up: func(*gorm.DB) error { return nil }}
+
+	migrations, err := Load(fsys, goMigration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, m := range migrations {
+		versions = append(versions, m.Version+"_"+m.Name)
+	}
+	want := []string{"20261017000000_baseline", "20261017000002_add_b", "20261017000001_backfill"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Fatalf("versions = %v, want %v", versions, want)
+	}
+
+	baseline, addB, backfill := migrations[0], migrations[1], migrations[2]
+	if baseline.Down == nil || addB.Down != nil {
+		t.Error("Down should be set only when a down.sql exists")
+	}
+	if baseline.Checksum != checksum("CREATE TABLE a (id int);\n", "DROP TABLE a;\n") {
+		t.Errorf("baseline checksum = %s", baseline.Checksum)
+	}
+	if backfill.Checksum != checksum("go", "20261017000001", "backfill") {
+		t.Errorf("go migration checksum = %s", backfill.Checksum)
+	}
+	if goMigration.Checksum != "" {
+		t.Error("Load must not modify the caller's Go migration")
+	}
+
+	// 修改 up 或 down 都会改变校验和
+	for _, name := range []string{"20261017000000_baseline.up.sql", "20261017000000_baseline.down.sql"} {
+		changed := fstest.MapFS{}
+		for k, v := range fsys {
+			changed[k] = v
+		}
+		changed[name] = &fstest.MapFile{Data: append([]byte("-- edited\n"), fsys[name].Data...)}
+		reloaded, err := Load(changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reloaded[0].Checksum == baseline.Checksum {
+			t.Errorf("editing %s did not change the checksum", name)
+		}
+		if !modified(record{Checksum: baseline.Checksum}, reloaded[0]) {
+			t.Errorf("editing %s is not reported as modified", name)
+		}
+	}
+	if modified(record{}, baseline) {
+		t.Error("a legacy record without checksum should not be reported as modified")
+	}
+}
+
+func TestLoadRejectsInvalidMigrations(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("SELECT 1;\n")}
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		goMigrations []*Migration
+		want         string
+	}{
+		{
+			name: "duplicate sql version",
+			fsys: fstest.MapFS{"20261017000000_a.up.sql": up, "20261017000000_b.up.sql": up},
+			want: "重复",
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{"20261017000000_a.down.sql": up},
+			want: "缺少 up.sql",
+		},
+		{
+			name:         "go version duplicates sql",
+			fsys:         fstest.MapFS{"20261017000000_a.up.sql": up},
+			goMigrations: []*Migration{{Version: "20261017000000", Name: "a_go"}},
+			want:         "版本无效或重复",
+		},
+		{
+			name:         "duplicate go versions",
+			fsys:         fstest.MapFS{},
+			goMigrations: []*Migration{{Version: "20261017000001", Name: "a"}, {Version: "20261017000001", Name: "b"}},
+			want:         "版本无效或重复",
+		},
+		{
+			name:         "invalid go version",
+			fsys:         fstest.MapFS{},
+			goMigrations: []*Migration{{Version: "2026", Name: "a"}},
+			want:         "版本无效或重复",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys, tt.goMigrations...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSortsByVersion(t *testing.T) {
+	migrations := []*Migration{{Version: "20261017000002"}, {Version: "20261017000000"}, {Version: "20261017000001"}}
+	m := New(nil, migrations)
+	for i, want := range []string{"20261017000000", "20261017000001", "20261017000002"} {
+		if m.migrations[i].Version != want {
+			t.Errorf("migrations[%d] = %s, want %s", i, m.migrations[i].Version, want)
+		}
+	}
+	if migrations[0].Version != "20261017000002" {
+		t.Error("New must not reorder the caller's slice")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC)
+
+	paths, err := Create(dir, " Add Work-Order index ", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "20261017083000_add_work_order_index.up.sql"),
+		filepath.Join(dir, "20261017083000_add_work_order_index.down.sql"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Name != "add_work_order_index" {
+		t.Fatalf("created migration not loadable: %+v", migrations)
+	}
+
+	if _, err := Create(dir, "add work order index", now); !errors.Is(err, os.ErrExist) {
+		t.Errorf("creating an existing migration: error = %v, want os.ErrExist", err)
+	}
+	if _, err := Create(dir, "!!!", now); err == nil {
+		t.Error("Create with an empty name succeeded")
+	}
+}
@@ -0,0 +1,165 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VersionLayout 迁移版本的时间格式
+const VersionLayout = "20060102150405"
+
+var (
+	fileNamePattern = regexp.MustCompile(`^(\d{14})_([a-z0-9_]+)\.(up|down)\.sql$`)
+	namePattern     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Load 读取目录中的SQL迁移并与Go迁移合并，版本重复或缺少 .up.sql 时报错
+// SQL文件中每条语句以行尾的分号结束，以 -- 开头的行为注释
+func Load(fsys fs.FS, goMigrations ...*Migration) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	type sqlFiles struct{ name, up, down string }
+	files := map[string]*sqlFiles{}
+	var versions []string
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, name, direction := match[1], match[2], match[3]
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		f, ok := files[version]
+		if !ok {
+			f = &sqlFiles{name: name}
+			files[version] = f
+			versions = append(versions, version)
+		} else if f.name != name {
+			return nil, fmt.Errorf("迁移版本 %s 重复: %s, %s", version, f.name, name)
+		}
+		if direction == "up" {
+			f.up = string(content)
+		} else {
+			f.down = string(content)
+		}
+	}
+
+	seen := map[string]bool{}
+	var migrations []*Migration
+	for _, version := range versions {
+		f := files[version]
+		if f.up == "" {
+			return nil, fmt.Errorf("迁移 %s_%s 缺少 up.sql", version, f.name)
+		}
+		migration := &Migration{
+			Version:  version,
+			Name:     f.name,
+			Up:       execStatements(f.up),
+			Checksum: checksum(f.up, f.down),
+		}
+		if f.down != "" {
+			migration.Down = execStatements(f.down)
+		}
+		migrations = append(migrations, migration)
+		seen[version] = true
+	}
+
+	for _, migration := range goMigrations {
+		if len(migration.Version) != len(VersionLayout) || seen[migration.Version] {
+			return nil, fmt.Errorf("Go迁移 %s_%s 版本无效或重复", migration.Version, migration.Name)
+		}
+		loaded := *migration
+		loaded.Checksum = checksum("go", migration.Version, migration.Name)
+		migrations = append(migrations, &loaded)
+		seen[migration.Version] = true
+	}
+	return migrations, nil
+}
+
+// checksum 各部分以\x00分隔后的SHA-256
+func checksum(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create 在目录中创建一对空的SQL迁移文件，返回文件路径
+func Create(dir, name string, now time.Time) ([]string, error) {
+	name = strings.Trim(namePattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("迁移名称无效")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(dir, now.Format(VersionLayout)+"_"+name)
+	paths := []string{base + ".up.sql", base + ".down.sql"}
+	templates := []string{
+		"-- " + name + "\n-- 每条语句以行尾的分号结束\n\n",
+		"-- 回滚 " + name + "，删除此文件表示该迁移不可回滚\n\n",
+	}
+	for i, path := range paths {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.WriteString(templates[i])
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// execStatements 返回依次执行SQL语句的迁移函数
+func execStatements(content string) func(tx *gorm.DB) error {
+	statements := splitStatements(content)
+	return func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements 按行尾分号拆分SQL语句，忽略空行和注释行
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
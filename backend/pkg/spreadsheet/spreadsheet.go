@@ -0,0 +1,308 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 支持的文件格式
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnsupportedFormat 不支持的文件格式
+var ErrUnsupportedFormat = errors.New("不支持的文件格式，仅支持csv和xlsx")
+
+// utf8BOM Excel打开UTF-8编码的CSV时依赖BOM识别编码
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// formulaPrefixes 表格软件会把以这些字符开头的单元格当作公式
+const formulaPrefixes = "=+-@\t\r"
+
+// Sheet 工作表，CSV文件只有一个工作表
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// FormatOf 按文件扩展名识别格式，不支持的格式返回空字符串
+func FormatOf(filename string) string {
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), ".")) {
+	case FormatCSV:
+		return FormatCSV
+	case FormatXLSX:
+		return FormatXLSX
+	}
+	return ""
+}
+
+// ContentType 返回文件格式对应的MIME类型
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Read 读取文件中的全部工作表
+// CSV兼容UTF-8（含BOM）和Excel默认保存的GB18030编码
+func Read(r io.Reader, format string) ([]Sheet, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatXLSX:
+		return readXLSX(r)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func readCSV(r io.Reader) ([]Sheet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		if data, err = simplifiedchinese.GB18030.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("无法识别的文件编码: %w", err)
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV解析失败: %w", err)
+	}
+	return []Sheet{{Rows: rows}}, nil
+}
+
+func readXLSX(r io.Reader) ([]Sheet, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("XLSX解析失败: %w", err)
+	}
+	defer file.Close()
+
+	var sheets []Sheet
+	for _, name := range file.GetSheetList() {
+		rows, err := file.GetRows(name)
+		if err != nil {
+			return nil, fmt.Errorf("读取工作表%s失败: %w", name, err)
+		}
+		sheets = append(sheets, Sheet{Name: name, Rows: rows})
+	}
+	return sheets, nil
+}
+
+// Write 写出工作表，每个工作表的第一行作为表头
+// CSV只能包含一个工作表，只写出第一个工作表
+func Write(w io.Writer, format string, sheets ...Sheet) error {
+	switch format {
+	case FormatCSV:
+		if len(sheets) > 1 {
+			sheets = sheets[:1]
+		}
+	case FormatXLSX:
+	default:
+		return ErrUnsupportedFormat
+	}
+
+	book := newWorkbook(w, format)
+	for _, sheet := range sheets {
+		writer, err := book.sheet(sheet.Name)
+		if err != nil {
+			return err
+		}
+		for _, row := range sheet.Rows {
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return book.close()
+}
+
+// Writer 逐行写出单个工作表，第一行作为表头
+type Writer interface {
+	WriteRow(row []string) error
+	// Close 写出剩余内容，XLSX文件在Close时才写入w
+	Close() error
+}
+
+// NewWriter 创建逐行写出的工作表，用于导出大量数据
+func NewWriter(w io.Writer, format, sheetName string) (Writer, error) {
+	if format != FormatCSV && format != FormatXLSX {
+		return nil, ErrUnsupportedFormat
+	}
+	book := newWorkbook(w, format)
+	writer, err := book.sheet(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	return &bookWriter{sheetWriter: writer, book: book}, nil
+}
+
+type bookWriter struct {
+	sheetWriter
+	book *workbook
+}
+
+func (w *bookWriter) Close() error {
+	return w.book.close()
+}
+
+type sheetWriter interface {
+	WriteRow(row []string) error
+}
+
+// workbook 按格式写出一个或多个工作表
+type workbook struct {
+	w      io.Writer
+	format string
+
+	csv *csv.Writer
+
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	header int
+	sheets int
+}
+
+func newWorkbook(w io.Writer, format string) *workbook {
+	return &workbook{w: w, format: format}
+}
+
+// sheet 开始写出新的工作表
+func (b *workbook) sheet(name string) (sheetWriter, error) {
+	b.sheets++
+	if b.format == FormatCSV {
+		if _, err := b.w.Write(utf8BOM); err != nil {
+			return nil, err
+		}
+		b.csv = csv.NewWriter(b.w)
+		return csvSheet{b.csv}, nil
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", b.sheets)
+	}
+	if b.file == nil {
+		b.file = excelize.NewFile()
+		style, err := b.file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+		if err != nil {
+			return nil, err
+		}
+		b.header = style
+		if err := b.file.SetSheetName(b.file.GetSheetName(0), name); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := b.stream.Flush(); err != nil {
+			return nil, err
+		}
+		if _, err := b.file.NewSheet(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// 流式写入，导出大量数据时避免在内存中保留整个工作表
+	stream, err := b.file.NewStreamWriter(name)
+	if err != nil {
+		return nil, err
+	}
+	b.stream = stream
+	return &xlsxSheet{stream: stream, header: b.header}, nil
+}
+
+func (b *workbook) close() error {
+	if b.format == FormatCSV {
+		if b.csv == nil {
+			_, err := b.w.Write(utf8BOM)
+			return err
+		}
+		b.csv.Flush()
+		return b.csv.Error()
+	}
+
+	if b.file == nil {
+		if _, err := b.sheet(""); err != nil {
+			return err
+		}
+	}
+	defer b.file.Close()
+	if err := b.stream.Flush(); err != nil {
+		return err
+	}
+	return b.file.Write(b.w)
+}
+
+type csvSheet struct {
+	writer *csv.Writer
+}
+
+// WriteRow 写出一行，可能被当作公式的值前加单引号，防止打开文件时执行用户输入的公式
+func (s csvSheet) WriteRow(row []string) error {
+	escaped := make([]string, len(row))
+	for i, value := range row {
+		if isFormulaLike(value) {
+			value = "'" + value
+		}
+		escaped[i] = value
+	}
+	return s.writer.Write(escaped)
+}
+
+type xlsxSheet struct {
+	stream *excelize.StreamWriter
+	header int
+	rows   int
+}
+
+// WriteRow 写出一行，可能被当作公式的值写为内联字符串单元格
+func (s *xlsxSheet) WriteRow(row []string) error {
+	values := make([]interface{}, len(row))
+	for i, value := range row {
+		switch {
+		case s.rows == 0:
+			values[i] = excelize.Cell{StyleID: s.header, Value: value}
+		case isFormulaLike(value):
+			values[i] = []excelize.RichTextRun{{Text: value}}
+		default:
+			values[i] = value
+		}
+	}
+	if s.rows == 0 && len(row) > 0 {
+		if err := s.stream.SetColWidth(1, len(row), 16); err != nil {
+			return err
+		}
+	}
+
+	s.rows++
+	cell, err := excelize.CoordinatesToCellName(1, s.rows)
+	if err != nil {
+		return err
+	}
+	return s.stream.SetRow(cell, values)
+}
+
+// isFormulaLike 值是否可能被表格软件当作公式，负数等纯数字除外
+func isFormulaLike(value string) bool {
+	if value == "" || !strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return false
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err != nil
+}
@@ -0,0 +1,85 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestIsFormulaLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"办公楼", false},
+		{"=1+1", true},
+		{"+86 10 1234", true},
+		{"-cmd|' /C calc'!A0", true},
+		{"@SUM(A1)", true},
+		{"\t=1", true},
+		{"\r=1", true},
+		{"-12.5", false},
+		{"+3", false},
+		{"a=b", false},
+	}
+	for _, tt := range tests {
+		if got := isFormulaLike(tt.value); got != tt.want {
+			t.Errorf("isFormulaLike(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, FormatCSV, Sheet{Rows: [][]string{
+		{"名称", "说明"},
+		{"=HYPERLINK(\"http://x\")", "-5"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, err := Read(&buf, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"名称", "说明"}, {"'=HYPERLINK(\"http://x\")", "-5"}}
+	if !reflect.DeepEqual(sheets[0].Rows, want) {
+		t.Errorf("rows = %q, want %q", sheets[0].Rows, want)
+	}
+}
+
+func TestWriteXLSXWritesFormulasAsStrings(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, FormatXLSX, Sheet{Name: "资产", Rows: [][]string{
+		{"名称"},
+		{"=1+1"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	formula, err := file.GetCellFormula("资产", "A2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formula != "" {
+		t.Errorf("A2 formula = %q, want none", formula)
+	}
+	value, err := file.GetCellValue("资产", "A2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "=1+1" {
+		t.Errorf("A2 = %q, want %q", value, "=1+1")
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"building-asset-backend/internal/config"
+	"building-asset-backend/pkg/logger"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Storage 文件存储后端，key为以/分隔的相对路径
+type Storage interface {
+	// Put 写入文件，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// Exists 判断文件是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+var store Storage
+
+// Init 按上传配置初始化存储后端
+func Init(cfg *config.UploadConfig) error {
+	switch cfg.Storage {
+	case "", "local":
+		local, err := NewLocal(config.GetUploadPath(""))
+		if err != nil {
+			return err
+		}
+		store = local
+	case "s3":
+		s3, err := NewS3(&cfg.S3)
+		if err != nil {
+			return err
+		}
+		store = s3
+	default:
+		return fmt.Errorf("unsupported upload storage %q", cfg.Storage)
+	}
+
+	logger.Info(fmt.Sprintf("File storage initialized: %s", cfg.Storage))
+	return nil
+}
+
+// Get 获取存储后端
+func Get() Storage {
+	return store
+}
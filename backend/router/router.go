@@ -1,16 +1,31 @@
 package router
 
 import (
+	"fmt"
+
 	v1 "building-asset-backend/api/v1"
+	appconfig "building-asset-backend/internal/config"
+	"building-asset-backend/internal/metrics"
 	"building-asset-backend/internal/middleware"
+	"building-asset-backend/internal/model"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter() *gin.Engine {
+func InitRouter() (*gin.Engine, error) {
 	r := gin.New()
-	r.Use(gin.Logger())
+	// 只信任配置的反向代理传入的 X-Forwarded-For，登录失败限制等按客户端IP计数的功能依赖该设置
+	if err := r.SetTrustedProxies(appconfig.Get().App.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid app.trusted_proxies: %w", err)
+	}
+	// 探针和指标抓取请求频繁，不记录访问日志
+	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{SkipPaths: []string{"/health", "/livez", "/readyz", "/metrics"}}))
+	// 指标记录在Recovery之外，panic的请求按500计入
+	metricsConfig := appconfig.Get().Metrics
+	if metricsConfig.Enabled {
+		r.Use(middleware.Metrics())
+	}
 	r.Use(gin.Recovery())
 
 	// CORS configuration
@@ -21,11 +36,16 @@ func InitRouter() *gin.Engine {
 	r.Use(cors.New(config))
 
 	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-		})
-	})
+	// /livez 存活检查，/readyz 就绪检查，/health 保留为存活检查以兼容已有探针
+	healthAPI := v1.NewHealthAPI()
+	r.GET("/livez", healthAPI.Live)
+	r.GET("/readyz", healthAPI.Ready)
+	r.GET("/health", healthAPI.Live)
+
+	// Prometheus指标，未配置独立监听地址时由业务端口提供，需携带令牌
+	if metricsConfig.Enabled && metricsConfig.Listen == "" {
+		r.GET("/metrics", gin.WrapH(metrics.Handler(metricsConfig.Token)))
+	}
 
 	// API v1 routes
 	apiv1 := r.Group("/api/v1")
@@ -35,120 +55,210 @@ func InitRouter() *gin.Engine {
 		auth := apiv1.Group("/auth")
 		{
 			auth.POST("/login", authAPI.Login)
-			auth.POST("/logout", authAPI.Logout)
+			auth.POST("/logout", middleware.JWTAuth(), authAPI.Logout)
 			auth.POST("/refresh", authAPI.RefreshToken)
+			auth.POST("/change-password", authAPI.ChangeExpiredPassword)
+			auth.POST("/2fa/verify", authAPI.VerifyTwoFactor)
+			auth.POST("/2fa/setup", authAPI.BeginRequiredTwoFactorSetup)
+			auth.POST("/2fa/setup/confirm", authAPI.ConfirmRequiredTwoFactorSetup)
 		}
 
 		// Protected routes
+		// 操作日志：路由组用OperationModule声明模块，特殊操作用OperationAction声明操作类型，
+		// 其余写操作按请求方法推导为create/update/delete
 		protected := apiv1.Group("")
-		protected.Use(middleware.JWTAuth())
+		protected.Use(middleware.JWTAuth(), middleware.DataScope(), middleware.OperationLog())
 		{
 			// User info
 			protected.GET("/me", authAPI.GetUserInfo)
+			protected.PUT("/me/password", middleware.OperationAction("change_password"), authAPI.ChangePassword)
+			protected.POST("/me/2fa/setup", middleware.OperationAction("setup_2fa"), authAPI.SetupTwoFactor)
+			protected.POST("/me/2fa/confirm", middleware.OperationAction("enable_2fa"), authAPI.ConfirmTwoFactor)
+			protected.POST("/me/2fa/disable", middleware.OperationAction("disable_2fa"), authAPI.DisableTwoFactor)
+			protected.POST("/me/2fa/recovery-codes", middleware.OperationAction("regenerate_recovery_codes"), authAPI.RegenerateRecoveryCodes)
 
 			// Asset management routes
 			assetAPI := v1.NewAssetAPI()
 
 			// Asset routes
-			assets := protected.Group("/assets")
+			assets := protected.Group("/assets", middleware.OperationModule("asset"))
 			{
-				assets.GET("", assetAPI.GetAssets)
-				assets.GET("/:id", assetAPI.GetAsset)
-				assets.POST("", assetAPI.CreateAsset)
-				assets.PUT("/:id", assetAPI.UpdateAsset)
-				assets.DELETE("/:id", assetAPI.DeleteAsset)
+				assets.GET("", middleware.RequirePermission("asset:list"), assetAPI.GetAssets)
+				assets.GET("/import/template", middleware.RequirePermission("asset:import"), assetAPI.GetImportTemplate)
+				assets.POST("/import", middleware.OperationAction("import"), middleware.RequirePermission("asset:import"), assetAPI.ImportAssets)
+				assets.GET("/geo/bbox", middleware.RequirePermission("asset:list"), assetAPI.SearchAssetsInBBox)
+				assets.GET("/geo/radius", middleware.RequirePermission("asset:list"), assetAPI.SearchAssetsInRadius)
+				assets.POST("/geo/polygon", middleware.OperationAction("query"), middleware.RequirePermission("asset:list"), assetAPI.SearchAssetsInPolygon)
+				assets.GET("/:id", middleware.RequirePermission("asset:view"), assetAPI.GetAsset)
+				assets.GET("/:id/history", middleware.RequirePermission("asset:view"), assetAPI.GetAssetHistory)
+				assets.GET("/:id/attachments", middleware.RequirePermission("asset:view"), assetAPI.GetAttachments(model.EntityAsset))
+				assets.POST("/:id/attachments", middleware.OperationAction("upload"), middleware.RequirePermission("asset:update"), assetAPI.UploadAttachment(model.EntityAsset))
+				assets.GET("/:id/attachments/:attachment_id", middleware.RequirePermission("asset:view"), assetAPI.DownloadAttachment(model.EntityAsset))
+				assets.DELETE("/:id/attachments/:attachment_id", middleware.RequirePermission("asset:update"), assetAPI.DeleteAttachment(model.EntityAsset))
+				assets.POST("", middleware.RequirePermission("asset:create"), assetAPI.CreateAsset)
+				assets.PUT("/:id", middleware.RequirePermission("asset:update"), assetAPI.UpdateAsset)
+				assets.DELETE("/:id", middleware.RequirePermission("asset:delete"), assetAPI.DeleteAsset)
 			}
 
 			// Building routes
-			buildings := protected.Group("/buildings")
+			buildings := protected.Group("/buildings", middleware.OperationModule("building"))
 			{
-				buildings.GET("", assetAPI.GetBuildings)
-				buildings.GET("/:id", assetAPI.GetBuilding)
-				buildings.POST("", assetAPI.CreateBuilding)
-				buildings.PUT("/:id", assetAPI.UpdateBuilding)
-				buildings.DELETE("/:id", assetAPI.DeleteBuilding)
+				buildings.GET("", middleware.RequirePermission("building:list"), assetAPI.GetBuildings)
+				buildings.GET("/:id", middleware.RequirePermission("building:view"), assetAPI.GetBuilding)
+				buildings.GET("/:id/history", middleware.RequirePermission("building:view"), assetAPI.GetBuildingHistory)
+				buildings.GET("/:id/attachments", middleware.RequirePermission("building:view"), assetAPI.GetAttachments(model.EntityBuilding))
+				buildings.POST("/:id/attachments", middleware.OperationAction("upload"), middleware.RequirePermission("building:update"), assetAPI.UploadAttachment(model.EntityBuilding))
+				buildings.GET("/:id/attachments/:attachment_id", middleware.RequirePermission("building:view"), assetAPI.DownloadAttachment(model.EntityBuilding))
+				buildings.DELETE("/:id/attachments/:attachment_id", middleware.RequirePermission("building:update"), assetAPI.DeleteAttachment(model.EntityBuilding))
+				buildings.POST("", middleware.RequirePermission("building:create"), assetAPI.CreateBuilding)
+				buildings.PUT("/:id", middleware.RequirePermission("building:update"), assetAPI.UpdateBuilding)
+				buildings.DELETE("/:id", middleware.RequirePermission("building:delete"), assetAPI.DeleteBuilding)
 			}
 
 			// Floor routes
-			floors := protected.Group("/floors")
+			floors := protected.Group("/floors", middleware.OperationModule("floor"))
 			{
-				floors.GET("", assetAPI.GetFloors)
-				floors.POST("", assetAPI.CreateFloor)
-				floors.PUT("/:id", assetAPI.UpdateFloor)
-				floors.DELETE("/:id", assetAPI.DeleteFloor)
+				floors.GET("", middleware.RequirePermission("floor:list"), assetAPI.GetFloors)
+				floors.GET("/:id/history", middleware.RequirePermission("floor:list"), assetAPI.GetFloorHistory)
+				floors.GET("/:id/attachments", middleware.RequirePermission("floor:list"), assetAPI.GetAttachments(model.EntityFloor))
+				floors.POST("/:id/attachments", middleware.OperationAction("upload"), middleware.RequirePermission("floor:update"), assetAPI.UploadAttachment(model.EntityFloor))
+				floors.GET("/:id/attachments/:attachment_id", middleware.RequirePermission("floor:list"), assetAPI.DownloadAttachment(model.EntityFloor))
+				floors.DELETE("/:id/attachments/:attachment_id", middleware.RequirePermission("floor:update"), assetAPI.DeleteAttachment(model.EntityFloor))
+				floors.POST("", middleware.RequirePermission("floor:create"), assetAPI.CreateFloor)
+				floors.PUT("/:id", middleware.RequirePermission("floor:update"), assetAPI.UpdateFloor)
+				floors.DELETE("/:id", middleware.RequirePermission("floor:delete"), assetAPI.DeleteFloor)
 			}
 
 			// Room routes
-			rooms := protected.Group("/rooms")
+			rooms := protected.Group("/rooms", middleware.OperationModule("room"))
+			{
+				rooms.GET("", middleware.RequirePermission("room:list"), assetAPI.GetRooms)
+				rooms.GET("/:id/history", middleware.RequirePermission("room:list"), assetAPI.GetRoomHistory)
+				rooms.GET("/:id/attachments", middleware.RequirePermission("room:list"), assetAPI.GetAttachments(model.EntityRoom))
+				rooms.POST("/:id/attachments", middleware.OperationAction("upload"), middleware.RequirePermission("room:update"), assetAPI.UploadAttachment(model.EntityRoom))
+				rooms.GET("/:id/attachments/:attachment_id", middleware.RequirePermission("room:list"), assetAPI.DownloadAttachment(model.EntityRoom))
+				rooms.DELETE("/:id/attachments/:attachment_id", middleware.RequirePermission("room:update"), assetAPI.DeleteAttachment(model.EntityRoom))
+				rooms.POST("", middleware.RequirePermission("room:create"), assetAPI.CreateRoom)
+				rooms.PUT("/:id", middleware.RequirePermission("room:update"), assetAPI.UpdateRoom)
+				rooms.DELETE("/:id", middleware.RequirePermission("room:delete"), assetAPI.DeleteRoom)
+			}
+
+			// Maintenance jobs
+			admin := protected.Group("/admin", middleware.OperationModule("admin"))
+			{
+				admin.POST("/recompute-rollups", middleware.OperationAction("recompute_rollups"), middleware.RequirePermission("rollup:recompute"), assetAPI.RecomputeRollups)
+			}
+
+			// Lease management routes
+			leaseAPI := v1.NewLeaseAPI()
+
+			tenants := protected.Group("/tenants", middleware.OperationModule("tenant"))
+			{
+				tenants.GET("", middleware.RequirePermission("tenant:list"), leaseAPI.GetTenants)
+				tenants.GET("/:id", middleware.RequirePermission("tenant:view"), leaseAPI.GetTenant)
+				tenants.POST("", middleware.RequirePermission("tenant:create"), leaseAPI.CreateTenant)
+				tenants.PUT("/:id", middleware.RequirePermission("tenant:update"), leaseAPI.UpdateTenant)
+				tenants.DELETE("/:id", middleware.RequirePermission("tenant:delete"), leaseAPI.DeleteTenant)
+			}
+
+			leases := protected.Group("/leases", middleware.OperationModule("lease"))
+			{
+				leases.GET("", middleware.RequirePermission("lease:list"), leaseAPI.GetLeases)
+				leases.GET("/:id", middleware.RequirePermission("lease:view"), leaseAPI.GetLease)
+				leases.POST("", middleware.RequirePermission("lease:create"), leaseAPI.CreateLease)
+				leases.PUT("/:id", middleware.RequirePermission("lease:update"), leaseAPI.UpdateLease)
+				leases.DELETE("/:id", middleware.RequirePermission("lease:delete"), leaseAPI.DeleteLease)
+			}
+
+			// Maintenance work orders
+			workOrderAPI := v1.NewWorkOrderAPI()
+			workOrders := protected.Group("/work-orders", middleware.OperationModule("work_order"))
+			{
+				workOrders.GET("", middleware.RequirePermission("work_order:list"), workOrderAPI.GetWorkOrders)
+				workOrders.GET("/:id", middleware.RequirePermission("work_order:view"), workOrderAPI.GetWorkOrder)
+				workOrders.POST("", middleware.RequirePermission("work_order:create"), workOrderAPI.CreateWorkOrder)
+				workOrders.PUT("/:id", middleware.RequirePermission("work_order:update"), workOrderAPI.UpdateWorkOrder)
+				workOrders.POST("/:id/assign", middleware.OperationAction("assign"), middleware.RequirePermission("work_order:assign"), workOrderAPI.AssignWorkOrder)
+				workOrders.POST("/:id/status", middleware.OperationAction("update_status"), middleware.RequirePermission("work_order:update"), workOrderAPI.ChangeWorkOrderStatus)
+				workOrders.POST("/:id/comments", middleware.OperationAction("comment"), middleware.RequirePermission("work_order:update"), workOrderAPI.AddWorkOrderComment)
+				workOrders.DELETE("/:id", middleware.RequirePermission("work_order:delete"), workOrderAPI.DeleteWorkOrder)
+			}
+
+			// Map feeds
+			maps := protected.Group("/map")
 			{
-				rooms.GET("", assetAPI.GetRooms)
-				rooms.POST("", assetAPI.CreateRoom)
-				rooms.PUT("/:id", assetAPI.UpdateRoom)
-				rooms.DELETE("/:id", assetAPI.DeleteRoom)
+				maps.GET("/assets.geojson", middleware.RequirePermission("asset:view"), assetAPI.GetMapGeoJSON)
+				maps.GET("/assets.kml", middleware.RequirePermission("asset:view"), assetAPI.GetMapKML)
 			}
 
 			// Statistics
-			protected.GET("/statistics/assets", assetAPI.GetAssetStatistics)
+			protected.GET("/statistics/assets", middleware.RequirePermission("statistics:view"), assetAPI.GetAssetStatistics)
+			protected.GET("/statistics/trends", middleware.RequirePermission("statistics:view"), assetAPI.GetTrends)
 
 			// System management routes
 			systemAPI := v1.NewSystemAPI()
 
 			// User management
-			users := protected.Group("/users")
+			users := protected.Group("/users", middleware.OperationModule("user"))
 			{
-				users.GET("", systemAPI.GetUsers)
-				users.GET("/:id", systemAPI.GetUser)
-				users.POST("", systemAPI.CreateUser)
-				users.PUT("/:id", systemAPI.UpdateUser)
-				users.DELETE("/:id", systemAPI.DeleteUser)
-				users.PUT("/:id/password", systemAPI.ResetPassword)
+				users.GET("", middleware.RequirePermission("user:list"), systemAPI.GetUsers)
+				users.GET("/:id", middleware.RequirePermission("user:view"), systemAPI.GetUser)
+				users.GET("/:id/history", middleware.RequirePermission("user:view"), systemAPI.GetUserHistory)
+				users.POST("", middleware.RequirePermission("user:create"), systemAPI.CreateUser)
+				users.PUT("/:id", middleware.RequirePermission("user:update"), systemAPI.UpdateUser)
+				users.DELETE("/:id", middleware.RequirePermission("user:delete"), systemAPI.DeleteUser)
+				users.PUT("/:id/password", middleware.OperationAction("reset_password"), middleware.RequirePermission("user:update"), systemAPI.ResetPassword)
+				users.POST("/:id/unlock", middleware.OperationAction("unlock"), middleware.RequirePermission("user:update"), systemAPI.UnlockUser)
+				users.POST("/:id/revoke-sessions", middleware.OperationAction("revoke_sessions"), middleware.RequirePermission("user:update"), systemAPI.RevokeUserSessions)
+				users.DELETE("/:id/2fa", middleware.OperationAction("reset_2fa"), middleware.RequirePermission("user:update"), systemAPI.ResetUserTwoFactor)
 			}
 
 			// Role management
-			roles := protected.Group("/roles")
+			roles := protected.Group("/roles", middleware.OperationModule("role"))
 			{
-				roles.GET("", systemAPI.GetRoles)
-				roles.GET("/:id", systemAPI.GetRole)
-				roles.POST("", systemAPI.CreateRole)
-				roles.PUT("/:id", systemAPI.UpdateRole)
-				roles.DELETE("/:id", systemAPI.DeleteRole)
-				roles.PUT("/:id/permissions", systemAPI.UpdateRolePermissions)
+				roles.GET("", middleware.RequirePermission("role:list"), systemAPI.GetRoles)
+				roles.GET("/:id", middleware.RequirePermission("role:view"), systemAPI.GetRole)
+				roles.POST("", middleware.RequirePermission("role:create"), systemAPI.CreateRole)
+				roles.PUT("/:id", middleware.RequirePermission("role:update"), systemAPI.UpdateRole)
+				roles.DELETE("/:id", middleware.RequirePermission("role:delete"), systemAPI.DeleteRole)
+				roles.PUT("/:id/permissions", middleware.OperationAction("assign_permissions"), middleware.RequirePermission("role:update"), systemAPI.UpdateRolePermissions)
+				roles.PUT("/:id/two-factor", middleware.OperationAction("update_2fa_policy"), middleware.RequirePermission("role:update"), systemAPI.UpdateRoleTwoFactor)
 			}
 
 			// Permission management
-			permissions := protected.Group("/permissions")
+			permissions := protected.Group("/permissions", middleware.OperationModule("permission"))
 			{
-				permissions.GET("", systemAPI.GetPermissions)
-				permissions.GET("/tree", systemAPI.GetPermissionTree)
+				permissions.GET("", middleware.RequirePermission("role:list"), systemAPI.GetPermissions)
+				permissions.GET("/tree", middleware.RequirePermission("role:list"), systemAPI.GetPermissionTree)
 			}
 
 			// Menu management
-			menus := protected.Group("/menus")
+			menus := protected.Group("/menus", middleware.OperationModule("menu"))
 			{
-				menus.GET("", systemAPI.GetMenus)
-				menus.GET("/tree", systemAPI.GetMenuTree)
-				menus.GET("/user", systemAPI.GetUserMenus)
+				menus.GET("", middleware.RequirePermission("menu:list"), systemAPI.GetMenus)
+				menus.GET("/tree", middleware.RequirePermission("menu:list"), systemAPI.GetMenuTree)
+				menus.GET("/user", systemAPI.GetUserMenus) // 当前用户菜单，登录即可访问
 			}
 
 			// Organization management
-			orgs := protected.Group("/organizations")
+			orgs := protected.Group("/organizations", middleware.OperationModule("org"))
 			{
-				orgs.GET("", systemAPI.GetOrganizations)
-				orgs.GET("/tree", systemAPI.GetOrganizationTree)
-				orgs.GET("/:id", systemAPI.GetOrganization)
-				orgs.POST("", systemAPI.CreateOrganization)
-				orgs.PUT("/:id", systemAPI.UpdateOrganization)
-				orgs.DELETE("/:id", systemAPI.DeleteOrganization)
+				orgs.GET("", middleware.RequirePermission("org:list"), systemAPI.GetOrganizations)
+				orgs.GET("/tree", middleware.RequirePermission("org:list"), systemAPI.GetOrganizationTree)
+				orgs.GET("/:id", middleware.RequirePermission("org:view"), systemAPI.GetOrganization)
+				orgs.POST("", middleware.RequirePermission("org:create"), systemAPI.CreateOrganization)
+				orgs.PUT("/:id", middleware.RequirePermission("org:update"), systemAPI.UpdateOrganization)
+				orgs.DELETE("/:id", middleware.RequirePermission("org:delete"), systemAPI.DeleteOrganization)
 			}
 
 			// Operation logs
-			logs := protected.Group("/logs")
+			logs := protected.Group("/logs", middleware.OperationModule("log"))
 			{
-				logs.GET("/operations", systemAPI.GetOperationLogs)
-				logs.GET("/logins", systemAPI.GetLoginLogs)
+				logs.GET("/operations", middleware.RequirePermission("log:list"), systemAPI.GetOperationLogs)
+				logs.GET("/logins", middleware.RequirePermission("log:list"), systemAPI.GetLoginLogs)
 			}
 		}
 	}
 
-	return r
+	return r, nil
 }
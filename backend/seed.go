@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"building-asset-backend/internal/model"
+	"building-asset-backend/internal/service"
+	"building-asset-backend/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// demoAssetCode identifies the demo data so seeding twice does nothing
+const demoAssetCode = "DEMO-001"
+
+// seed creates default data, which setup has already done, and optionally a demo asset hierarchy
+//
+//	seed [--demo]
+func seed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	demo := fs.Bool("demo", false, "also create a sample district, street, asset hierarchy, tenants and leases")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*demo {
+		fmt.Println("Default data is up to date")
+		return nil
+	}
+
+	err := database.GetDB().Where("asset_code = ?", demoAssetCode).First(&model.Asset{}).Error
+	if err == nil {
+		fmt.Println("Demo data already exists")
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return seedDemo()
+}
+
+// seedDemo creates the demo data through the services so rollups and room status stay consistent.
+// Everything runs in one transaction, so a failure leaves no partial demo data behind.
+func seedDemo() error {
+	var summary string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		summary, err = seedDemoData(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(summary)
+	return nil
+}
+
+// seedDemoData creates the demo hierarchy, tenants, leases and work order with services bound to tx
+func seedDemoData(tx *gorm.DB) (string, error) {
+	userService := service.NewUserService().WithTx(tx)
+	assetService := service.NewAssetService().WithTx(tx)
+	leaseService := service.NewLeaseService().WithTx(tx)
+
+	rootID, err := organizationID("")
+	if err != nil {
+		return "", err
+	}
+	district, err := userService.CreateOrganization(&model.Organization{
+		Name: "示例区", Code: "DEMO-D", Type: "district", ParentID: &rootID, Sort: 99,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create district: %w", err)
+	}
+	street, err := userService.CreateOrganization(&model.Organization{
+		Name: "示例街道", Code: "DEMO-S", Type: "street", ParentID: &district.ID, DistrictID: &district.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create street: %w", err)
+	}
+
+	asset, err := assetService.CreateAsset(&model.Asset{
+		AssetCode:  demoAssetCode,
+		AssetName:  "示例科创园",
+		StreetID:   street.ID,
+		Address:    "示例路1号",
+		Longitude:  121.4737,
+		Latitude:   31.2304,
+		LandNature: "工业用地",
+		AssetTags:  model.StringArray{"示例", "科创园"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create asset: %w", err)
+	}
+
+	var rooms []model.Room
+	for b, name := range []string{"A栋", "B栋"} {
+		building, err := assetService.CreateBuilding(&model.Building{
+			BuildingCode: fmt.Sprintf("%s-B%d", demoAssetCode, b+1),
+			BuildingName: name,
+			AssetID:      asset.ID,
+			BuildingType: "office",
+			TotalFloors:  3,
+		})
+		if err != nil {
+			return "", fmt.Errorf("create building %s: %w", name, err)
+		}
+
+		for f := 1; f <= 3; f++ {
+			floor, err := assetService.CreateFloor(&model.Floor{
+				BuildingID:  building.ID,
+				FloorNumber: f,
+				FloorName:   fmt.Sprintf("%d层", f),
+				FloorArea:   800,
+			})
+			if err != nil {
+				return "", fmt.Errorf("create floor %s %d: %w", name, f, err)
+			}
+
+			for r := 1; r <= 4; r++ {
+				room, err := assetService.CreateRoom(&model.Room{
+					FloorID:    floor.ID,
+					RoomNumber: fmt.Sprintf("%d%02d", f, r),
+					RoomType:   "office",
+					RoomArea:   float64(120 + r*20),
+					RentPrice:  float64(6000 + r*1000),
+					Decoration: "simple",
+					HasWindow:  true,
+					HasAC:      true,
+				})
+				if err != nil {
+					return "", fmt.Errorf("create room: %w", err)
+				}
+				rooms = append(rooms, *room)
+			}
+		}
+	}
+
+	// Two tenants leasing part of the first floors, one lease ending in the past
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	leases := []struct {
+		tenant     string
+		start, end time.Time
+		rooms      []model.Room
+	}{
+		{"示例科技有限公司", today.AddDate(0, -6, 0), today.AddDate(1, 0, 0), rooms[0:3]},
+		{"示例设计工作室", today.AddDate(-1, 0, 0), today.AddDate(0, 0, -1), rooms[12:14]},
+	}
+	for i, l := range leases {
+		tenant, err := leaseService.CreateTenant(&model.Tenant{
+			TenantType: "company", Name: l.tenant, ContactPerson: "张三", ContactPhone: "13800000000",
+		})
+		if err != nil {
+			return "", fmt.Errorf("create tenant: %w", err)
+		}
+
+		roomIDs := make([]uint, 0, len(l.rooms))
+		var rent float64
+		for _, room := range l.rooms {
+			roomIDs = append(roomIDs, room.ID)
+			rent += room.RentPrice
+		}
+		_, err = leaseService.CreateLease(&model.Lease{
+			LeaseNo:     fmt.Sprintf("%s-L%d", demoAssetCode, i+1),
+			TenantID:    tenant.ID,
+			Lessor:      "示例资产管理有限公司",
+			StartDate:   l.start,
+			EndDate:     l.end,
+			MonthlyRent: rent,
+			Deposit:     rent * 2,
+		}, roomIDs)
+		if err != nil {
+			return "", fmt.Errorf("create lease: %w", err)
+		}
+	}
+
+	// An open work order that puts a vacant room into maintenance
+	roomID := rooms[5].ID
+	_, err = service.NewWorkOrderService().WithTx(tx).CreateWorkOrder(&model.WorkOrder{
+		Title:           "空调不制冷",
+		Description:     "示例工单",
+		Category:        "hvac",
+		Priority:        model.WorkOrderPriorityHigh,
+		RoomID:          &roomID,
+		ReporterName:    "张三",
+		RoomMaintenance: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create work order: %w", err)
+	}
+
+	return fmt.Sprintf("Created demo asset %s with 2 buildings, %d rooms, 2 leases and 1 work order", demoAssetCode, len(rooms)), nil
+}
@@ -55,7 +55,7 @@ echo "======================================"
 # 启动后端
 echo "启动后端服务..."
 cd ../backend
-go run main.go &
+go run . &
 BACKEND_PID=$!
 echo "后端服务 PID: $BACKEND_PID"
 
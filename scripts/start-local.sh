@@ -51,7 +51,7 @@ EOF
 
 # 安装依赖并运行
 go mod download
-go run main.go &
+go run . &
 BACKEND_PID=$!
 echo "后端服务已启动 (PID: $BACKEND_PID)"
 